@@ -14,9 +14,13 @@ import (
 // Command returns a cobra.Command instance that runs the clean command.
 func Command() *cobra.Command {
 	var (
-		apiURL     string
-		olderThan  time.Duration
-		largerThan int64
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		olderThan   time.Duration
+		largerThan  int64
 	)
 
 	cmd := &cobra.Command{
@@ -36,7 +40,12 @@ func Command() *cobra.Command {
 				return errors.New("one of --older-than or --larger-than must be set")
 			}
 
-			cl := client.New(apiURL)
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
 
 			profiles, err := cl.List(ctx)
 			if err != nil {
@@ -59,11 +68,18 @@ func Command() *cobra.Command {
 					continue
 				}
 
-				if err = cl.Delete(ctx, profile.Key); err != nil {
+				// Each channel is deleted individually, so a stale default channel does not remove the application's
+				// other channels.
+				channel := profile.Channel
+				if channel == "" {
+					channel = "default"
+				}
+
+				if err = cl.DeleteChannel(ctx, profile.Key, channel); err != nil {
 					return err
 				}
 
-				fmt.Printf("Deleted profile '%s'\n", profile.Key)
+				fmt.Printf("Deleted profile '%s' in channel '%s'\n", profile.Key, channel)
 			}
 
 			return nil
@@ -72,6 +88,10 @@ func Command() *cobra.Command {
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
 	flags.DurationVarP(&olderThan, "older-than", "d", 0, "The duration a profile must have remained static for")
 	flags.Int64VarP(&largerThan, "larger-than", "s", 0, "The minimum size a profile must be")
 
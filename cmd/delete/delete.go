@@ -13,7 +13,12 @@ import (
 // Command returns a cobra.Command instance used for the delete command.
 func Command() *cobra.Command {
 	var (
-		apiURL string
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
 	)
 
 	cmd := &cobra.Command{
@@ -23,7 +28,9 @@ func Command() *cobra.Command {
 		Args:    cobra.ExactArgs(1),
 		Long: "Deletes the profile for an application. This will also delete profiles pending merge for the application.\n\n" +
 			"This command will not prevent further profiles from being created if they are still being scraped, so you\n" +
-			"should stop all scraping prior to using this command unless you want to start profiling from scratch.",
+			"should stop all scraping prior to using this command unless you want to start profiling from scratch.\n\n" +
+			"The --channel flag can be provided to delete a single channel, otherwise all of the application's channels\n" +
+			"are deleted.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			app := args[0]
 			ctx := cmd.Context()
@@ -32,12 +39,27 @@ func Command() *cobra.Command {
 				return fmt.Errorf("%s is not a valid application name", app)
 			}
 
-			return client.New(apiURL).Delete(ctx, app)
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			return cl.DeleteChannel(ctx, app, channel)
 		},
 	}
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel to delete, deletes all channels when unset")
 
 	return cmd
 }
@@ -0,0 +1,184 @@
+// Package diff provides the command for comparing two of an application's profiles.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatPprof = "pprof"
+)
+
+// Command returns a cobra.Command instance used for the diff command.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+		version     int
+		baseChannel string
+		baseVersion int
+		top         int
+		format      string
+		output      string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "diff <app>",
+		Short:   "Compare two profiles",
+		GroupID: "utils",
+		Args:    cobra.ExactArgs(1),
+		Long: "Compares two of an application's profiles, normalizing both so that they can be compared regardless of\n" +
+			"how much CPU time they contain.\n\n" +
+			"The target profile is the current profile of the channel given by the --channel flag, or a previous\n" +
+			"generation of it when the --version flag is provided. The base profile is selected using the\n" +
+			"--base-channel & --base-version flags, which default to the target channel and its current profile.\n\n" +
+			"The table & json formats describe the functions whose share of CPU time grew or shrank the most, the hot\n" +
+			"functions that are new or removed and an overall similarity score. The pprof format writes a diff-base\n" +
+			"profile to the file given by the --output flag, which can be viewed using go tool pprof.",
+		Example: "autopgo diff hello-world --base-version 3\n" +
+			"autopgo diff hello-world --channel canary --base-channel default\n" +
+			"autopgo diff hello-world --base-version 3 --format pprof --output diff.pb.gz",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := args[0]
+			ctx := cmd.Context()
+
+			if !profile.IsValidAppName(app) {
+				return fmt.Errorf("%s is not a valid application name", app)
+			}
+
+			for _, name := range []string{channel, baseChannel} {
+				if name != "" && name != profile.DefaultChannel && !profile.IsValidChannelName(name) {
+					return fmt.Errorf("%s is not a valid channel name", name)
+				}
+			}
+
+			if version < 0 || baseVersion < 0 {
+				return errors.New("version cannot be negative")
+			}
+
+			if format != formatTable && format != formatJSON && format != formatPprof {
+				return fmt.Errorf("%s is not a valid format", format)
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			opts := client.DiffOptions{
+				Channel:     channel,
+				Version:     version,
+				BaseChannel: baseChannel,
+				BaseVersion: baseVersion,
+				Top:         top,
+			}
+
+			if format == formatPprof {
+				// The profile is buffered so that the output file is not created when the comparison fails.
+				var buf bytes.Buffer
+				if err = cl.DiffBase(ctx, app, opts, &buf); err != nil {
+					return err
+				}
+
+				return os.WriteFile(output, buf.Bytes(), 0o644)
+			}
+
+			diff, err := cl.Diff(ctx, app, opts)
+			if err != nil {
+				return err
+			}
+
+			if format == formatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(diff)
+			}
+
+			return printDiff(os.Stdout, diff)
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel of the target profile, uses the default channel when unset")
+	flags.IntVar(&version, "version", 0, "The generation of the target profile, uses the current profile when unset")
+	flags.StringVar(&baseChannel, "base-channel", "", "The channel of the base profile, uses --channel when unset")
+	flags.IntVar(&baseVersion, "base-version", 0, "The generation of the base profile, uses the current profile when unset")
+	flags.IntVar(&top, "top", 10, "The number of functions to print in each list")
+	flags.StringVar(&format, "format", formatTable, "The output format (table, json, pprof)")
+	flags.StringVarP(&output, "output", "o", "diff.pb.gz", "Where to write the diff-base profile when using the pprof format")
+
+	return cmd
+}
+
+func printDiff(w io.Writer, diff profile.Diff) error {
+	writer := tabwriter.NewWriter(w, 4, 1, 2, ' ', tabwriter.TabIndent)
+
+	lines := []string{
+		fmt.Sprintf("SIMILARITY:\t%.4f", diff.Similarity),
+		fmt.Sprintf("BASE CPU TIME:\t%s", diff.BaseCPUTime),
+		fmt.Sprintf("TARGET CPU TIME:\t%s", diff.TargetCPUTime),
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+
+	sections := []struct {
+		title     string
+		functions []profile.FunctionDiff
+	}{
+		{title: "GREW:", functions: diff.Grew},
+		{title: "SHRANK:", functions: diff.Shrank},
+		{title: "NEW:", functions: diff.New},
+		{title: "REMOVED:", functions: diff.Removed},
+	}
+
+	for _, section := range sections {
+		_, err := fmt.Fprintf(writer, "%s\n\tBASE FLAT\tTARGET FLAT\tDELTA\tBASE CUM\tTARGET CUM\tDELTA\tFUNCTION\n", section.title)
+		if err != nil {
+			return err
+		}
+
+		for _, fn := range section.functions {
+			_, err = fmt.Fprintf(writer, "\t%.2f%%\t%.2f%%\t%+.2f%%\t%.2f%%\t%.2f%%\t%+.2f%%\t%s\n",
+				fn.BaseFlat,
+				fn.TargetFlat,
+				fn.FlatDelta,
+				fn.BaseCumulative,
+				fn.TargetCumulative,
+				fn.CumulativeDelta,
+				fn.Name,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Flush()
+}
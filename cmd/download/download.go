@@ -3,11 +3,11 @@ package download
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
 
 	"github.com/spf13/cobra"
 
-	"github.com/davidsbond/autopgo/internal/closers"
+	"github.com/davidsbond/autopgo/internal/logger"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/pkg/client"
 )
@@ -15,17 +15,31 @@ import (
 // Command returns a cobra.Command instance used for the download command.
 func Command() *cobra.Command {
 	var (
-		apiURL string
-		output string
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		output      string
+		version     int
+		channel     string
+		fallback    []string
 	)
 
 	cmd := &cobra.Command{
 		Use:     "download <app>",
 		Short:   "Download a profile",
 		GroupID: "utils",
-		Long:    "Download a combined pprof profile for an application from the autopgo server",
-		Example: "autopgo download hello-world",
-		Args:    cobra.ExactArgs(1),
+		Long: "Download a combined pprof profile for an application from the autopgo server.\n\n" +
+			"The --version flag can be provided to download a previous generation of the profile instead of the\n" +
+			"current one.\n\n" +
+			"The --channel flag selects the channel to download from. If the channel has no profile, the channels\n" +
+			"given by the --fallback flag are tried in order.\n\n" +
+			"If the output file already contains the requested profile, it is left untouched.",
+		Example: "autopgo download hello-world\n" +
+			"autopgo download hello-world --version 3\n" +
+			"autopgo download hello-world --channel canary --fallback prod",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			app := args[0]
@@ -34,19 +48,50 @@ func Command() *cobra.Command {
 				return fmt.Errorf("%s is not a valid application name", app)
 			}
 
-			f, err := os.Create(output)
+			for _, name := range append([]string{channel}, fallback...) {
+				if name != "" && !profile.IsValidChannelName(name) {
+					return fmt.Errorf("%s is not a valid channel name", name)
+				}
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
 			if err != nil {
 				return err
 			}
 
-			defer closers.Close(ctx, f)
-			return client.New(apiURL).Download(ctx, app, f)
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+
+			opts := client.DownloadOptions{
+				Channel:  channel,
+				Fallback: fallback,
+				Version:  version,
+			}
+
+			written, err := cl.DownloadFile(ctx, app, opts, output)
+			if err != nil {
+				return err
+			}
+
+			if !written {
+				logger.FromContext(ctx).
+					With(slog.String("output", output)).
+					InfoContext(ctx, "profile is unchanged, skipping download")
+			}
+
+			return nil
 		},
 	}
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server.")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
 	flags.StringVarP(&output, "output", "o", "default.pgo", "Where to place the downloaded profile on the local filesystem.")
+	flags.IntVar(&version, "version", 0, "The generation of the profile to download, defaults to the current profile")
+	flags.StringVar(&channel, "channel", "", "The channel to download the profile from, uses the default channel when unset")
+	flags.StringSliceVar(&fallback, "fallback", nil, "Channels to download from, in order, when --channel has no profile")
 
 	return cmd
 }
@@ -0,0 +1,100 @@
+// Package inspect provides the command for inspecting how the compiler will use an application's profile.
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+// Command returns a cobra.Command instance used for the inspect command.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+		edges       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "inspect <app>",
+		Short:   "Inspect a profile",
+		GroupID: "utils",
+		Args:    cobra.ExactArgs(1),
+		Long: "Inspects the profile for an application to show what profile-guided optimization will act on.\n\n" +
+			"The --edges flag prints the hot call sites within the profile, weighted the same way as the compiler. These\n" +
+			"are the call sites whose cumulative weight falls within the compiler's hot call site threshold, making them\n" +
+			"the candidates for inlining and devirtualization.",
+		Example: "autopgo inspect hello-world --edges\n" +
+			"autopgo inspect hello-world --edges --channel canary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := args[0]
+			ctx := cmd.Context()
+
+			if !profile.IsValidAppName(app) {
+				return fmt.Errorf("%s is not a valid application name", app)
+			}
+
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
+			if !edges {
+				return errors.New("nothing to inspect, use the --edges flag")
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			report, err := cl.Edges(ctx, app, channel)
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 4, 1, 2, ' ', tabwriter.TabIndent)
+			if _, err = fmt.Fprintln(writer, "WEIGHT\tPERCENT\tCUMULATIVE\tCPU TIME\tCALLER\tOFFSET\tCALLEE"); err != nil {
+				return err
+			}
+
+			for _, edge := range report.Edges {
+				_, err = fmt.Fprintf(writer, "%d\t%.2f%%\t%.2f%%\t%s\t%s\t%d\t%s\n",
+					edge.Weight,
+					edge.Percent,
+					edge.CumulativePercent,
+					edge.CPUTime,
+					edge.Caller,
+					edge.CallSiteOffset,
+					edge.Callee,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return writer.Flush()
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel to inspect, uses the default channel when unset")
+	flags.BoolVar(&edges, "edges", false, "Print the hot call sites that are candidates for inlining and devirtualization")
+
+	return cmd
+}
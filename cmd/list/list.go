@@ -2,43 +2,108 @@
 package list
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/pkg/client"
 )
 
 // Command returns a cobra.Command instance that allows listing and printing profile information from the CLI.
 func Command() *cobra.Command {
 	var (
-		apiURL string
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		summary     bool
+		top         int
+		prefix      string
+		sort        string
+		limit       int
+		since       string
 	)
 
 	cmd := &cobra.Command{
 		Use:     "list",
 		Short:   "List all profiles",
-		Long:    "Prints information on all profiles currently stored within the server",
 		GroupID: "utils",
+		Long: "Prints information on all profiles currently stored within the server.\n\n" +
+			"The --summary flag also prints a summary of each profile's contents, including its top functions by flat\n" +
+			"and cumulative CPU time.\n\n" +
+			"Profiles can be filtered by the prefix of their application name using the --prefix flag, and to those\n" +
+			"modified recently using the --modified-since flag, which accepts either an RFC 3339 timestamp or a duration\n" +
+			"relative to now. The --sort flag orders profiles by name, size or lastModified, descending when prefixed\n" +
+			"with a hyphen.",
+		Example: "autopgo list --prefix payments- --sort -size --limit 10\n" +
+			"autopgo list --modified-since 24h",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			profiles, err := client.New(apiURL).List(ctx)
+
+			if limit < 0 {
+				return errors.New("--limit must not be negative")
+			}
+
+			opts := client.ListOptions{
+				Prefix: prefix,
+				Sort:   sort,
+				Limit:  min(limit, profile.MaxListLimit),
+			}
+
+			if since != "" {
+				modifiedSince, err := parseModifiedSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+
+				opts.ModifiedSince = modifiedSince
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
 			if err != nil {
 				return err
 			}
 
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+
+			profiles := make([]profile.Profile, 0)
+			for p, err := range cl.Profiles(ctx, opts) {
+				if err != nil {
+					return err
+				}
+
+				profiles = append(profiles, p)
+				if limit > 0 && len(profiles) == limit {
+					break
+				}
+			}
+
+			if summary {
+				return printSummaries(cmd, cl, profiles, top)
+			}
+
 			writer := tabwriter.NewWriter(os.Stdout, 4, 1, 2, ' ', tabwriter.TabIndent)
-			if _, err = fmt.Fprintln(writer, "NAME\tSIZE\tLAST MODIFIED"); err != nil {
+			if _, err = fmt.Fprintln(writer, "NAME\tCHANNEL\tSIZE\tLAST MODIFIED"); err != nil {
 				return err
 			}
 
 			for _, profile := range profiles {
 				lastModified := time.Since(profile.LastModified).Truncate(time.Second)
 
-				if _, err = fmt.Fprintf(writer, "%s\t%d\t%s\n", profile.Key, profile.Size, lastModified); err != nil {
+				channel := profile.Channel
+				if channel == "" {
+					channel = "default"
+				}
+
+				if _, err = fmt.Fprintf(writer, "%s\t%s\t%d\t%s\n", profile.Key, channel, profile.Size, lastModified); err != nil {
 					return err
 				}
 			}
@@ -49,6 +114,95 @@ func Command() *cobra.Command {
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server.")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.BoolVar(&summary, "summary", false, "Print a summary of each profile's contents")
+	flags.IntVar(&top, "top", 10, "The number of top functions to print with --summary")
+	flags.StringVar(&prefix, "prefix", "", "Only list profiles for applications whose name begins with this prefix")
+	flags.StringVar(&sort, "sort", profile.ListSortName, "Order profiles by name, size or lastModified, descending when prefixed with a hyphen")
+	flags.IntVar(&limit, "limit", 0, "The maximum number of profiles to list, all profiles are listed when zero")
+	flags.StringVar(&since, "modified-since", "", "Only list profiles modified since an RFC 3339 timestamp or a duration ago")
 
 	return cmd
 }
+
+func parseModifiedSince(value string, now time.Time) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--modified-since must be an RFC 3339 timestamp or a duration: %w", err)
+	}
+
+	return since, nil
+}
+
+func printSummaries(cmd *cobra.Command, cl *client.Client, profiles []profile.Profile, top int) error {
+	writer := tabwriter.NewWriter(os.Stdout, 4, 1, 2, ' ', tabwriter.TabIndent)
+	for i, p := range profiles {
+		channel := p.Channel
+		if channel == "" {
+			channel = profile.DefaultChannel
+		}
+
+		summary, err := cl.Summary(cmd.Context(), p.Key, p.Channel, top)
+		if err != nil {
+			return fmt.Errorf("failed to summarise %s (%s): %w", p.Key, channel, err)
+		}
+
+		if i > 0 {
+			if _, err = fmt.Fprintln(writer); err != nil {
+				return err
+			}
+		}
+
+		lines := []string{
+			fmt.Sprintf("NAME:\t%s", p.Key),
+			fmt.Sprintf("CHANNEL:\t%s", channel),
+			fmt.Sprintf("SIZE:\t%d", p.Size),
+			fmt.Sprintf("LAST MODIFIED:\t%s", time.Since(p.LastModified).Truncate(time.Second)),
+			fmt.Sprintf("SAMPLE TYPES:\t%s", strings.Join(summary.SampleTypes, ", ")),
+			fmt.Sprintf("SAMPLES:\t%d", summary.TotalSamples),
+			fmt.Sprintf("CPU TIME:\t%s", summary.CPUTime),
+			fmt.Sprintf("FUNCTIONS:\t%d", summary.Functions),
+			fmt.Sprintf("LOCATIONS:\t%d", summary.Locations),
+			fmt.Sprintf("MAPPINGS:\t%d", summary.Mappings),
+			fmt.Sprintf("TIME RANGE:\t%s - %s", summary.Start.Format(time.RFC3339), summary.End.Format(time.RFC3339)),
+			fmt.Sprintf("UPLOADS:\t%d", summary.Uploads),
+		}
+
+		for _, line := range lines {
+			if _, err = fmt.Fprintln(writer, line); err != nil {
+				return err
+			}
+		}
+
+		if err = printFunctions(writer, "TOP FLAT:", summary.TopFlat); err != nil {
+			return err
+		}
+
+		if err = printFunctions(writer, "TOP CUMULATIVE:", summary.TopCumulative); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func printFunctions(w io.Writer, title string, functions []profile.FunctionSummary) error {
+	if _, err := fmt.Fprintf(w, "%s\n\tFLAT\tCUMULATIVE\tFUNCTION\n", title); err != nil {
+		return err
+	}
+
+	for _, fn := range functions {
+		if _, err := fmt.Fprintf(w, "\t%s\t%s\t%s\n", fn.Flat, fn.Cumulative, fn.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
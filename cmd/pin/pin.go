@@ -0,0 +1,74 @@
+// Package pin provides the command for pinning an application's profile.
+package pin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+// Command returns a cobra.Command instance used for the pin command.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+		version     int
+	)
+
+	cmd := &cobra.Command{
+		Use:     "pin <app>",
+		Short:   "Pin a profile",
+		GroupID: "utils",
+		Args:    cobra.ExactArgs(1),
+		Long: "Pins the profile for an application, so that downloads are served the same profile until it is unpinned.\n" +
+			"Use this during code freezes to keep builds reproducible. Uploaded profiles continue to be merged while the\n" +
+			"profile is pinned, the result of which is served once the profile is unpinned.\n\n" +
+			"The current profile is pinned unless the --version flag is provided. Pinning an already pinned profile\n" +
+			"replaces the pinned profile.",
+		Example: "autopgo pin hello-world\n" +
+			"autopgo pin hello-world --channel canary --version 3",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := args[0]
+			ctx := cmd.Context()
+
+			if !profile.IsValidAppName(app) {
+				return fmt.Errorf("%s is not a valid application name", app)
+			}
+
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
+			if version < 0 {
+				return errors.New("version cannot be negative")
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			return cl.Pin(ctx, app, channel, version)
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel to pin, uses the default channel when unset")
+	flags.IntVar(&version, "version", 0, "The generation of the profile to pin, pins the current profile when unset")
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+// Package promote provides the command for promoting a channel's profile to an application's stable profile.
+package promote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+// Command returns a cobra.Command instance used for the promote command.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+		version     int
+		history     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "promote <app>",
+		Short:   "Promote a profile",
+		GroupID: "utils",
+		Args:    cobra.ExactArgs(1),
+		Long: "Copies a generation of a channel's profile to the application's stable profile, which is served from the\n" +
+			"default channel in place of its merged profile. Use this to test profiles merged into a candidate channel\n" +
+			"before they are used within release builds. The generations of a channel can be found via the versions\n" +
+			"endpoint. A promotion is undone by promoting the previously promoted generation again.\n\n" +
+			"Promotions are performed asynchronously by the worker, which records who promoted each profile and when.\n" +
+			"The --history flag prints these records instead of performing a promotion.",
+		Example: "autopgo promote hello-world --channel candidate --version 3\n" +
+			"autopgo promote hello-world --history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := args[0]
+			ctx := cmd.Context()
+
+			if !profile.IsValidAppName(app) {
+				return fmt.Errorf("%s is not a valid application name", app)
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			if history {
+				return printHistory(cmd, cl, app)
+			}
+
+			switch {
+			case channel == profile.DefaultChannel || !profile.IsValidChannelName(channel):
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			case version <= 0:
+				return errors.New("a version must be specified")
+			}
+
+			return cl.Promote(ctx, app, channel, version)
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel to promote the profile from")
+	flags.IntVar(&version, "version", 0, "The generation of the channel's profile to promote")
+	flags.BoolVar(&history, "history", false, "Print the application's previous promotions")
+
+	return cmd
+}
+
+func printHistory(cmd *cobra.Command, cl *client.Client, app string) error {
+	promotions, err := cl.Promotions(cmd.Context(), app)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 4, 1, 2, ' ', tabwriter.TabIndent)
+	if _, err = fmt.Fprintln(writer, "CHANNEL\tVERSION\tPROMOTED BY\tPROMOTED AT"); err != nil {
+		return err
+	}
+
+	for _, promotion := range promotions {
+		promotedBy := promotion.PromotedBy
+		if promotedBy == "" {
+			promotedBy = "-"
+		}
+
+		_, err = fmt.Fprintf(writer, "%s\t%d\t%s\t%s\n",
+			promotion.Channel,
+			promotion.Version,
+			promotedBy,
+			promotion.PromotedAt.Format(time.RFC3339),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
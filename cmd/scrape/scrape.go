@@ -2,6 +2,7 @@
 package scrape
 
 import (
+	"fmt"
 	"time"
 
 	consul "github.com/hashicorp/consul/api"
@@ -13,10 +14,12 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/metrics"
 	"github.com/davidsbond/autopgo/internal/operation"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/server"
 	"github.com/davidsbond/autopgo/internal/target"
+	"github.com/davidsbond/autopgo/internal/tracing"
 	"github.com/davidsbond/autopgo/pkg/client"
 )
 
@@ -30,14 +33,31 @@ const (
 // Command returns a cobra.Command instance used to run the scraper.
 func Command() *cobra.Command {
 	var (
-		apiURL     string
-		port       int
-		sampleSize uint
-		duration   time.Duration
-		frequency  time.Duration
-		app        string
-		mode       string
-		debug      bool
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		port        int
+		sampleSize  uint
+		duration    time.Duration
+		frequency   time.Duration
+		app         string
+		channel     string
+		mode        string
+		debug       bool
+		tlsCertFile string
+		tlsKeyFile  string
+		tlsClientCA string
+		tlsVersion  string
+		minSamples  int64
+		minCPUTime  time.Duration
+
+		adaptiveTarget        int64
+		adaptiveMinDuration   time.Duration
+		adaptiveMaxDuration   time.Duration
+		adaptiveMinSampleSize uint
+		adaptiveMaxSampleSize uint
 	)
 
 	cmd := &cobra.Command{
@@ -54,6 +74,10 @@ func Command() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
 			var source target.Source
 			var err error
 
@@ -77,12 +101,36 @@ func Command() *cobra.Command {
 				return err
 			}
 
-			cl := client.New(apiURL)
+			var adaptive *profile.AdaptiveConfig
+			if adaptiveTarget > 0 {
+				if adaptiveMaxSampleSize == 0 {
+					adaptiveMaxSampleSize = sampleSize
+				}
+
+				adaptive = &profile.AdaptiveConfig{
+					TargetSamplesPerHour: adaptiveTarget,
+					MinProfileDuration:   adaptiveMinDuration,
+					MaxProfileDuration:   adaptiveMaxDuration,
+					MinSampleSize:        adaptiveMinSampleSize,
+					MaxSampleSize:        adaptiveMaxSampleSize,
+				}
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
 			scraper := profile.NewScraper(cl, profile.ScrapeConfig{
 				SampleSize:      sampleSize,
 				ProfileDuration: duration,
 				ScrapeFrequency: frequency,
 				App:             app,
+				Channel:         channel,
+				MinSamples:      minSamples,
+				MinCPUTime:      minCPUTime,
+				Adaptive:        adaptive,
 			})
 
 			group, ctx := errgroup.WithContext(ctx)
@@ -93,12 +141,22 @@ func Command() *cobra.Command {
 				return server.Run(ctx, server.Config{
 					Debug: debug,
 					Port:  port,
+					TLS: server.TLSConfig{
+						CertFile:     tlsCertFile,
+						KeyFile:      tlsKeyFile,
+						ClientCAFile: tlsClientCA,
+						MinVersion:   tlsVersion,
+					},
 					Controllers: []server.Controller{
 						operation.NewHTTPController([]operation.Checker{
 							source,
 						}),
+						profile.NewScraperHTTPController(scraper),
+						metrics.NewHTTPController(),
 					},
 					Middleware: []server.Middleware{
+						tracing.Middleware(),
+						metrics.Middleware(),
 						logger.Middleware(logger.FromContext(ctx)),
 					},
 				})
@@ -110,13 +168,29 @@ func Command() *cobra.Command {
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
 	flags.IntVarP(&port, "port", "p", 8082, "Port to use for HTTP traffic")
 	flags.StringVarP(&app, "app", "a", "", "The name of the application being profiled")
+	flags.StringVar(&channel, "channel", "", "The channel to upload profiles to, such as an environment or branch, uses the default channel when unset")
 	flags.UintVarP(&sampleSize, "sample-size", "s", 0, "The maximum number of targets to scrape concurrently")
 	flags.DurationVarP(&duration, "duration", "d", time.Second*30, "How long to profile targets for")
 	flags.DurationVarP(&frequency, "frequency", "f", time.Minute, "Interval between scraping targets")
 	flags.StringVarP(&mode, "mode", "m", modeFile, "Mode to use for obtaining targets (file, kube, nomad, consul)")
 	flags.BoolVar(&debug, "debug", false, "Enable debug endpoints")
+	flags.StringVar(&tlsCertFile, "tls-cert-file", "", "Location of a PEM-encoded certificate to serve HTTPS traffic with, reloaded on change")
+	flags.StringVar(&tlsKeyFile, "tls-key-file", "", "Location of the PEM-encoded private key for --tls-cert-file, reloaded on change")
+	flags.StringVar(&tlsClientCA, "tls-client-ca-file", "", "Location of PEM-encoded CA certificates used to verify client certificates, enables mTLS")
+	flags.StringVar(&tlsVersion, "tls-min-version", "1.2", "The minimum TLS version to accept (1.2 or 1.3)")
+	flags.Int64Var(&minSamples, "min-samples", 0, "The minimum number of samples a profile must contain to be uploaded")
+	flags.DurationVar(&minCPUTime, "min-cpu-time", 0, "The minimum total CPU time a profile must contain to be uploaded")
+	flags.Int64Var(&adaptiveTarget, "adaptive-target-samples", 0, "The number of CPU samples to obtain per hour, enables adaptive scraping when set")
+	flags.DurationVar(&adaptiveMinDuration, "adaptive-min-duration", time.Second*5, "The minimum profile duration when adaptive scraping is enabled")
+	flags.DurationVar(&adaptiveMaxDuration, "adaptive-max-duration", time.Minute, "The maximum profile duration when adaptive scraping is enabled")
+	flags.UintVar(&adaptiveMinSampleSize, "adaptive-min-sample-size", 1, "The minimum sample size when adaptive scraping is enabled")
+	flags.UintVar(&adaptiveMaxSampleSize, "adaptive-max-sample-size", 0, "The maximum sample size when adaptive scraping is enabled, defaults to --sample-size")
 
 	cmd.MarkFlagRequired("app")
 	cmd.MarkFlagRequired("sample-size")
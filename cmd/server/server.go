@@ -2,15 +2,29 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/davidsbond/autopgo/internal/auth"
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/closers"
 	"github.com/davidsbond/autopgo/internal/event"
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/metrics"
 	"github.com/davidsbond/autopgo/internal/operation"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/server"
+	"github.com/davidsbond/autopgo/internal/tracing"
+	"github.com/davidsbond/autopgo/internal/ui"
 )
 
 // Command returns a cobra.Command instance used to run the server.
@@ -19,7 +33,21 @@ func Command() *cobra.Command {
 		port           int
 		eventWriterURL string
 		blobStoreURL   string
+		blobPrefix     string
 		debug          bool
+		tlsCertFile    string
+		tlsKeyFile     string
+		tlsClientCA    string
+		tlsVersion     string
+		tokensFile     string
+		tokensSecret   string
+		kubeConfig     string
+		issuersFile    string
+		maxUploadBytes int64
+		minDuration    time.Duration
+		maxDuration    time.Duration
+		dedupWindow    time.Duration
+		enableUI       bool
 	)
 
 	cmd := &cobra.Command{
@@ -45,19 +73,68 @@ func Command() *cobra.Command {
 			}
 			defer closers.Close(ctx, blobs)
 
+			middleware := []server.Middleware{
+				tracing.Middleware(),
+				metrics.Middleware(),
+				logger.Middleware(logger.FromContext(ctx)),
+			}
+
+			var verifiers []auth.Verifier
+			tokens, err := loadTokens(ctx, tokensFile, tokensSecret, kubeConfig)
+			switch {
+			case err != nil:
+				return err
+			case tokens != nil:
+				verifiers = append(verifiers, auth.NewTokenVerifier(tokens))
+			}
+
+			if issuersFile != "" {
+				issuers, err := auth.LoadIssuersFile(ctx, issuersFile)
+				if err != nil {
+					return err
+				}
+
+				verifiers = append(verifiers, auth.NewJWTVerifier(issuers))
+			}
+
+			if len(verifiers) == 0 {
+				logger.FromContext(ctx).Warn("server starting with authentication disabled")
+			} else {
+				middleware = slices.Insert(middleware, 0, auth.Middleware(auth.Chain(verifiers...)))
+			}
+
+			repository := metrics.NewBlobRepository(profile.NewPrefixedBlobRepository(blobs, blobPrefix))
+
+			controllers := []server.Controller{
+				profile.NewHTTPController(repository, metrics.NewEventWriter(writer), profile.UploadConfig{
+					MaxBytes:    maxUploadBytes,
+					MinDuration: minDuration,
+					MaxDuration: maxDuration,
+					DedupWindow: dedupWindow,
+				}),
+				metrics.NewHTTPController(),
+				operation.NewHTTPController([]operation.Checker{
+					blobs,
+					writer,
+				}),
+			}
+
+			if enableUI {
+				controllers = append(controllers, ui.NewHTTPController())
+			}
+
 			return server.Run(ctx, server.Config{
-				Debug: debug,
-				Port:  port,
-				Controllers: []server.Controller{
-					profile.NewHTTPController(blobs, writer),
-					operation.NewHTTPController([]operation.Checker{
-						blobs,
-						writer,
-					}),
-				},
-				Middleware: []server.Middleware{
-					logger.Middleware(logger.FromContext(ctx)),
+				Debug:  debug,
+				Port:   port,
+				Logger: logger.FromContext(ctx),
+				TLS: server.TLSConfig{
+					CertFile:     tlsCertFile,
+					KeyFile:      tlsKeyFile,
+					ClientCAFile: tlsClientCA,
+					MinVersion:   tlsVersion,
 				},
+				Controllers: controllers,
+				Middleware:  middleware,
 			})
 		},
 	}
@@ -66,10 +143,60 @@ func Command() *cobra.Command {
 	flags.IntVarP(&port, "port", "p", 8080, "Port to use for HTTP traffic")
 	flags.StringVar(&eventWriterURL, "event-writer-url", "", "The URL to use for writing to the event bus")
 	flags.StringVar(&blobStoreURL, "blob-store-url", "", "The URL to use for connecting to blob storage")
+	flags.StringVar(&blobPrefix, "blob-prefix", "", "A prefix to store all objects beneath within blob storage, which must match between the server & worker")
 	flags.BoolVar(&debug, "debug", false, "Enable debug endpoints")
+	flags.StringVar(&tlsCertFile, "tls-cert-file", "", "Location of a PEM-encoded certificate to serve HTTPS traffic with, reloaded on change")
+	flags.StringVar(&tlsKeyFile, "tls-key-file", "", "Location of the PEM-encoded private key for --tls-cert-file, reloaded on change")
+	flags.StringVar(&tlsClientCA, "tls-client-ca-file", "", "Location of PEM-encoded CA certificates used to verify client certificates, enables mTLS")
+	flags.StringVar(&tlsVersion, "tls-min-version", "1.2", "The minimum TLS version to accept (1.2 or 1.3)")
+	flags.StringVar(&tokensFile, "auth-tokens-file", "", "Location of a JSON file containing bearer tokens for authentication")
+	flags.StringVar(&tokensSecret, "auth-tokens-secret", "", "Kubernetes Secret containing bearer tokens for authentication, in namespace/name format")
+	flags.StringVar(&issuersFile, "auth-issuers-file", "", "Location of a JSON file describing trusted JWT issuers for authentication")
+	flags.StringVar(&kubeConfig, "kubeconfig", "", "Location of the kubeconfig file used to read --auth-tokens-secret, uses in-cluster configuration when unset")
+	flags.Int64Var(&maxUploadBytes, "upload-max-bytes", 32<<20, "The maximum size of an uploaded profile in bytes, 0 for no limit")
+	flags.DurationVar(&minDuration, "upload-min-duration", 0, "The minimum duration an uploaded profile must cover, 0 for no limit")
+	flags.DurationVar(&maxDuration, "upload-max-duration", 0, "The maximum duration an uploaded profile may cover, 0 for no limit")
+	flags.DurationVar(&dedupWindow, "upload-dedup-window", time.Hour, "How long uploads are remembered to detect duplicates, 0 to disable duplicate detection")
+	flags.BoolVar(&enableUI, "ui", false, "Serve the web interface for browsing profiles at /ui/")
 
 	cmd.MarkPersistentFlagRequired("blob-store-url")
 	cmd.MarkPersistentFlagRequired("event-writer-url")
 
 	return cmd
 }
+
+func loadTokens(ctx context.Context, file, secret, kubeConfig string) ([]auth.Token, error) {
+	switch {
+	case file != "" && secret != "":
+		return nil, errors.New("only one of --auth-tokens-file and --auth-tokens-secret may be set")
+	case file != "":
+		return auth.LoadTokensFile(ctx, file)
+	case secret != "":
+		namespace, name, ok := strings.Cut(secret, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid secret %q, expected namespace/name format", secret)
+		}
+
+		var config *rest.Config
+		var err error
+		switch {
+		case kubeConfig != "":
+			config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
+		default:
+			config, err = rest.InClusterConfig()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		cl, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.LoadTokensSecret(ctx, cl, namespace, name)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,130 @@
+// Package staged provides the command-line entrypoint to the staged command and its subcommands.
+package staged
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+// Command returns a cobra.Command instance used for managing the profiles waiting to be merged.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "staged",
+		Short:   "Manage profiles waiting to be merged",
+		GroupID: "utils",
+		Long: "Lists, requeues & discards the profiles uploaded for an application that are waiting to be merged by the\n" +
+			"worker. Uploads are removed once merged, so a growing list of staged uploads indicates that the worker is\n" +
+			"falling behind or failing to merge them.",
+	}
+
+	newClient := func(app string) (*client.Client, error) {
+		if !profile.IsValidAppName(app) {
+			return nil, fmt.Errorf("%s is not a valid application name", app)
+		}
+
+		if channel != "" && !profile.IsValidChannelName(channel) {
+			return nil, fmt.Errorf("%s is not a valid channel name", channel)
+		}
+
+		tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig)), nil
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list <app>",
+			Short: "List profiles waiting to be merged",
+			Args:  cobra.ExactArgs(1),
+			Long: "Prints the identifier, size & age of each profile uploaded for an application that is waiting to be merged,\n" +
+				"oldest first.",
+			Example: "autopgo staged list hello-world --channel canary",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cl, err := newClient(args[0])
+				if err != nil {
+					return err
+				}
+
+				uploads, err := cl.Staged(cmd.Context(), args[0], channel)
+				if err != nil {
+					return err
+				}
+
+				writer := tabwriter.NewWriter(os.Stdout, 4, 1, 2, ' ', tabwriter.TabIndent)
+				if _, err = fmt.Fprintln(writer, "ID\tSIZE\tAGE"); err != nil {
+					return err
+				}
+
+				for _, upload := range uploads {
+					age := time.Since(upload.UploadedAt).Truncate(time.Second)
+					if _, err = fmt.Fprintf(writer, "%s\t%d\t%s\n", upload.ID, upload.Size, age); err != nil {
+						return err
+					}
+				}
+
+				return writer.Flush()
+			},
+		},
+		&cobra.Command{
+			Use:   "requeue <app> <id>",
+			Short: "Requeue a profile waiting to be merged",
+			Args:  cobra.ExactArgs(2),
+			Long: "Publishes the upload event of a profile waiting to be merged again, so that the worker attempts to merge it.\n" +
+				"Use this to recover uploads whose events were lost or failed to be handled.",
+			Example: "autopgo staged requeue hello-world 1730477575747397000",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cl, err := newClient(args[0])
+				if err != nil {
+					return err
+				}
+
+				return cl.Requeue(cmd.Context(), args[0], channel, args[1])
+			},
+		},
+		&cobra.Command{
+			Use:   "discard <app> <id>",
+			Short: "Discard a profile waiting to be merged",
+			Args:  cobra.ExactArgs(2),
+			Long: "Deletes a profile waiting to be merged without merging it. Use this to remove uploads the worker is unable\n" +
+				"to merge.",
+			Example: "autopgo staged discard hello-world 1730477575747397000",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cl, err := newClient(args[0])
+				if err != nil {
+					return err
+				}
+
+				return cl.Discard(cmd.Context(), args[0], channel, args[1])
+			},
+		},
+	)
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel of the staged profiles, uses the default channel when unset")
+
+	return cmd
+}
@@ -0,0 +1,63 @@
+// Package unpin provides the command for unpinning an application's profile.
+package unpin
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+// Command returns a cobra.Command instance used for the unpin command.
+func Command() *cobra.Command {
+	var (
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		channel     string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "unpin <app>",
+		Short:   "Unpin a profile",
+		GroupID: "utils",
+		Args:    cobra.ExactArgs(1),
+		Long: "Unpins the profile for an application, so that downloads are served its current profile, including any\n" +
+			"profiles merged while it was pinned.",
+		Example: "autopgo unpin hello-world",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := args[0]
+			ctx := cmd.Context()
+
+			if !profile.IsValidAppName(app) {
+				return fmt.Errorf("%s is not a valid application name", app)
+			}
+
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
+			return cl.Unpin(ctx, app, channel)
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
+	flags.StringVar(&channel, "channel", "", "The channel to unpin, uses the default channel when unset")
+
+	return cmd
+}
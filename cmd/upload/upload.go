@@ -4,10 +4,13 @@ package upload
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
 
+	"github.com/davidsbond/autopgo/internal/closers"
+	"github.com/davidsbond/autopgo/internal/logger"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/pkg/client"
 )
@@ -15,8 +18,14 @@ import (
 // Command returns a cobra.Command instance used for the upload command.
 func Command() *cobra.Command {
 	var (
-		apiURL string
-		app    string
+		apiURL      string
+		token       string
+		apiCAFile   string
+		apiCertFile string
+		apiKeyFile  string
+		app         string
+		channel     string
+		idempotency string
 	)
 
 	cmd := &cobra.Command{
@@ -31,10 +40,19 @@ func Command() *cobra.Command {
 				return fmt.Errorf("%s is not a valid application name", app)
 			}
 
+			if channel != "" && !profile.IsValidChannelName(channel) {
+				return fmt.Errorf("%s is not a valid channel name", channel)
+			}
+
 			ctx := cmd.Context()
 			location := args[0]
 
-			cl := client.New(apiURL)
+			tlsConfig, err := client.LoadTLSConfig(apiCAFile, apiCertFile, apiKeyFile)
+			if err != nil {
+				return err
+			}
+
+			cl := client.New(apiURL, client.WithToken(token), client.WithTLSConfig(tlsConfig))
 
 			file, err := os.Open(location)
 			switch {
@@ -44,13 +62,32 @@ func Command() *cobra.Command {
 				return err
 			}
 
-			return cl.Upload(ctx, app, file)
+			defer closers.Close(ctx, file)
+
+			upload, err := cl.UploadWithOptions(ctx, app, client.UploadOptions{Channel: channel, IdempotencyKey: idempotency}, file)
+			if err != nil {
+				return err
+			}
+
+			if upload.Duplicate {
+				logger.FromContext(ctx).
+					With(slog.String("profile.key", upload.Key)).
+					InfoContext(ctx, "profile has already been uploaded, it will not be merged again")
+			}
+
+			return nil
 		},
 	}
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&apiURL, "api-url", "u", "http://localhost:8080", "Base URL of the autopgo server")
+	flags.StringVar(&token, "token", "", "Bearer token used to authenticate with the autopgo server")
+	flags.StringVar(&apiCAFile, "api-ca-file", "", "Location of PEM-encoded CA certificates used to verify the autopgo server")
+	flags.StringVar(&apiCertFile, "api-cert-file", "", "Location of a PEM-encoded client certificate used to authenticate with the autopgo server")
+	flags.StringVar(&apiKeyFile, "api-key-file", "", "Location of the PEM-encoded private key for --api-cert-file")
 	flags.StringVarP(&app, "app", "a", "", "The name of the application")
+	flags.StringVar(&channel, "channel", "", "The channel to upload the profile to, uses the default channel when unset")
+	flags.StringVar(&idempotency, "idempotency-key", "", "Identifies the upload, so that reruns using the same key are not merged more than once")
 
 	cmd.MarkPersistentFlagRequired("app")
 
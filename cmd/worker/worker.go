@@ -2,6 +2,8 @@
 package worker
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 
@@ -9,9 +11,11 @@ import (
 	"github.com/davidsbond/autopgo/internal/closers"
 	"github.com/davidsbond/autopgo/internal/event"
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/metrics"
 	"github.com/davidsbond/autopgo/internal/operation"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/server"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 // Command returns a cobra.Command instance used to run the worker.
@@ -20,9 +24,19 @@ func Command() *cobra.Command {
 		eventReaderURL string
 		eventWriterURL string
 		blobStoreURL   string
+		blobPrefix     string
 		prune          string
+		retain         int
+		reconcile      time.Duration
+		reconcileAfter time.Duration
+		dedupWindow    time.Duration
+		maxRequeues    int
 		port           int
 		debug          bool
+		tlsCertFile    string
+		tlsKeyFile     string
+		tlsClientCA    string
+		tlsVersion     string
 	)
 
 	cmd := &cobra.Command{
@@ -34,6 +48,12 @@ func Command() *cobra.Command {
 			"The --prune flag can be optionally provided to parse a JSON-encoded configuration file that describes how\n" +
 			"profiles should be pruned as they are merged. See the documentation for more information on configuring\n" +
 			"pruning.\n\n" +
+			"Each merged profile is also kept as a numbered generation, the --retain-versions flag controls how many\n" +
+			"generations are kept per application. Previous generations can be downloaded or restored via the server.\n\n" +
+			"Every --reconcile-interval, profiles that have waited longer than --reconcile-after to be merged are\n" +
+			"requeued, or deleted if they have already been merged, recovering uploads whose events were lost. Requeues\n" +
+			"back off exponentially and stop after --reconcile-attempts. Records of uploads older than\n" +
+			"--upload-dedup-window are also deleted.\n\n" +
 			"The URL based flags follow the semantics based on the individual provider. Supported provides include AWS,\n" +
 			"GCP & Azure. See the gocloud.dev documentation for further information on configuring these flags for your\n" +
 			"specific provider.",
@@ -67,30 +87,52 @@ func Command() *cobra.Command {
 				logger.FromContext(ctx).Warn("worker starting with no prune rules")
 			}
 
-			worker := profile.NewWorker(blobs, writer, pruning)
+			repository := metrics.NewBlobRepository(profile.NewPrefixedBlobRepository(blobs, blobPrefix))
+			worker := profile.NewWorker(repository, metrics.NewEventWriter(writer), pruning, retain)
 
 			types := []string{
 				profile.EventTypeMerged,
 				profile.EventTypeUploaded,
 				profile.EventTypeDeleted,
+				profile.EventTypeRolledBack,
+				profile.EventTypePromoted,
 			}
 
 			group, ctx := errgroup.WithContext(ctx)
 			group.Go(func() error {
 				return reader.Read(ctx, types, worker.HandleEvent)
 			})
+			if reconcile > 0 {
+				group.Go(func() error {
+					return worker.Reconcile(ctx, profile.ReconcileConfig{
+						Interval:    reconcile,
+						Threshold:   reconcileAfter,
+						DedupWindow: dedupWindow,
+						MaxAttempts: maxRequeues,
+					})
+				})
+			}
 			group.Go(func() error {
 				return server.Run(ctx, server.Config{
 					Debug: debug,
 					Port:  port,
+					TLS: server.TLSConfig{
+						CertFile:     tlsCertFile,
+						KeyFile:      tlsKeyFile,
+						ClientCAFile: tlsClientCA,
+						MinVersion:   tlsVersion,
+					},
 					Controllers: []server.Controller{
 						operation.NewHTTPController([]operation.Checker{
 							blobs,
 							reader,
 							writer,
 						}),
+						metrics.NewHTTPController(),
 					},
 					Middleware: []server.Middleware{
+						tracing.Middleware(),
+						metrics.Middleware(),
 						logger.Middleware(logger.FromContext(ctx)),
 					},
 				})
@@ -104,9 +146,19 @@ func Command() *cobra.Command {
 	flags.StringVar(&eventReaderURL, "event-reader-url", "", "The URL to use for reading from the event bus")
 	flags.StringVar(&eventWriterURL, "event-writer-url", "", "The URL to use for writing to the event bus")
 	flags.StringVar(&blobStoreURL, "blob-store-url", "", "The URL to use for connecting to blob storage")
+	flags.StringVar(&blobPrefix, "blob-prefix", "", "A prefix to store all objects beneath within blob storage, which must match between the server & worker")
 	flags.IntVarP(&port, "port", "p", 8081, "Port to use for HTTP traffic")
 	flags.StringVar(&prune, "prune", "", "Location of the configuration file for profile pruning")
+	flags.IntVar(&retain, "retain-versions", 10, "Number of generations of each merged profile to keep, 0 disables versioning")
+	flags.DurationVar(&reconcile, "reconcile-interval", 5*time.Minute, "How often to search for profiles waiting too long to be merged, 0 disables reconciliation")
+	flags.DurationVar(&reconcileAfter, "reconcile-after", time.Hour, "How long a profile may wait to be merged before it is requeued by reconciliation")
+	flags.IntVar(&maxRequeues, "reconcile-attempts", 5, "How many times a profile may be requeued by reconciliation before it is abandoned, 0 requeues indefinitely")
+	flags.DurationVar(&dedupWindow, "upload-dedup-window", time.Hour, "How long records of uploads are kept to detect duplicates, which must match the server, 0 keeps them indefinitely")
 	flags.BoolVar(&debug, "debug", false, "Enable debug endpoints")
+	flags.StringVar(&tlsCertFile, "tls-cert-file", "", "Location of a PEM-encoded certificate to serve HTTPS traffic with, reloaded on change")
+	flags.StringVar(&tlsKeyFile, "tls-key-file", "", "Location of the PEM-encoded private key for --tls-cert-file, reloaded on change")
+	flags.StringVar(&tlsClientCA, "tls-client-ca-file", "", "Location of PEM-encoded CA certificates used to verify client certificates, enables mTLS")
+	flags.StringVar(&tlsVersion, "tls-min-version", "1.2", "The minimum TLS version to accept (1.2 or 1.3)")
 
 	cmd.MarkPersistentFlagRequired("blob-store-url")
 	cmd.MarkPersistentFlagRequired("event-reader-url")
@@ -0,0 +1,233 @@
+// Package auth provides types and functions for authenticating and authorizing requests made to the profile server
+// using bearer tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/davidsbond/autopgo/internal/api"
+	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/server"
+)
+
+type (
+	// The Scope type describes an operation that a token is permitted to perform.
+	Scope string
+
+	// The Principal type describes an authenticated caller and the operations it is permitted to perform.
+	Principal struct {
+		// A name describing the caller, used for logging.
+		Name string
+		// The permissions granted to the caller.
+		Grants []Grant
+	}
+
+	// The Grant type describes a set of operations that can be performed against a set of applications.
+	Grant struct {
+		// The operations that can be performed.
+		Scopes []Scope `json:"scopes"`
+		// Patterns for the application names the operations can be performed against. Patterns use the syntax
+		// described in path.Match, so "*" permits all applications.
+		Apps []string `json:"apps"`
+	}
+
+	// The Verifier interface describes types that can verify bearer tokens.
+	Verifier interface {
+		// Verify should return the Principal that the token belongs to. It should return ErrInvalidToken if the token
+		// is not recognised.
+		Verify(ctx context.Context, token string) (Principal, error)
+	}
+
+	// The TokenVerifier type is a Verifier implementation that checks bearer tokens against a static set of Token
+	// configurations.
+	TokenVerifier struct {
+		tokens map[[sha256.Size]byte]Principal
+	}
+)
+
+// Constants for scopes that can be assigned to tokens.
+const (
+	ScopeUpload   Scope = "upload"
+	ScopeDownload Scope = "download"
+	ScopeList     Scope = "list"
+	ScopeDelete   Scope = "delete"
+	ScopePromote  Scope = "promote"
+)
+
+var (
+	// ErrInvalidToken is the error given when a bearer token cannot be verified.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// NewTokenVerifier returns a new instance of the TokenVerifier type that will accept the provided tokens.
+func NewTokenVerifier(tokens []Token) *TokenVerifier {
+	verifier := &TokenVerifier{
+		tokens: make(map[[sha256.Size]byte]Principal, len(tokens)),
+	}
+
+	for _, token := range tokens {
+		// Tokens are stored by their hash so that lookups do not leak timing information about the tokens themselves.
+		verifier.tokens[sha256.Sum256([]byte(token.Token))] = Principal{
+			Name: token.Name,
+			Grants: []Grant{
+				{Scopes: token.Scopes, Apps: token.Apps},
+			},
+		}
+	}
+
+	return verifier
+}
+
+// Verify the token, returning the Principal it describes. Returns ErrInvalidToken if the token is not known.
+func (tv *TokenVerifier) Verify(_ context.Context, token string) (Principal, error) {
+	principal, ok := tv.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return Principal{}, ErrInvalidToken
+	}
+
+	return principal, nil
+}
+
+// Allows returns true if any of the Principal's grants permit the operation described by the Scope against the
+// specified application. The app may be blank for operations that do not target a single application.
+func (p Principal) Allows(scope Scope, app string) bool {
+	return slices.ContainsFunc(p.Grants, func(g Grant) bool {
+		return g.Allows(scope, app)
+	})
+}
+
+// Allows returns true if the Grant permits the operation described by the Scope against the specified application.
+// The app may be blank for operations that do not target a single application.
+func (g Grant) Allows(scope Scope, app string) bool {
+	if !slices.Contains(g.Scopes, scope) {
+		return false
+	}
+
+	if app == "" {
+		return true
+	}
+
+	return slices.ContainsFunc(g.Apps, func(pattern string) bool {
+		match, err := path.Match(pattern, app)
+		return err == nil && match
+	})
+}
+
+// Chain returns a Verifier implementation that tries each of the provided Verifier implementations in order, returning
+// the Principal from the first that accepts the token. Returns ErrInvalidToken if none of them accept it.
+func Chain(verifiers ...Verifier) Verifier {
+	return chain(verifiers)
+}
+
+type (
+	chain []Verifier
+)
+
+func (c chain) Verify(ctx context.Context, token string) (Principal, error) {
+	for _, verifier := range c {
+		principal, err := verifier.Verify(ctx, token)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			continue
+		case err != nil:
+			return Principal{}, err
+		default:
+			return principal, nil
+		}
+	}
+
+	return Principal{}, ErrInvalidToken
+}
+
+// Middleware is a server.Middleware implementation that enables authentication of the profile server's endpoints.
+// Endpoints registered using Require then need a bearer token within the Authorization header that the Verifier
+// accepts and is scoped to the requested operation and application. Other endpoints, such as health checks and
+// metrics, are not authenticated.
+func Middleware(verifier Verifier) server.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := r.WithContext(context.WithValue(r.Context(), verifierKey{}, verifier))
+			next.ServeHTTP(w, req)
+
+			// The http.ServeMux sets the matched pattern on the request it was given, so it is copied back for any
+			// middleware wrapping this one.
+			r.Pattern = req.Pattern
+		})
+	}
+}
+
+// Require returns an http.Handler that only calls the handler if the request is permitted to perform the operation
+// described by the Scope against the application in its "app" path value. Requests are only checked when
+// authentication is enabled using Middleware.
+func Require(scope Scope, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifier, ok := r.Context().Value(verifierKey{}).(Verifier)
+		if !ok {
+			handler(w, r)
+			return
+		}
+
+		authorize(verifier, scope, handler).ServeHTTP(w, r)
+	})
+}
+
+func authorize(verifier Verifier, scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			api.ErrorResponse(ctx, w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		principal, err := verifier.Verify(ctx, token)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusUnauthorized)
+			return
+		case err != nil:
+			logger.FromContext(ctx).With(slog.String("error", err.Error())).ErrorContext(ctx, "failed to verify token")
+			api.ErrorResponse(ctx, w, "failed to verify token", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(ctx).With(
+			slog.String("auth.principal", principal.Name),
+			slog.String("auth.scope", string(scope)),
+		).DebugContext(ctx, "authenticated request")
+
+		if !principal.Allows(scope, r.PathValue("app")) {
+			api.ErrorResponse(ctx, w, "token is not permitted to perform this operation", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ToContext(ctx, principal)))
+	})
+}
+
+type (
+	ctxKey      struct{}
+	verifierKey struct{}
+)
+
+// ToContext returns a context.Context that contains the Principal.
+func ToContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, principal)
+}
+
+// FromContext returns the Principal that authenticated the request the context.Context belongs to. Returns false if
+// the request was not authenticated.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ctxKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,217 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/auth"
+	"github.com/davidsbond/autopgo/internal/metrics"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	verifier := auth.NewTokenVerifier([]auth.Token{
+		{
+			Name:   "scraper",
+			Token:  "scraper-token",
+			Scopes: []auth.Scope{auth.ScopeUpload},
+			Apps:   []string{"orders-*"},
+		},
+		{
+			Name:   "admin",
+			Token:  "admin-token",
+			Scopes: []auth.Scope{auth.ScopeUpload, auth.ScopeDownload, auth.ScopeList, auth.ScopeDelete},
+			Apps:   []string{"*"},
+		},
+		{
+			Name:   "release",
+			Token:  "release-token",
+			Scopes: []auth.Scope{auth.ScopePromote},
+			Apps:   []string{"*"},
+		},
+	})
+
+	tt := []struct {
+		Name      string
+		Method    string
+		Path      string
+		Token     string
+		Expected  int
+		Principal string
+	}{
+		{
+			Name:     "allows scoped upload",
+			Method:   http.MethodPost,
+			Path:     "/api/profile/orders-api",
+			Token:    "scraper-token",
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "allows scoped upload to a channel",
+			Method:   http.MethodPost,
+			Path:     "/api/profile/orders-api/prod",
+			Token:    "scraper-token",
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "rejects upload for another app",
+			Method:   http.MethodPost,
+			Path:     "/api/profile/payments",
+			Token:    "scraper-token",
+			Expected: http.StatusForbidden,
+		},
+		{
+			Name:     "rejects operation outside of scope",
+			Method:   http.MethodDelete,
+			Path:     "/api/profile/orders-api",
+			Token:    "scraper-token",
+			Expected: http.StatusForbidden,
+		},
+		{
+			Name:      "allows promote",
+			Method:    http.MethodPost,
+			Path:      "/api/profile/orders-api/promote",
+			Token:     "release-token",
+			Expected:  http.StatusOK,
+			Principal: "release",
+		},
+		{
+			Name:     "rejects missing token",
+			Method:   http.MethodGet,
+			Path:     "/api/profile",
+			Expected: http.StatusUnauthorized,
+		},
+		{
+			Name:     "rejects unknown token",
+			Method:   http.MethodGet,
+			Path:     "/api/profile/orders-api",
+			Token:    "unknown",
+			Expected: http.StatusUnauthorized,
+		},
+		{
+			Name:     "allows list",
+			Method:   http.MethodGet,
+			Path:     "/api/profile",
+			Token:    "admin-token",
+			Expected: http.StatusOK,
+		},
+		{
+			Name:     "does not authenticate health checks",
+			Method:   http.MethodGet,
+			Path:     "/api/health",
+			Expected: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var principal auth.Principal
+			ok := func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = auth.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("GET /api/profile", auth.Require(auth.ScopeList, ok))
+			mux.Handle("GET /api/profile/{app}", auth.Require(auth.ScopeDownload, ok))
+			mux.Handle("POST /api/profile/{app}", auth.Require(auth.ScopeUpload, ok))
+			mux.Handle("POST /api/profile/{app}/{channel}", auth.Require(auth.ScopeUpload, ok))
+			mux.Handle("DELETE /api/profile/{app}", auth.Require(auth.ScopeDelete, ok))
+			mux.Handle("POST /api/profile/{app}/promote", auth.Require(auth.ScopePromote, ok))
+			mux.HandleFunc("GET /api/health", ok)
+
+			handler := auth.Middleware(verifier)(mux)
+
+			r := httptest.NewRequest(tc.Method, tc.Path, nil)
+			if tc.Token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.Token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.EqualValues(t, tc.Expected, w.Code)
+			if tc.Principal != "" {
+				assert.EqualValues(t, tc.Principal, principal.Name)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Parallel()
+
+	handler := auth.Require(auth.ScopeDelete, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("it should not authenticate requests without the middleware", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/profile/orders-api", nil))
+
+		assert.EqualValues(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("it should authenticate requests with the middleware", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		auth.Middleware(auth.NewTokenVerifier(nil))(handler).
+			ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/profile/orders-api", nil))
+
+		assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestMiddleware_Pattern(t *testing.T) {
+	t.Parallel()
+
+	const pattern = "GET /auth-test/{app}"
+
+	mux := http.NewServeMux()
+	mux.Handle(pattern, auth.Require(auth.ScopeDownload, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	verifier := auth.NewTokenVerifier([]auth.Token{
+		{
+			Token:  "token",
+			Scopes: []auth.Scope{auth.ScopeDownload},
+			Apps:   []string{"*"},
+		},
+	})
+
+	// Authentication replaces the request, so middleware wrapping it must still see the pattern matched by the mux.
+	handler := metrics.Middleware()(auth.Middleware(verifier)(mux))
+
+	r := httptest.NewRequest(http.MethodGet, "/auth-test/orders-api", nil)
+	r.Header.Set("Authorization", "Bearer token")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, pattern, r.Pattern)
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_http_requests_total", map[string]string{"route": pattern, "code": "200"}))
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	verifier := auth.Chain(
+		auth.NewTokenVerifier([]auth.Token{{Name: "first", Token: "first"}}),
+		auth.NewTokenVerifier([]auth.Token{{Name: "second", Token: "second"}}),
+	)
+
+	principal, err := verifier.Verify(context.Background(), "second")
+	require.NoError(t, err)
+	assert.EqualValues(t, "second", principal.Name)
+
+	_, err = verifier.Verify(context.Background(), "third")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
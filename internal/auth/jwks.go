@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/davidsbond/autopgo/internal/closers"
+)
+
+type (
+	// The keySet type contains the public keys from a JSON Web Key Set, used to verify the signatures of JWTs. Keys
+	// are fetched lazily and refreshed when a token references a key that is not known.
+	keySet struct {
+		issuer   string
+		location string
+		http     *http.Client
+		group    singleflight.Group
+
+		mux      sync.Mutex
+		keys     map[string]crypto.PublicKey
+		err      error
+		failures int
+		next     time.Time
+	}
+
+	jsonWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+const (
+	// The minimum time between fetches of a key set, prevents tokens with unknown key ids from causing a fetch for
+	// every request.
+	keySetRefreshInterval = time.Minute
+	// The time to wait before fetching a key set again after a failed fetch, doubled for each consecutive failure up
+	// to keySetMaxRetryInterval.
+	keySetRetryInterval    = 5 * time.Second
+	keySetMaxRetryInterval = 5 * time.Minute
+)
+
+func newKeySet(issuer, location string, client *http.Client) *keySet {
+	return &keySet{
+		issuer:   issuer,
+		location: location,
+		http:     client,
+	}
+}
+
+// Keys returns the keys within the set. If kid is non-empty and the key set does not contain it, the key set is
+// fetched again. Concurrent callers share a single fetch, which is performed without holding the lock so that tokens
+// signed by known keys can be verified while it is in progress.
+func (ks *keySet) Keys(ctx context.Context, kid string) (map[string]crypto.PublicKey, error) {
+	if keys, ok, err := ks.cached(kid); ok {
+		return keys, err
+	}
+
+	// The fetch is shared, so it must not be cancelled along with the request that started it.
+	result := ks.group.DoChan("", func() (any, error) {
+		ks.refresh(context.WithoutCancel(ctx))
+		return nil, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-result:
+		return ks.current()
+	}
+}
+
+// cached returns the keys, or the error from the last fetch, if the key set should not be fetched again for the key id.
+func (ks *keySet) cached(kid string) (map[string]crypto.PublicKey, bool, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	_, known := ks.keys[kid]
+	switch {
+	case ks.keys != nil && (kid == "" || known):
+		return ks.keys, true, nil
+	case time.Now().Before(ks.next):
+		keys, err := ks.result()
+		return keys, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+func (ks *keySet) current() (map[string]crypto.PublicKey, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	return ks.result()
+}
+
+// result returns the keys from the last successful fetch, or the error from the last fetch if none have succeeded. The
+// previous keys remain in use until a fetch succeeds. Callers must hold the lock.
+func (ks *keySet) result() (map[string]crypto.PublicKey, error) {
+	if ks.keys == nil {
+		return nil, ks.err
+	}
+
+	return ks.keys, nil
+}
+
+// refresh fetches the key set, discovering its location first if required. Failed fetches are retried with an
+// exponential backoff, so an unavailable key set is not requested for every token. Refreshes never run concurrently,
+// so the location is only accessed here.
+func (ks *keySet) refresh(ctx context.Context) {
+	keys, err := ks.load(ctx)
+
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	if err != nil {
+		ks.failures++
+		ks.err = err
+		ks.next = time.Now().Add(min(keySetRetryInterval<<min(ks.failures-1, 10), keySetMaxRetryInterval))
+		return
+	}
+
+	ks.keys = keys
+	ks.err = nil
+	ks.failures = 0
+	ks.next = time.Now().Add(keySetRefreshInterval)
+}
+
+func (ks *keySet) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if ks.location == "" {
+		location, err := discoverJWKS(ctx, ks.http, ks.issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover key set for %s: %w", ks.issuer, err)
+		}
+
+		ks.location = location
+	}
+
+	keys, err := ks.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key set from %s: %w", ks.location, err)
+	}
+
+	return keys, nil
+}
+
+func (ks *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(ks.location, "http://") || strings.HasPrefix(ks.location, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.location, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := ks.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			closers.Close(ctx, resp.Body)
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		body = resp.Body
+	} else {
+		f, err := os.Open(strings.TrimPrefix(ks.location, "file://"))
+		if err != nil {
+			return nil, err
+		}
+
+		body = f
+	}
+	defer closers.Close(ctx, body)
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Keys intended for encryption cannot be used to verify signatures.
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid point size")
+		}
+
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	default:
+		// Unsupported key types are ignored so that a key set containing them can still be used.
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/davidsbond/autopgo/internal/closers"
+)
+
+type (
+	// The Issuer type describes a trusted issuer of JSON Web Tokens, such as an OIDC provider, and how the claims
+	// within its tokens map to the operations they are permitted to perform.
+	Issuer struct {
+		// The expected value of the "iss" claim.
+		Issuer string `json:"issuer"`
+		// The expected value of the "aud" claim.
+		Audience string `json:"audience"`
+		// The location of the JSON Web Key Set used to verify token signatures. This can be an HTTP(S) URL or a path
+		// to a local file. When blank, it is obtained using OIDC discovery on the Issuer.
+		JWKS string `json:"jwks,omitempty"`
+		// The claim used to name the caller in logs. Defaults to "sub", which is also used for tokens that do not
+		// contain the claim. Tokens containing neither are rejected.
+		NameClaim string `json:"nameClaim,omitempty"`
+		// Rules that determine the operations tokens can perform based on their claims.
+		Rules []Rule `json:"rules"`
+	}
+
+	// The Rule type describes the Grant given to tokens whose claims match.
+	Rule struct {
+		// Patterns that claim values must match for the rule to apply, keyed by claim name. Patterns use the syntax
+		// described in path.Match. For claims containing multiple values, such as "aud", only one value must match.
+		Claims map[string]string `json:"claims"`
+		Grant
+	}
+
+	// The JWTVerifier type is a Verifier implementation that verifies JSON Web Tokens issued by trusted issuers.
+	JWTVerifier struct {
+		issuers map[string]issuer
+	}
+
+	issuer struct {
+		config Issuer
+		keys   *keySet
+	}
+)
+
+const (
+	// The allowed difference in clocks between the server and token issuers.
+	jwtLeeway = 30 * time.Second
+)
+
+// LoadIssuersFile reads the JSON-encoded issuer configuration contained within the file at the specified location.
+func LoadIssuersFile(ctx context.Context, location string) ([]Issuer, error) {
+	f, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Close(ctx, f)
+
+	var issuers []Issuer
+	if err = json.NewDecoder(f).Decode(&issuers); err != nil {
+		return nil, err
+	}
+
+	for _, iss := range issuers {
+		if iss.Issuer == "" {
+			return nil, errors.New("issuers must not be empty")
+		}
+
+		if iss.Audience == "" {
+			return nil, fmt.Errorf("issuer %q has no audience", iss.Issuer)
+		}
+
+		for _, rule := range iss.Rules {
+			if err = rule.Grant.validate(); err != nil {
+				return nil, fmt.Errorf("issuer %q has an invalid rule: %w", iss.Issuer, err)
+			}
+
+			for name, pattern := range rule.Claims {
+				if _, err = path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("issuer %q has an invalid pattern for claim %q: %w", iss.Issuer, name, err)
+				}
+			}
+		}
+	}
+
+	return issuers, nil
+}
+
+// NewJWTVerifier returns a new instance of the JWTVerifier type that will accept tokens from the provided issuers.
+// Key sets are fetched when the first token from each issuer is verified.
+func NewJWTVerifier(issuers []Issuer) *JWTVerifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	verifier := &JWTVerifier{
+		issuers: make(map[string]issuer, len(issuers)),
+	}
+
+	for _, iss := range issuers {
+		if iss.NameClaim == "" {
+			iss.NameClaim = "sub"
+		}
+
+		verifier.issuers[iss.Issuer] = issuer{
+			config: iss,
+			keys:   newKeySet(iss.Issuer, iss.JWKS, client),
+		}
+	}
+
+	return verifier
+}
+
+// Verify the token, returning a Principal whose grants are determined by the issuer's rules that match the token's
+// claims. Returns ErrInvalidToken if the token is malformed, expired, from an unknown issuer or has an invalid
+// signature.
+func (jv *JWTVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	parser := jwt.NewParser()
+
+	unverified, _, err := parser.ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	name, err := unverified.Claims.GetIssuer()
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	iss, ok := jv.issuers[name]
+	if !ok {
+		return Principal{}, ErrInvalidToken
+	}
+
+	kid, _ := unverified.Header["kid"].(string)
+	keys, err := iss.keys.Keys(ctx, kid)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, keyFunc(keys),
+		jwt.WithIssuer(iss.config.Issuer),
+		jwt.WithAudience(iss.config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+	)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	caller := claimString(claims, iss.config.NameClaim)
+	if caller == "" {
+		caller = claimString(claims, "sub")
+	}
+
+	if caller == "" {
+		return Principal{}, fmt.Errorf("%w: token has no %q or \"sub\" claim", ErrInvalidToken, iss.config.NameClaim)
+	}
+
+	principal := Principal{
+		Name: caller,
+	}
+
+	for _, rule := range iss.config.Rules {
+		if rule.matches(claims) {
+			principal.Grants = append(principal.Grants, rule.Grant)
+		}
+	}
+
+	return principal, nil
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, ok := claims[name]
+	if !ok || value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func keyFunc(keys map[string]crypto.PublicKey) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid != "" {
+			key, ok := keys[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %q", kid)
+			}
+
+			return key, nil
+		}
+
+		// Without a key id, each key in the set is tried in turn.
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, key)
+		}
+
+		return set, nil
+	}
+}
+
+func (r Rule) matches(claims jwt.MapClaims) bool {
+	for name, pattern := range r.Claims {
+		var values []string
+		switch value := claims[name].(type) {
+		case nil:
+			return false
+		case []any:
+			for _, v := range value {
+				values = append(values, fmt.Sprint(v))
+			}
+		default:
+			values = append(values, fmt.Sprint(value))
+		}
+
+		matched := slices.ContainsFunc(values, func(v string) bool {
+			match, err := path.Match(pattern, v)
+			return err == nil && match
+		})
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func discoverJWKS(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+
+	var config struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return "", err
+	}
+
+	if config.JWKSURI == "" {
+		return "", fmt.Errorf("no jwks_uri in discovery document from %s", u)
+	}
+
+	return config.JWKSURI, nil
+}
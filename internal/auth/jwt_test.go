@@ -0,0 +1,349 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/auth"
+)
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// The "discovered" issuer serves its key set via OIDC discovery, while the "local" issuer's key set is read from
+	// a file.
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/jwks"}))
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(jwks(t, map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey})))
+	})
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	data, err := json.Marshal(jwks(t, map[string]crypto.PublicKey{"ec": &ecKey.PublicKey}))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(jwksFile, data, 0o600))
+
+	verifier := auth.NewJWTVerifier([]auth.Issuer{
+		{
+			Issuer:   server.URL,
+			Audience: "autopgo",
+			Rules: []auth.Rule{
+				{
+					Claims: map[string]string{"repository": "acme/orders-*"},
+					Grant: auth.Grant{
+						Scopes: []auth.Scope{auth.ScopeUpload},
+						Apps:   []string{"orders-*"},
+					},
+				},
+				{
+					Claims: map[string]string{"groups": "admins"},
+					Grant: auth.Grant{
+						Scopes: []auth.Scope{auth.ScopeDelete},
+						Apps:   []string{"*"},
+					},
+				},
+			},
+		},
+		{
+			Issuer:    "https://kubernetes.default.svc",
+			Audience:  "autopgo",
+			JWKS:      jwksFile,
+			NameClaim: "email",
+			Rules: []auth.Rule{
+				{
+					Claims: map[string]string{"sub": "system:serviceaccount:autopgo:*"},
+					Grant: auth.Grant{
+						Scopes: []auth.Scope{auth.ScopeDownload},
+						Apps:   []string{"*"},
+					},
+				},
+			},
+		},
+	})
+
+	now := time.Now()
+
+	tt := []struct {
+		Name         string
+		Token        string
+		ExpectsError bool
+		Expected     auth.Principal
+	}{
+		{
+			Name: "discovered issuer",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{
+				"iss":        server.URL,
+				"aud":        "autopgo",
+				"sub":        "repo:acme/orders-api",
+				"exp":        now.Add(time.Hour).Unix(),
+				"repository": "acme/orders-api",
+				"groups":     []string{"developers", "admins"},
+			}),
+			Expected: auth.Principal{
+				Name: "repo:acme/orders-api",
+				Grants: []auth.Grant{
+					{Scopes: []auth.Scope{auth.ScopeUpload}, Apps: []string{"orders-*"}},
+					{Scopes: []auth.Scope{auth.ScopeDelete}, Apps: []string{"*"}},
+				},
+			},
+		},
+		{
+			Name: "local key set",
+			Token: sign(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"iss": "https://kubernetes.default.svc",
+				"aud": []string{"autopgo"},
+				"sub": "system:serviceaccount:autopgo:ci",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+			Expected: auth.Principal{
+				Name: "system:serviceaccount:autopgo:ci",
+				Grants: []auth.Grant{
+					{Scopes: []auth.Scope{auth.ScopeDownload}, Apps: []string{"*"}},
+				},
+			},
+		},
+		{
+			Name: "name claim",
+			Token: sign(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"iss":   "https://kubernetes.default.svc",
+				"aud":   "autopgo",
+				"sub":   "system:serviceaccount:autopgo:ci",
+				"email": "ci@example.com",
+				"exp":   now.Add(time.Hour).Unix(),
+			}),
+			Expected: auth.Principal{
+				Name: "ci@example.com",
+				Grants: []auth.Grant{
+					{Scopes: []auth.Scope{auth.ScopeDownload}, Apps: []string{"*"}},
+				},
+			},
+		},
+		{
+			Name: "no name",
+			Token: sign(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"iss": "https://kubernetes.default.svc",
+				"aud": "autopgo",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+			ExpectsError: true,
+		},
+		{
+			Name: "no matching rules",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{
+				"iss":        server.URL,
+				"aud":        "autopgo",
+				"sub":        "repo:acme/payments",
+				"exp":        now.Add(time.Hour).Unix(),
+				"repository": "acme/payments",
+			}),
+			Expected: auth.Principal{
+				Name: "repo:acme/payments",
+			},
+		},
+		{
+			Name: "wrong audience",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{
+				"iss": server.URL,
+				"aud": "something-else",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+			ExpectsError: true,
+		},
+		{
+			Name: "expired",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{
+				"iss": server.URL,
+				"aud": "autopgo",
+				"exp": now.Add(-time.Hour).Unix(),
+			}),
+			ExpectsError: true,
+		},
+		{
+			Name: "unknown issuer",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, jwt.MapClaims{
+				"iss": "https://example.com",
+				"aud": "autopgo",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+			ExpectsError: true,
+		},
+		{
+			Name: "invalid signature",
+			Token: sign(t, jwt.SigningMethodRS256, "rsa", otherKey, jwt.MapClaims{
+				"iss": server.URL,
+				"aud": "autopgo",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+			ExpectsError: true,
+		},
+		{
+			Name:         "not a jwt",
+			Token:        "test",
+			ExpectsError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := verifier.Verify(context.Background(), tc.Token)
+			if tc.ExpectsError {
+				assert.ErrorIs(t, err, auth.ErrInvalidToken)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestJWTVerifier_KeySetFailures(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// The key set is slow to respond and unavailable, so concurrent tokens should share a single request and the
+	// failure should be reused by later tokens rather than fetching the key set again.
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWTVerifier([]auth.Issuer{
+		{
+			Issuer:   "https://example.com",
+			Audience: "autopgo",
+			JWKS:     server.URL,
+		},
+	})
+
+	token := sign(t, jwt.SigningMethodRS256, "rsa", key, jwt.MapClaims{
+		"iss": "https://example.com",
+		"aud": "autopgo",
+		"sub": "test",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			_, err := verifier.Verify(context.Background(), token)
+			assert.Error(t, err)
+		})
+	}
+	wg.Wait()
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestLoadIssuersFile(t *testing.T) {
+	t.Parallel()
+
+	actual, err := auth.LoadIssuersFile(context.Background(), "testdata/issuers.json")
+	require.NoError(t, err)
+
+	expected := []auth.Issuer{
+		{
+			Issuer:   "https://token.actions.githubusercontent.com",
+			Audience: "autopgo",
+			Rules: []auth.Rule{
+				{
+					Claims: map[string]string{
+						"repository": "acme/orders-*",
+						"ref":        "refs/heads/main",
+					},
+					Grant: auth.Grant{
+						Scopes: []auth.Scope{auth.ScopeDownload},
+						Apps:   []string{"orders-*"},
+					},
+				},
+			},
+		},
+	}
+
+	assert.EqualValues(t, expected, actual)
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func jwks(t *testing.T, keys map[string]crypto.PublicKey) map[string]any {
+	t.Helper()
+
+	encode := base64.RawURLEncoding.EncodeToString
+
+	set := make([]map[string]string, 0, len(keys))
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set = append(set, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   encode(k.N.Bytes()),
+				"e":   encode(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			point, err := k.Bytes()
+			require.NoError(t, err)
+
+			size := (len(point) - 1) / 2
+			set = append(set, map[string]string{
+				"kty": "EC",
+				"kid": kid,
+				"crv": "P-256",
+				"x":   encode(point[1 : 1+size]),
+				"y":   encode(point[1+size:]),
+			})
+		}
+	}
+
+	return map[string]any{"keys": set}
+}
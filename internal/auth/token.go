@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/davidsbond/autopgo/internal/closers"
+)
+
+type (
+	// The Token type describes a single bearer token and the operations it is permitted to perform.
+	Token struct {
+		// A name describing the token's owner, used for logging.
+		Name string `json:"name"`
+		// The bearer token value.
+		Token string `json:"token"`
+		// The operations the token can perform.
+		Scopes []Scope `json:"scopes"`
+		// Patterns for the application names the token can perform operations against. Patterns use the syntax
+		// described in path.Match, so "*" permits all applications.
+		Apps []string `json:"apps"`
+	}
+)
+
+const (
+	// SecretKey is the key within a Kubernetes Secret whose value contains the JSON-encoded tokens.
+	SecretKey = "tokens.json"
+)
+
+// LoadTokensFile reads the JSON-encoded tokens contained within the file at the specified location.
+func LoadTokensFile(ctx context.Context, location string) ([]Token, error) {
+	f, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Close(ctx, f)
+
+	return decodeTokens(json.NewDecoder(f))
+}
+
+// LoadTokensSecret reads the JSON-encoded tokens stored within the Kubernetes Secret of the specified name in the
+// specified namespace. The tokens are expected to be stored under the SecretKey key.
+func LoadTokensSecret(ctx context.Context, client kubernetes.Interface, namespace, name string) ([]Token, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data[SecretKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no %s key", namespace, name, SecretKey)
+	}
+
+	return decodeTokens(json.NewDecoder(bytes.NewReader(data)))
+}
+
+func decodeTokens(decoder *json.Decoder) ([]Token, error) {
+	var tokens []Token
+	if err := decoder.Decode(&tokens); err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		if token.Token == "" {
+			return nil, errors.New("tokens must not be empty")
+		}
+
+		grant := Grant{Scopes: token.Scopes, Apps: token.Apps}
+		if err := grant.validate(); err != nil {
+			return nil, fmt.Errorf("token %q is invalid: %w", token.Name, err)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (g Grant) validate() error {
+	for _, scope := range g.Scopes {
+		switch scope {
+		case ScopeUpload, ScopeDownload, ScopeList, ScopeDelete, ScopePromote:
+			continue
+		default:
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	for _, app := range g.Apps {
+		if _, err := path.Match(app, ""); err != nil {
+			return fmt.Errorf("invalid app pattern %q: %w", app, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package auth_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/davidsbond/autopgo/internal/auth"
+)
+
+var expectedTokens = []auth.Token{
+	{
+		Name:   "scraper",
+		Token:  "scraper-token",
+		Scopes: []auth.Scope{auth.ScopeUpload},
+		Apps:   []string{"orders-*"},
+	},
+	{
+		Name:   "ci",
+		Token:  "ci-token",
+		Scopes: []auth.Scope{auth.ScopeDownload, auth.ScopeList},
+		Apps:   []string{"*"},
+	},
+}
+
+func TestLoadTokensFile(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		Location     string
+		ExpectsError bool
+		Expected     []auth.Token
+	}{
+		{
+			Name:     "success",
+			Location: "testdata/tokens.json",
+			Expected: expectedTokens,
+		},
+		{
+			Name:         "file does not exist",
+			Location:     "testdata/missing.json",
+			ExpectsError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := auth.LoadTokensFile(context.Background(), tc.Location)
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestLoadTokensSecret(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("testdata/tokens.json")
+	require.NoError(t, err)
+
+	tt := []struct {
+		Name         string
+		Objects      []runtime.Object
+		ExpectsError bool
+		Expected     []auth.Token
+	}{
+		{
+			Name: "success",
+			Objects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "tokens", Namespace: "autopgo"},
+					Data:       map[string][]byte{auth.SecretKey: data},
+				},
+			},
+			Expected: expectedTokens,
+		},
+		{
+			Name: "invalid scope",
+			Objects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "tokens", Namespace: "autopgo"},
+					Data: map[string][]byte{
+						auth.SecretKey: []byte(`[{"name":"test","token":"test","scopes":["admin"]}]`),
+					},
+				},
+			},
+			ExpectsError: true,
+		},
+		{
+			Name: "missing key",
+			Objects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "tokens", Namespace: "autopgo"},
+				},
+			},
+			ExpectsError: true,
+		},
+		{
+			Name:         "secret does not exist",
+			ExpectsError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := fake.NewClientset(tc.Objects...)
+
+			actual, err := auth.LoadTokensSecret(context.Background(), client, "autopgo", "tokens")
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
@@ -9,6 +9,15 @@ import (
 	"log/slog"
 	"time"
 
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azblobblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
 	"gocloud.dev/blob"
 	_ "gocloud.dev/blob/azureblob"
 	_ "gocloud.dev/blob/gcsblob"
@@ -27,6 +36,18 @@ type (
 	// The Filter function allows callers of Bucket.List to programmatically filter results.
 	Filter func(obj Object) bool
 
+	// The ListOptions type contains parameters used to limit the objects returned by Bucket.List. The Prefix and
+	// Delimiter are handled by the blob storage provider, so should be preferred over a Filter wherever possible.
+	ListOptions struct {
+		// Only objects whose keys begin with the prefix are returned.
+		Prefix string
+		// When set, objects whose keys contain the delimiter after the prefix are grouped into a single directory
+		// object, whose key is the prefix up to and including the first delimiter. Directory objects have IsDir set.
+		Delimiter string
+		// Only objects matching the filter are returned. Provide a nil Filter to return all objects.
+		Filter Filter
+	}
+
 	// The Object type contains metadata on an object within the blob store.
 	Object struct {
 		// The object's key.
@@ -35,12 +56,17 @@ type (
 		Size int64
 		// When the object was last modified.
 		LastModified time.Time
+		// Whether the object is a directory of other objects. Directories are only returned when listing with a
+		// delimiter and have no size or modification time.
+		IsDir bool
 	}
 )
 
 var (
 	// ErrNotExist is the error given when performing an action against an object that does not exist.
 	ErrNotExist = errors.New("does not exist")
+	// ErrExist is the error given when creating an object that already exists.
+	ErrExist = errors.New("already exists")
 )
 
 // NewBucket returns a new instance of the Bucket type that performs actions against a blob storage provider. The
@@ -89,6 +115,76 @@ func (b *Bucket) NewWriter(ctx context.Context, path string) (io.WriteCloser, er
 	return b.blob.NewWriter(ctx, path, &blob.WriterOptions{})
 }
 
+// Create writes data to the blob store at a given path only if no object exists there, returning ErrExist if one
+// does. Where the provider supports conditional writes (AWS, GCP & Azure) the check and write are performed
+// atomically, so only one of many concurrent callers creating the same path succeeds. Other providers only check for
+// the object before writing it.
+func (b *Bucket) Create(ctx context.Context, path string, data []byte) error {
+	logger.FromContext(ctx).
+		With(slog.String("path", path)).
+		DebugContext(ctx, "creating object")
+
+	exists, err := b.blob.Exists(ctx, path)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return ErrExist
+	}
+
+	err = b.blob.WriteAll(ctx, path, data, &blob.WriterOptions{BeforeWrite: ifNotExist})
+	switch {
+	case isConditionNotMet(err):
+		return ErrExist
+	case err != nil:
+		return err
+	default:
+		return nil
+	}
+}
+
+// ifNotExist is used as the blob.WriterOptions.BeforeWrite function to prevent writes from replacing an existing
+// object for those providers that support conditional writes.
+func ifNotExist(as func(any) bool) error {
+	var (
+		object  **storage.ObjectHandle
+		input   *s3.PutObjectInput
+		options *azblob.UploadStreamOptions
+	)
+
+	switch {
+	case as(&object):
+		*object = (*object).If(storage.Conditions{DoesNotExist: true})
+	case as(&input):
+		input.IfNoneMatch = aws.String("*")
+	case as(&options):
+		options.AccessConditions = &azblob.AccessConditions{
+			ModifiedAccessConditions: &azblobblob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)},
+		}
+	}
+
+	return nil
+}
+
+// isConditionNotMet returns true if the error is the result of a conditional write against an object that already
+// exists.
+func isConditionNotMet(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if gcerrors.Code(err) == gcerrors.FailedPrecondition {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict"
+	}
+
+	return bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet)
+}
+
 // Delete an object at a specified path. Returns ErrNotExist if the object does not exist.
 func (b *Bucket) Delete(ctx context.Context, path string) error {
 	logger.FromContext(ctx).
@@ -106,11 +202,14 @@ func (b *Bucket) Delete(ctx context.Context, path string) error {
 	}
 }
 
-// List objects within the bucket that match the given Filter. Provide a nil Filter to return all objects. This
-// method returns an iterator so is used with a range statement. The second range parameter is an error that must be
-// checked on each iteration.
-func (b *Bucket) List(ctx context.Context, filter Filter) iter.Seq2[Object, error] {
-	iterator := b.blob.List(&blob.ListOptions{})
+// List objects within the bucket that match the given ListOptions. Provide empty ListOptions to return all objects.
+// This method returns an iterator so is used with a range statement. The second range parameter is an error that must
+// be checked on each iteration.
+func (b *Bucket) List(ctx context.Context, opts ListOptions) iter.Seq2[Object, error] {
+	iterator := b.blob.List(&blob.ListOptions{
+		Prefix:    opts.Prefix,
+		Delimiter: opts.Delimiter,
+	})
 
 	return func(yield func(Object, error) bool) {
 		for {
@@ -119,22 +218,18 @@ func (b *Bucket) List(ctx context.Context, filter Filter) iter.Seq2[Object, erro
 			case errors.Is(err, io.EOF):
 				return
 			case err != nil:
-				if !yield(Object{}, err) {
-					return
-				}
-			}
-
-			if item.IsDir {
-				continue
+				yield(Object{}, err)
+				return
 			}
 
 			obj := Object{
 				Key:          item.Key,
 				Size:         item.Size,
 				LastModified: item.ModTime,
+				IsDir:        item.IsDir,
 			}
 
-			if filter != nil && !filter(obj) {
+			if opts.Filter != nil && !opts.Filter(obj) {
 				continue
 			}
 
@@ -145,6 +240,24 @@ func (b *Bucket) List(ctx context.Context, filter Filter) iter.Seq2[Object, erro
 	}
 }
 
+// Stat returns metadata on the object at the given path. Returns ErrNotExist if there is no object at the specified
+// path.
+func (b *Bucket) Stat(ctx context.Context, path string) (Object, error) {
+	attrs, err := b.blob.Attributes(ctx, path)
+	switch {
+	case gcerrors.Code(err) == gcerrors.NotFound:
+		return Object{}, ErrNotExist
+	case err != nil:
+		return Object{}, err
+	default:
+		return Object{
+			Key:          path,
+			Size:         attrs.Size,
+			LastModified: attrs.ModTime,
+		}, nil
+	}
+}
+
 // Exists returns true if an object exists at the given path.
 func (b *Bucket) Exists(ctx context.Context, path string) (bool, error) {
 	return b.blob.Exists(ctx, path)
@@ -72,7 +72,7 @@ func TestBucket_List_Integration(t *testing.T) {
 
 	t.Run("it should list without filters", func(t *testing.T) {
 		items := make([]blob.Object, 0)
-		for item, err := range bucket.List(ctx, nil) {
+		for item, err := range bucket.List(ctx, blob.ListOptions{}) {
 			require.NoError(t, err)
 			items = append(items, item)
 		}
@@ -86,13 +86,44 @@ func TestBucket_List_Integration(t *testing.T) {
 		alwaysExclude := func(o blob.Object) bool { return false }
 
 		items := make([]blob.Object, 0)
-		for item, err := range bucket.List(ctx, alwaysExclude) {
+		for item, err := range bucket.List(ctx, blob.ListOptions{Filter: alwaysExclude}) {
 			require.NoError(t, err)
 			items = append(items, item)
 		}
 
 		assert.Len(t, items, 0)
 	})
+
+	testData(t, bucket, "test-dir/a", []byte("hello world"))
+	testData(t, bucket, "test-dir/nested/b", []byte("hello world"))
+
+	t.Run("it should list with a prefix", func(t *testing.T) {
+		items := make([]blob.Object, 0)
+		for item, err := range bucket.List(ctx, blob.ListOptions{Prefix: "test-dir/"}) {
+			require.NoError(t, err)
+			items = append(items, item)
+		}
+
+		if assert.Len(t, items, 2) {
+			assert.Equal(t, "test-dir/a", items[0].Key)
+			assert.Equal(t, "test-dir/nested/b", items[1].Key)
+		}
+	})
+
+	t.Run("it should list with a delimiter", func(t *testing.T) {
+		items := make([]blob.Object, 0)
+		for item, err := range bucket.List(ctx, blob.ListOptions{Prefix: "test-dir/", Delimiter: "/"}) {
+			require.NoError(t, err)
+			items = append(items, item)
+		}
+
+		if assert.Len(t, items, 2) {
+			assert.Equal(t, "test-dir/a", items[0].Key)
+			assert.False(t, items[0].IsDir)
+			assert.Equal(t, "test-dir/nested/", items[1].Key)
+			assert.True(t, items[1].IsDir)
+		}
+	})
 }
 
 func TestBucket_Exists_Integration(t *testing.T) {
@@ -119,6 +150,59 @@ func TestBucket_Exists_Integration(t *testing.T) {
 	})
 }
 
+func TestBucket_Create_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+		return
+	}
+
+	ctx := context.Background()
+	bucket := testutil.MinioContainer(t)
+
+	t.Run("it should write the object if the key does not exist", func(t *testing.T) {
+		require.NoError(t, bucket.Create(ctx, "test-key", []byte("hello world")))
+
+		reader, err := bucket.NewReader(ctx, "test-key")
+		require.NoError(t, err)
+
+		actual, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		assert.Equal(t, []byte("hello world"), actual)
+	})
+
+	t.Run("it should return an error if the key exists", func(t *testing.T) {
+		err := bucket.Create(ctx, "test-key", []byte("goodbye world"))
+		assert.ErrorIs(t, err, blob.ErrExist)
+	})
+}
+
+func TestBucket_Stat_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+		return
+	}
+
+	ctx := context.Background()
+	bucket := testutil.MinioContainer(t)
+
+	testData(t, bucket, "test-key", []byte("hello world"))
+
+	t.Run("it should return metadata if the key exists", func(t *testing.T) {
+		object, err := bucket.Stat(ctx, "test-key")
+		require.NoError(t, err)
+		assert.Equal(t, "test-key", object.Key)
+		assert.EqualValues(t, 11, object.Size)
+		assert.False(t, object.LastModified.IsZero())
+	})
+
+	t.Run("it should return an error if the key does not exist", func(t *testing.T) {
+		_, err := bucket.Stat(ctx, "test-key-1")
+		assert.ErrorIs(t, err, blob.ErrNotExist)
+	})
+}
+
 func testData(t *testing.T, bucket *blob.Bucket, key string, data []byte) {
 	ctx := context.Background()
 	writer, err := bucket.NewWriter(ctx, key)
@@ -2,6 +2,7 @@
 package event
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 	_ "gocloud.dev/pubsub/gcppubsub"
 	_ "gocloud.dev/pubsub/kafkapubsub"
 	_ "gocloud.dev/pubsub/natspubsub"
+
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 type (
@@ -24,6 +27,8 @@ type (
 		Type string `json:"type"`
 		// The raw JSON of the event payload.
 		Payload json.RawMessage `json:"payload"`
+		// Additional metadata about the event, such as the trace context of the request that produced it.
+		Headers map[string]string `json:"headers,omitempty"`
 	}
 
 	// The Payload interface describes types that can be used as event payloads.
@@ -35,7 +40,7 @@ type (
 	}
 )
 
-func wrap(payload Payload) (Envelope, error) {
+func wrap(ctx context.Context, payload Payload) (Envelope, error) {
 	b, err := json.Marshal(payload)
 	if err != nil {
 		return Envelope{}, err
@@ -46,6 +51,7 @@ func wrap(payload Payload) (Envelope, error) {
 		Timestamp: time.Now().UTC(),
 		Type:      payload.Type(),
 		Payload:   b,
+		Headers:   tracing.Inject(ctx),
 	}, nil
 }
 
@@ -8,9 +8,12 @@ import (
 	"slices"
 	"time"
 
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"gocloud.dev/pubsub"
 
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 type (
@@ -81,7 +84,7 @@ func (r *Reader) Read(ctx context.Context, types []string, h Handler) error {
 			}
 
 			log.DebugContext(ctx, "consumed event")
-			if err = h(ctx, envelope); err != nil {
+			if err = handle(ctx, envelope, h); err != nil {
 				nack(message)
 				return fmt.Errorf("failed to handle event %s: %w", envelope.ID, err)
 			}
@@ -91,6 +94,21 @@ func (r *Reader) Read(ctx context.Context, types []string, h Handler) error {
 	}
 }
 
+func handle(ctx context.Context, envelope Envelope, h Handler) (err error) {
+	// The span is started using the trace context of the publisher, so that handling the event is part of the same
+	// trace as the request that produced it.
+	ctx, span := tracing.Start(tracing.Extract(ctx, envelope.Headers), "process "+envelope.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingMessageID(envelope.ID),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	return h(ctx, envelope)
+}
+
 func nack(message *pubsub.Message) {
 	if message.Nackable() {
 		message.Nack()
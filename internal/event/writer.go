@@ -11,9 +11,12 @@ import (
 	servicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/IBM/sarama"
 	snstypesv2 "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"gocloud.dev/pubsub"
 
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 type (
@@ -45,12 +48,23 @@ func (w *Writer) Close() error {
 
 // Write an event onto the bus. Messages must implement the Payload interface and are wrapped in an Envelope before
 // publishing. If the event bus supports message keys/partitioning the Payload.Key method will be used to populate it.
-func (w *Writer) Write(ctx context.Context, e Payload) error {
-	envelope, err := wrap(e)
+func (w *Writer) Write(ctx context.Context, e Payload) (err error) {
+	ctx, span := tracing.Start(ctx, "publish "+e.Type(),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingMessageConversationID(e.Key()),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	envelope, err := wrap(ctx, e)
 	if err != nil {
 		return fmt.Errorf("failed to wrap event: %w", err)
 	}
 
+	span.SetAttributes(semconv.MessagingMessageID(envelope.ID))
+
 	body, err := json.Marshal(envelope)
 	if err != nil {
 		return fmt.Errorf("failed to marshal envelope: %w", err)
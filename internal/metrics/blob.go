@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+type (
+	// The BlobRepository type is a profile.BlobRepository implementation that records the duration and outcome of
+	// each operation performed against an underlying profile.BlobRepository.
+	BlobRepository struct {
+		blobs profile.BlobRepository
+	}
+)
+
+var (
+	blobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "blob",
+		Name:      "operation_duration_seconds",
+		Help:      "How long it took to perform operations against blob storage, by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+)
+
+// NewBlobRepository returns a new instance of the BlobRepository type that wraps the provided profile.BlobRepository
+// implementation.
+func NewBlobRepository(blobs profile.BlobRepository) *BlobRepository {
+	return &BlobRepository{blobs: blobs}
+}
+
+// NewWriter calls NewWriter on the underlying profile.BlobRepository, recording the time taken to open the writer.
+func (b *BlobRepository) NewWriter(ctx context.Context, key string) (io.WriteCloser, error) {
+	start := time.Now()
+	writer, err := b.blobs.NewWriter(ctx, key)
+	observeBlob("new_writer", start, err)
+
+	return writer, err
+}
+
+// NewReader calls NewReader on the underlying profile.BlobRepository, recording the time taken to open the reader.
+func (b *BlobRepository) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	start := time.Now()
+	reader, err := b.blobs.NewReader(ctx, key)
+	observeBlob("new_reader", start, err)
+
+	return reader, err
+}
+
+// Create calls Create on the underlying profile.BlobRepository, recording the time taken.
+func (b *BlobRepository) Create(ctx context.Context, key string, data []byte) error {
+	start := time.Now()
+	err := b.blobs.Create(ctx, key, data)
+	observeBlob("create", start, err)
+
+	return err
+}
+
+// Delete calls Delete on the underlying profile.BlobRepository, recording the time taken.
+func (b *BlobRepository) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := b.blobs.Delete(ctx, key)
+	observeBlob("delete", start, err)
+
+	return err
+}
+
+// List calls List on the underlying profile.BlobRepository, recording the time taken to iterate over all results.
+func (b *BlobRepository) List(ctx context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error] {
+	return func(yield func(blob.Object, error) bool) {
+		start := time.Now()
+
+		var err error
+		for object, e := range b.blobs.List(ctx, opts) {
+			if e != nil {
+				err = e
+			}
+
+			if !yield(object, e) {
+				break
+			}
+		}
+
+		observeBlob("list", start, err)
+	}
+}
+
+// Exists calls Exists on the underlying profile.BlobRepository, recording the time taken.
+func (b *BlobRepository) Exists(ctx context.Context, path string) (bool, error) {
+	start := time.Now()
+	exists, err := b.blobs.Exists(ctx, path)
+	observeBlob("exists", start, err)
+
+	return exists, err
+}
+
+// Stat calls Stat on the underlying profile.BlobRepository, recording the time taken.
+func (b *BlobRepository) Stat(ctx context.Context, key string) (blob.Object, error) {
+	start := time.Now()
+	object, err := b.blobs.Stat(ctx, key)
+	observeBlob("stat", start, err)
+
+	return object, err
+}
+
+func observeBlob(operation string, start time.Time, err error) {
+	outcome := "success"
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		outcome = "not_found"
+	case errors.Is(err, blob.ErrExist):
+		outcome = "exists"
+	case err != nil:
+		outcome = "failure"
+	}
+
+	blobDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
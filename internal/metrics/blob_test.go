@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+	"github.com/davidsbond/autopgo/internal/metrics"
+	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestBlobRepository(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name              string
+		Setup             func(blobs *mocks.MockBlobRepository)
+		Call              func(blobs *metrics.BlobRepository) error
+		ExpectedOperation string
+		ExpectedOutcome   string
+	}{
+		{
+			Name: "new reader succeeds",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(io.NopCloser(nil), nil)
+			},
+			Call: func(blobs *metrics.BlobRepository) error {
+				_, err := blobs.NewReader(context.Background(), "test-app/default.pgo")
+				return err
+			},
+			ExpectedOperation: "new_reader",
+			ExpectedOutcome:   "success",
+		},
+		{
+			Name: "stat on missing key",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+			},
+			Call: func(blobs *metrics.BlobRepository) error {
+				_, err := blobs.Stat(context.Background(), "test-app/default.pgo")
+				return err
+			},
+			ExpectedOperation: "stat",
+			ExpectedOutcome:   "not_found",
+		},
+		{
+			Name: "create on existing key",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Create(mock.Anything, "test-app/uploads/1.json", []byte("{}")).
+					Return(blob.ErrExist)
+			},
+			Call: func(blobs *metrics.BlobRepository) error {
+				return blobs.Create(context.Background(), "test-app/uploads/1.json", []byte("{}"))
+			},
+			ExpectedOperation: "create",
+			ExpectedOutcome:   "exists",
+		},
+		{
+			Name: "delete fails",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/default.pgo").
+					Return(io.EOF)
+			},
+			Call: func(blobs *metrics.BlobRepository) error {
+				return blobs.Delete(context.Background(), "test-app/default.pgo")
+			},
+			ExpectedOperation: "delete",
+			ExpectedOutcome:   "failure",
+		},
+		{
+			Name: "list fails part way",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/"}).
+					Return(func(yield func(blob.Object, error) bool) {
+						if !yield(blob.Object{Key: "test-app/default.pgo"}, nil) {
+							return
+						}
+
+						yield(blob.Object{}, io.EOF)
+					})
+			},
+			Call: func(blobs *metrics.BlobRepository) error {
+				var err error
+				for _, e := range blobs.List(context.Background(), blob.ListOptions{Prefix: "test-app/"}) {
+					if e != nil {
+						err = e
+					}
+				}
+
+				return err
+			},
+			ExpectedOperation: "list",
+			ExpectedOutcome:   "failure",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			tc.Setup(blobs)
+
+			labels := map[string]string{"operation": tc.ExpectedOperation, "outcome": tc.ExpectedOutcome}
+			before := testutil.MetricValue(t, "autopgo_blob_operation_duration_seconds", labels)
+
+			_ = tc.Call(metrics.NewBlobRepository(blobs))
+			assert.EqualValues(t, before+1, testutil.MetricValue(t, "autopgo_blob_operation_duration_seconds", labels))
+		})
+	}
+}
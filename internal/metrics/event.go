@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/davidsbond/autopgo/internal/event"
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+type (
+	// The EventWriter type is a profile.EventWriter implementation that records the duration and outcome of each
+	// event published via an underlying profile.EventWriter.
+	EventWriter struct {
+		events profile.EventWriter
+	}
+)
+
+var (
+	eventDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "event",
+		Name:      "write_duration_seconds",
+		Help:      "How long it took to publish events to the event bus, by event type and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "outcome"})
+)
+
+// NewEventWriter returns a new instance of the EventWriter type that wraps the provided profile.EventWriter
+// implementation.
+func NewEventWriter(events profile.EventWriter) *EventWriter {
+	return &EventWriter{events: events}
+}
+
+// Write calls Write on the underlying profile.EventWriter, recording the time taken.
+func (e *EventWriter) Write(ctx context.Context, evt event.Payload) error {
+	start := time.Now()
+	err := e.events.Write(ctx, evt)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	eventDuration.WithLabelValues(evt.Type(), outcome).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,54 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/davidsbond/autopgo/internal/metrics"
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestEventWriter_Write(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name            string
+		Event           profile.DeletedEvent
+		Error           error
+		ExpectedOutcome string
+	}{
+		{
+			Name:            "success",
+			Event:           profile.DeletedEvent{App: "test-app"},
+			ExpectedOutcome: "success",
+		},
+		{
+			Name:            "failure",
+			Event:           profile.DeletedEvent{App: "test-app"},
+			Error:           io.EOF,
+			ExpectedOutcome: "failure",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			events := mocks.NewMockEventWriter(t)
+			events.EXPECT().
+				Write(mock.Anything, tc.Event).
+				Return(tc.Error)
+
+			labels := map[string]string{"type": tc.Event.Type(), "outcome": tc.ExpectedOutcome}
+			before := testutil.MetricValue(t, "autopgo_event_write_duration_seconds", labels)
+
+			err := metrics.NewEventWriter(events).Write(context.Background(), tc.Event)
+			assert.ErrorIs(t, err, tc.Error)
+			assert.EqualValues(t, before+1, testutil.MetricValue(t, "autopgo_event_write_duration_seconds", labels))
+		})
+	}
+}
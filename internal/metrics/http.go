@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/davidsbond/autopgo/internal/server"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "The number of HTTP requests handled, by route and status code.",
+	}, []string{"route", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long it took to handle HTTP requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+)
+
+// Middleware is a server.Middleware implementation that records the number of requests and their duration for each
+// route. Routes are taken from the pattern matched by the http.ServeMux, so this should be placed in server.Config
+// before any middleware that replaces the request, such as logger.Middleware.
+func Middleware() server.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+
+			httpRequests.WithLabelValues(route, strconv.Itoa(recorder.code)).Inc()
+			httpDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+type (
+	statusRecorder struct {
+		http.ResponseWriter
+		code int
+	}
+)
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,42 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/davidsbond/autopgo/internal/metrics"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	const pattern = "GET /metrics-test/{app}"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("app") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	handler := metrics.Middleware()(mux)
+
+	// The "unmatched" route is shared by all requests the mux does not match, so only its change is checked.
+	unmatched := testutil.MetricValue(t, "autopgo_http_requests_total", map[string]string{"route": "unmatched", "code": "404"})
+
+	for _, path := range []string{"/metrics-test/a", "/metrics-test/b", "/metrics-test/missing", "/not-found"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Requests are labelled by the pattern they matched rather than their path, so there is a single route for all apps.
+	assert.EqualValues(t, 2, testutil.MetricValue(t, "autopgo_http_requests_total", map[string]string{"route": pattern, "code": "200"}))
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_http_requests_total", map[string]string{"route": pattern, "code": "404"}))
+	assert.EqualValues(t, 3, testutil.MetricValue(t, "autopgo_http_request_duration_seconds", map[string]string{"route": pattern}))
+	assert.EqualValues(t, unmatched+1, testutil.MetricValue(t, "autopgo_http_requests_total", map[string]string{"route": "unmatched", "code": "404"}))
+
+	for _, path := range []string{"/metrics-test/a", "/metrics-test/b", "/metrics-test/missing", "/not-found"} {
+		assert.Zero(t, testutil.MetricValue(t, "autopgo_http_request_duration_seconds", map[string]string{"route": path}))
+	}
+}
@@ -0,0 +1,28 @@
+// Package metrics provides types for exposing Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// The HTTPController type is used to serve Prometheus metrics.
+	HTTPController struct {
+		handler http.Handler
+	}
+)
+
+// NewHTTPController returns a new instance of the HTTPController type that serves all metrics registered with the
+// default Prometheus registry.
+func NewHTTPController() *HTTPController {
+	return &HTTPController{
+		handler: promhttp.Handler(),
+	}
+}
+
+// Register endpoints onto the http.ServeMux.
+func (h *HTTPController) Register(mux *http.ServeMux) {
+	mux.Handle("GET /metrics", h.handler)
+}
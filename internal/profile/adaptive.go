@@ -0,0 +1,84 @@
+package profile
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/davidsbond/autopgo/internal/logger"
+)
+
+type (
+	// The AdaptiveConfig type describes how the Scraper should adjust its profile duration and sample size between
+	// scrapes in order to obtain a consistent number of CPU samples over time.
+	AdaptiveConfig struct {
+		// The desired number of CPU samples uploaded per hour, across all targets.
+		TargetSamplesPerHour int64
+		// The lower bound for the profile duration.
+		MinProfileDuration time.Duration
+		// The upper bound for the profile duration.
+		MaxProfileDuration time.Duration
+		// The lower bound for the number of targets profiled per scrape.
+		MinSampleSize uint
+		// The upper bound for the number of targets profiled per scrape.
+		MaxSampleSize uint
+	}
+)
+
+const (
+	// The maximum factor the profile duration and sample size can change by in a single scrape. This prevents a
+	// single unusually busy or idle scrape from swinging the configuration too far.
+	maxAdaptiveStep = 2.0
+)
+
+func (s *Scraper) adapt(ctx context.Context, samples int64) {
+	duration, sampleSize := s.adaptive.next(s.profileDuration, s.sampleSize, s.scrapeFrequency, samples)
+	if duration == s.profileDuration && sampleSize == s.sampleSize {
+		return
+	}
+
+	logger.FromContext(ctx).With(
+		slog.Int64("scrape.samples", samples),
+		slog.Duration("scrape.duration.old", s.profileDuration),
+		slog.Duration("scrape.duration.new", duration),
+		slog.Uint64("scrape.sample_size.old", uint64(s.sampleSize)),
+		slog.Uint64("scrape.sample_size.new", uint64(sampleSize)),
+	).InfoContext(ctx, "adjusted scrape configuration")
+
+	s.profileDuration = duration
+	s.sampleSize = sampleSize
+}
+
+// next returns the profile duration and sample size to use for the next scrape, based on the number of samples
+// produced by the previous scrape. The profile duration is preferred for making adjustments, the sample size is
+// only changed once the profile duration reaches one of its bounds. Nothing changes if no samples were produced.
+func (a *AdaptiveConfig) next(duration time.Duration, sampleSize uint, frequency time.Duration, samples int64) (time.Duration, uint) {
+	if a.TargetSamplesPerHour <= 0 || frequency <= 0 {
+		return duration, sampleSize
+	}
+
+	// Extrapolate the samples obtained during the last scrape into an hourly rate to compare against the target. A
+	// scrape takes at least as long as the profile duration, so it determines the period when it exceeds the frequency.
+	observed := float64(samples) * float64(time.Hour) / float64(max(frequency, duration))
+	if observed <= 0 {
+		return duration, sampleSize
+	}
+
+	ratio := clamp(float64(a.TargetSamplesPerHour)/observed, 1/maxAdaptiveStep, maxAdaptiveStep)
+
+	// The pprof endpoint only accepts whole seconds, so the duration is always rounded.
+	next := time.Duration(float64(duration) * ratio).Round(time.Second)
+	next = clamp(next, max(a.MinProfileDuration, time.Second), max(a.MaxProfileDuration, time.Second))
+
+	// Whatever part of the ratio could not be satisfied by the duration is applied to the sample size.
+	remaining := ratio * float64(duration) / float64(next)
+	nextSize := uint(math.Round(float64(sampleSize) * remaining))
+	nextSize = clamp(nextSize, max(a.MinSampleSize, 1), max(a.MaxSampleSize, a.MinSampleSize, 1))
+
+	return next, nextSize
+}
+
+func clamp[T float64 | time.Duration | uint](v, lower, upper T) T {
+	return min(max(v, lower), upper)
+}
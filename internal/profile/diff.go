@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// DiffHotThreshold is the percentage of a profile's CPU time a function must spend within itself to be considered hot
+// when comparing profiles.
+const DiffHotThreshold = 1
+
+type (
+	// The Diff type describes how the share of CPU time spent within each function changed between a base profile and
+	// a target profile. Shares are normalized to percentages of each profile's total CPU time so that profiles
+	// covering different durations can be compared.
+	Diff struct {
+		// The total CPU time recorded within each profile, in nanoseconds.
+		BaseCPUTime   time.Duration `json:"baseCpuTime"`
+		TargetCPUTime time.Duration `json:"targetCpuTime"`
+		// The cosine similarity of the functions' shares of CPU time, from 0 for profiles with no functions in common
+		// to 1 for identical distributions.
+		Similarity float64 `json:"similarity"`
+		// The functions whose share of CPU time grew or shrank the most, in descending order of change.
+		Grew   []FunctionDiff `json:"grew"`
+		Shrank []FunctionDiff `json:"shrank"`
+		// The functions that are hot within the target profile but not the base profile, and vice versa, in
+		// descending order of their share of CPU time.
+		New     []FunctionDiff `json:"new"`
+		Removed []FunctionDiff `json:"removed"`
+	}
+
+	// The FunctionDiff type describes the change in a single function's share of CPU time between two profiles.
+	FunctionDiff struct {
+		// The name of the function.
+		Name string `json:"name"`
+		// The percentage of each profile's CPU time spent within the function itself.
+		BaseFlat   float64 `json:"baseFlat"`
+		TargetFlat float64 `json:"targetFlat"`
+		// The percentage of each profile's CPU time spent within the function and the functions it calls.
+		BaseCumulative   float64 `json:"baseCumulative"`
+		TargetCumulative float64 `json:"targetCumulative"`
+		// The change in the function's flat & cumulative percentages, positive when its share grew.
+		FlatDelta       float64 `json:"flatDelta"`
+		CumulativeDelta float64 `json:"cumulativeDelta"`
+	}
+)
+
+// Compare the CPU profiles, returning a Diff describing the top functions whose share of CPU time changed the most
+// between the base and target profiles. Returns ErrNotCPUProfile if either profile does not contain both samples/count
+// and cpu/nanoseconds sample types.
+func Compare(base, target *profile.Profile, top int) (Diff, error) {
+	_, baseCPU, err := CPUUsage(base)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	_, targetCPU, err := CPUUsage(target)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	diffs := make(map[string]*FunctionDiff)
+	function := func(name string) *FunctionDiff {
+		if fn, ok := diffs[name]; ok {
+			return fn
+		}
+
+		fn := &FunctionDiff{Name: name}
+		diffs[name] = fn
+		return fn
+	}
+
+	for _, fn := range functionSummaries(base) {
+		diff := function(fn.Name)
+		diff.BaseFlat = percentOf(int64(fn.Flat), int64(baseCPU))
+		diff.BaseCumulative = percentOf(int64(fn.Cumulative), int64(baseCPU))
+	}
+
+	for _, fn := range functionSummaries(target) {
+		diff := function(fn.Name)
+		diff.TargetFlat = percentOf(int64(fn.Flat), int64(targetCPU))
+		diff.TargetCumulative = percentOf(int64(fn.Cumulative), int64(targetCPU))
+	}
+
+	result := Diff{
+		BaseCPUTime:   baseCPU,
+		TargetCPUTime: targetCPU,
+		Grew:          []FunctionDiff{},
+		Shrank:        []FunctionDiff{},
+		New:           []FunctionDiff{},
+		Removed:       []FunctionDiff{},
+	}
+
+	var dot, baseNorm, targetNorm float64
+	for _, diff := range diffs {
+		diff.FlatDelta = diff.TargetFlat - diff.BaseFlat
+		diff.CumulativeDelta = diff.TargetCumulative - diff.BaseCumulative
+
+		dot += diff.BaseFlat * diff.TargetFlat
+		baseNorm += diff.BaseFlat * diff.BaseFlat
+		targetNorm += diff.TargetFlat * diff.TargetFlat
+
+		baseHot := diff.BaseFlat >= DiffHotThreshold
+		targetHot := diff.TargetFlat >= DiffHotThreshold
+
+		switch {
+		case diff.FlatDelta > 0:
+			result.Grew = append(result.Grew, *diff)
+		case diff.FlatDelta < 0:
+			result.Shrank = append(result.Shrank, *diff)
+		}
+
+		switch {
+		case targetHot && !baseHot:
+			result.New = append(result.New, *diff)
+		case baseHot && !targetHot:
+			result.Removed = append(result.Removed, *diff)
+		}
+	}
+
+	if baseNorm > 0 && targetNorm > 0 {
+		result.Similarity = dot / (math.Sqrt(baseNorm) * math.Sqrt(targetNorm))
+	}
+
+	sortDiffs := func(diffs []FunctionDiff, value func(FunctionDiff) float64) []FunctionDiff {
+		slices.SortFunc(diffs, func(a, b FunctionDiff) int {
+			return cmp.Or(cmp.Compare(value(b), value(a)), cmp.Compare(a.Name, b.Name))
+		})
+
+		return diffs[:min(top, len(diffs))]
+	}
+
+	result.Grew = sortDiffs(result.Grew, func(d FunctionDiff) float64 { return d.FlatDelta })
+	result.Shrank = sortDiffs(result.Shrank, func(d FunctionDiff) float64 { return -d.FlatDelta })
+	result.New = sortDiffs(result.New, func(d FunctionDiff) float64 { return d.TargetFlat })
+	result.Removed = sortDiffs(result.Removed, func(d FunctionDiff) float64 { return d.BaseFlat })
+
+	return result, nil
+}
+
+// DiffBaseLabel is the sample label pprof uses to identify the samples of a diff-base profile that were subtracted
+// from the base profile.
+const DiffBaseLabel = "pprof::base"
+
+// NewDiffBase returns a profile containing the difference between the target and base profiles, in the same format
+// pprof produces when given the -diff_base & -normalize flags. The target profile is normalized to the total of the
+// base profile, and the samples of the base profile are negated and labelled with DiffBaseLabel so that functions
+// whose CPU time grew have positive values. Neither of the given profiles are modified.
+func NewDiffBase(base, target *profile.Profile) (*profile.Profile, error) {
+	base, target = base.Copy(), target.Copy()
+
+	base.SetLabel(DiffBaseLabel, []string{"true"})
+	if err := target.Normalize(base); err != nil {
+		return nil, err
+	}
+
+	base.Scale(-1)
+	return profile.Merge([]*profile.Profile{target, base})
+}
@@ -0,0 +1,190 @@
+package profile_test
+
+import (
+	"math"
+	"testing"
+
+	pprof "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+func diffProfiles() (*pprof.Profile, *pprof.Profile) {
+	mainFn := &pprof.Function{ID: 1, Name: "main.main"}
+	fooFn := &pprof.Function{ID: 2, Name: "main.foo"}
+	barFn := &pprof.Function{ID: 3, Name: "main.bar"}
+
+	mainLoc := &pprof.Location{ID: 1, Line: []pprof.Line{{Function: mainFn}}}
+	fooLoc := &pprof.Location{ID: 2, Line: []pprof.Line{{Function: fooFn}}}
+	barLoc := &pprof.Location{ID: 3, Line: []pprof.Line{{Function: barFn}}}
+
+	sampleTypes := []*pprof.ValueType{
+		{Type: "samples", Unit: "count"},
+		{Type: "cpu", Unit: "nanoseconds"},
+	}
+
+	base := &pprof.Profile{
+		SampleType: sampleTypes,
+		PeriodType: &pprof.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10,
+		Sample: []*pprof.Sample{
+			{Location: []*pprof.Location{mainLoc}, Value: []int64{5, 50}},
+			{Location: []*pprof.Location{fooLoc, mainLoc}, Value: []int64{5, 50}},
+		},
+		Location: []*pprof.Location{mainLoc, fooLoc},
+		Function: []*pprof.Function{mainFn, fooFn},
+	}
+
+	target := &pprof.Profile{
+		SampleType: sampleTypes,
+		PeriodType: &pprof.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10,
+		Sample: []*pprof.Sample{
+			{Location: []*pprof.Location{mainLoc}, Value: []int64{4, 40}},
+			{Location: []*pprof.Location{fooLoc, mainLoc}, Value: []int64{4, 40}},
+			{Location: []*pprof.Location{barLoc, mainLoc}, Value: []int64{12, 120}},
+		},
+		Location: []*pprof.Location{mainLoc, fooLoc, barLoc},
+		Function: []*pprof.Function{mainFn, fooFn, barFn},
+	}
+
+	return base.Copy(), target.Copy()
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	base, target := diffProfiles()
+
+	bar := profile.FunctionDiff{
+		Name:             "main.bar",
+		TargetFlat:       60,
+		TargetCumulative: 60,
+		FlatDelta:        60,
+		CumulativeDelta:  60,
+	}
+
+	foo := profile.FunctionDiff{
+		Name:             "main.foo",
+		BaseFlat:         50,
+		TargetFlat:       20,
+		BaseCumulative:   50,
+		TargetCumulative: 20,
+		FlatDelta:        -30,
+		CumulativeDelta:  -30,
+	}
+
+	main := profile.FunctionDiff{
+		Name:             "main.main",
+		BaseFlat:         50,
+		TargetFlat:       20,
+		BaseCumulative:   100,
+		TargetCumulative: 100,
+		FlatDelta:        -30,
+	}
+
+	reversed := func(diff profile.FunctionDiff) profile.FunctionDiff {
+		diff.BaseFlat, diff.TargetFlat = diff.TargetFlat, diff.BaseFlat
+		diff.BaseCumulative, diff.TargetCumulative = diff.TargetCumulative, diff.BaseCumulative
+		diff.FlatDelta, diff.CumulativeDelta = -diff.FlatDelta, -diff.CumulativeDelta
+		return diff
+	}
+
+	similarity := 2000 / (math.Sqrt(5000) * math.Sqrt(4400))
+
+	tt := []struct {
+		Name          string
+		Base          *pprof.Profile
+		Target        *pprof.Profile
+		Top           int
+		Expected      profile.Diff
+		ExpectedError error
+	}{
+		{
+			Name:   "should report new hot functions",
+			Base:   base,
+			Target: target,
+			Top:    10,
+			Expected: profile.Diff{
+				BaseCPUTime:   100,
+				TargetCPUTime: 200,
+				Similarity:    similarity,
+				Grew:          []profile.FunctionDiff{bar},
+				Shrank:        []profile.FunctionDiff{foo, main},
+				New:           []profile.FunctionDiff{bar},
+				Removed:       []profile.FunctionDiff{},
+			},
+		},
+		{
+			Name:   "should report removed hot functions",
+			Base:   target,
+			Target: base,
+			Top:    1,
+			Expected: profile.Diff{
+				BaseCPUTime:   200,
+				TargetCPUTime: 100,
+				Similarity:    similarity,
+				Grew:          []profile.FunctionDiff{reversed(foo)},
+				Shrank:        []profile.FunctionDiff{reversed(bar)},
+				New:           []profile.FunctionDiff{},
+				Removed:       []profile.FunctionDiff{reversed(bar)},
+			},
+		},
+		{
+			Name: "should return an error for non-cpu profiles",
+			Base: base,
+			Target: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "alloc_objects", Unit: "count"},
+				},
+			},
+			ExpectedError: profile.ErrNotCPUProfile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := profile.Compare(tc.Base, tc.Target, tc.Top)
+			require.ErrorIs(t, err, tc.ExpectedError)
+			if tc.ExpectedError != nil {
+				return
+			}
+
+			assert.InDelta(t, tc.Expected.Similarity, actual.Similarity, 1e-9)
+			actual.Similarity = tc.Expected.Similarity
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestNewDiffBase(t *testing.T) {
+	t.Parallel()
+
+	base, target := diffProfiles()
+
+	diff, err := profile.NewDiffBase(base, target)
+	require.NoError(t, err)
+
+	totals := make(map[bool][]int64)
+	for _, sample := range diff.Sample {
+		isBase := len(sample.Label[profile.DiffBaseLabel]) > 0
+		if totals[isBase] == nil {
+			totals[isBase] = make([]int64, len(sample.Value))
+		}
+
+		for i, value := range sample.Value {
+			totals[isBase][i] += value
+		}
+	}
+
+	// The target profile is normalized to the totals of the base profile, whose samples are negated.
+	assert.EqualValues(t, []int64{10, 100}, totals[false])
+	assert.EqualValues(t, []int64{-10, -100}, totals[true])
+
+	// The given profiles are left unmodified.
+	assert.EqualValues(t, []int64{5, 50}, base.Sample[0].Value)
+	assert.EqualValues(t, []int64{4, 40}, target.Sample[0].Value)
+	assert.Empty(t, base.Sample[0].Label)
+}
@@ -0,0 +1,217 @@
+package profile
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// HotCallSiteThreshold is the percentage of the total call edge weight covered by the hot call sites within an
+// EdgeReport. It matches the default cumulative threshold used by the compiler's PGO inliner.
+const HotCallSiteThreshold = 99
+
+type (
+	// The EdgeReport type describes the hot call sites within a merged profile, which are the candidates for PGO
+	// inlining & devirtualization.
+	EdgeReport struct {
+		// The total weight of every call edge within the profile, in samples.
+		TotalWeight int64 `json:"totalWeight"`
+		// The percentage of the total weight covered by the hot call sites.
+		Threshold float64 `json:"threshold"`
+		// The hot call edges, in descending order of weight.
+		Edges []CallEdge `json:"edges"`
+	}
+
+	// The CallEdge type describes the samples in which one function called another from a call site.
+	CallEdge struct {
+		// The names of the calling & called functions.
+		Caller string `json:"caller"`
+		Callee string `json:"callee"`
+		// The line of the call site, relative to the start of the calling function.
+		CallSiteOffset int64 `json:"callSiteOffset"`
+		// The number of samples containing the call edge.
+		Weight int64 `json:"weight"`
+		// The CPU time of the samples containing the call edge, in nanoseconds.
+		CPUTime time.Duration `json:"cpuTime"`
+		// The percentage of the total weight attributed to the call edge.
+		Percent float64 `json:"percent"`
+		// The percentage of the total weight attributed to the call edge and every hotter call edge.
+		CumulativePercent float64 `json:"cumulativePercent"`
+	}
+)
+
+var (
+	// ErrMissingStartLine is the error given when a profile does not contain the start lines of its functions, which
+	// are required by the compiler to locate call sites. Profiles from Go 1.20 onwards include them.
+	ErrMissingStartLine = errors.New("profile is missing function start lines")
+)
+
+// HotCallEdges returns an EdgeReport of the call edges within the CPU profile, weighted as the compiler does when
+// building with PGO. Each pair of consecutive frames within the last two locations of a sample's stack, including
+// inlined frames, forms an edge between the caller's call site and the callee. The hottest edges are included until their cumulative weight exceeds
+// HotCallSiteThreshold. Returns ErrNotCPUProfile if the profile does not contain both samples/count and
+// cpu/nanoseconds sample types, or ErrMissingStartLine if its functions have no start lines.
+func HotCallEdges(p *profile.Profile) (EdgeReport, error) {
+	// Like the compiler, the weight is taken from the first recognised sample type. The samples/count & cpu/nanoseconds
+	// sample types are proportional to each other, so either would give the same hot call sites.
+	weightIndex, samplesIndex, cpuIndex := -1, -1, -1
+	for i, st := range p.SampleType {
+		switch {
+		case st.Type == "samples" && st.Unit == "count":
+			samplesIndex = i
+		case st.Type == "cpu" && st.Unit == "nanoseconds":
+			cpuIndex = i
+		default:
+			continue
+		}
+
+		if weightIndex == -1 {
+			weightIndex = i
+		}
+	}
+
+	if samplesIndex == -1 || cpuIndex == -1 {
+		return EdgeReport{}, ErrNotCPUProfile
+	}
+
+	report := EdgeReport{
+		Threshold: HotCallSiteThreshold,
+		Edges:     []CallEdge{},
+	}
+
+	type (
+		node struct {
+			address   uint64
+			name      string
+			line      int64
+			startLine int64
+		}
+
+		nodePair struct {
+			caller node
+			callee node
+		}
+
+		edgeKey struct {
+			caller string
+			callee string
+			offset int64
+		}
+	)
+
+	edges := make(map[edgeKey]*CallEdge)
+	hasNode, hasStartLine := false, false
+
+	for _, sample := range p.Sample {
+		weight := sample.Value[weightIndex]
+		if weight == 0 {
+			continue
+		}
+
+		cpu := time.Duration(sample.Value[cpuIndex])
+
+		// Edges are only counted once per sample, as recursive functions may call each other many times within the
+		// same stack.
+		seen := make(map[nodePair]bool)
+
+		// Like the compiler, only the last two locations in the stack form call edges. Calls further up the stack are
+		// unlikely to be repeated, so weighting them by every sample beneath them would overstate how hot they are.
+		var caller *node
+		for i := min(1, len(sample.Location)-1); i >= 0; i-- {
+			location := sample.Location[i]
+
+			lines := location.Line
+			if len(lines) == 0 {
+				lines = []profile.Line{{}}
+			}
+
+			// Lines within a location are ordered from the innermost inlined function, so they are walked in reverse
+			// to produce edges from caller to callee.
+			for j := len(lines) - 1; j >= 0; j-- {
+				callee := node{address: location.Address}
+				if fn := lines[j].Function; fn != nil {
+					callee.name = fn.Name
+					callee.line = lines[j].Line
+					callee.startLine = fn.StartLine
+				}
+
+				hasNode = true
+				hasStartLine = hasStartLine || callee.startLine != 0
+
+				if caller != nil && *caller != callee && !seen[nodePair{caller: *caller, callee: callee}] {
+					seen[nodePair{caller: *caller, callee: callee}] = true
+
+					key := edgeKey{
+						caller: caller.name,
+						callee: callee.name,
+						offset: caller.line - caller.startLine,
+					}
+
+					edge, ok := edges[key]
+					if !ok {
+						edge = &CallEdge{Caller: key.caller, Callee: key.callee, CallSiteOffset: key.offset}
+						edges[key] = edge
+					}
+
+					edge.Weight += weight
+					edge.CPUTime += cpu
+					report.TotalWeight += weight
+				}
+
+				caller = &callee
+			}
+		}
+	}
+
+	if !hasNode {
+		return report, nil
+	}
+
+	if !hasStartLine {
+		return EdgeReport{}, ErrMissingStartLine
+	}
+
+	sorted := make([]CallEdge, 0, len(edges))
+	for _, edge := range edges {
+		sorted = append(sorted, *edge)
+	}
+
+	slices.SortFunc(sorted, func(a, b CallEdge) int {
+		return cmp.Or(
+			cmp.Compare(b.Weight, a.Weight),
+			strings.Compare(a.Caller, b.Caller),
+			strings.Compare(a.Callee, b.Callee),
+			cmp.Compare(a.CallSiteOffset, b.CallSiteOffset),
+		)
+	})
+
+	var cumulative int64
+	for _, edge := range sorted {
+		cumulative += edge.Weight
+
+		edge.Percent = percentOf(edge.Weight, report.TotalWeight)
+		edge.CumulativePercent = percentOf(cumulative, report.TotalWeight)
+		report.Edges = append(report.Edges, edge)
+
+		// The edge that takes the cumulative weight over the threshold is still hot, as a single edge may account for
+		// more weight than the threshold allows.
+		if edge.CumulativePercent > HotCallSiteThreshold {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// percentOf returns the given weight as a percentage of the total.
+func percentOf(weight, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(weight) * 100 / float64(total)
+}
@@ -0,0 +1,123 @@
+package profile_test
+
+import (
+	"testing"
+
+	pprof "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+func TestHotCallEdges(t *testing.T) {
+	t.Parallel()
+
+	mainFn := &pprof.Function{ID: 1, Name: "main.main", StartLine: 10}
+	fooFn := &pprof.Function{ID: 2, Name: "main.foo", StartLine: 20}
+	barFn := &pprof.Function{ID: 3, Name: "main.bar", StartLine: 30}
+	bazFn := &pprof.Function{ID: 4, Name: "main.baz", StartLine: 40}
+
+	mainLoc := &pprof.Location{ID: 1, Address: 1, Line: []pprof.Line{{Function: mainFn, Line: 12}}}
+	fooLoc := &pprof.Location{ID: 2, Address: 2, Line: []pprof.Line{{Function: fooFn, Line: 25}}}
+	barLoc := &pprof.Location{ID: 3, Address: 3, Line: []pprof.Line{{Function: barFn, Line: 31}}}
+	fooLeafLoc := &pprof.Location{ID: 4, Address: 4, Line: []pprof.Line{{Function: fooFn, Line: 22}}}
+	// The main.baz function has been inlined into main.bar.
+	bazLoc := &pprof.Location{ID: 5, Address: 5, Line: []pprof.Line{
+		{Function: bazFn, Line: 41},
+		{Function: barFn, Line: 32},
+	}}
+
+	sampleTypes := []*pprof.ValueType{
+		{Type: "samples", Unit: "count"},
+		{Type: "cpu", Unit: "nanoseconds"},
+	}
+
+	cpuProfile := &pprof.Profile{
+		SampleType: sampleTypes,
+		Sample: []*pprof.Sample{
+			{Location: []*pprof.Location{barLoc, fooLoc, mainLoc}, Value: []int64{3, 30}},
+			{Location: []*pprof.Location{fooLeafLoc, mainLoc}, Value: []int64{96, 960}},
+			{Location: []*pprof.Location{bazLoc, fooLoc, mainLoc}, Value: []int64{1, 10}},
+			// Recursive calls from the same call site are not call edges.
+			{Location: []*pprof.Location{fooLoc, fooLoc, mainLoc}, Value: []int64{1, 10}},
+		},
+		Location: []*pprof.Location{mainLoc, fooLoc, barLoc, fooLeafLoc, bazLoc},
+		Function: []*pprof.Function{mainFn, fooFn, barFn, bazFn},
+	}
+
+	noStartLines := cpuProfile.Copy()
+	for _, fn := range noStartLines.Function {
+		fn.StartLine = 0
+	}
+
+	tt := []struct {
+		Name          string
+		Profile       *pprof.Profile
+		Expected      profile.EdgeReport
+		ExpectedError error
+	}{
+		{
+			Name:    "should report hot call edges",
+			Profile: cpuProfile,
+			Expected: profile.EdgeReport{
+				TotalWeight: 101,
+				Threshold:   profile.HotCallSiteThreshold,
+				Edges: []profile.CallEdge{
+					{
+						Caller:            "main.main",
+						Callee:            "main.foo",
+						CallSiteOffset:    2,
+						Weight:            96,
+						CPUTime:           960,
+						Percent:           96.0 * 100 / 101,
+						CumulativePercent: 96.0 * 100 / 101,
+					},
+					// This edge takes the cumulative weight over the threshold, so is the last hot edge, excluding
+					// main.bar's call to main.baz.
+					{
+						Caller:            "main.foo",
+						Callee:            "main.bar",
+						CallSiteOffset:    5,
+						Weight:            4,
+						CPUTime:           40,
+						Percent:           4.0 * 100 / 101,
+						CumulativePercent: 100.0 * 100 / 101,
+					},
+				},
+			},
+		},
+		{
+			Name: "should return an empty report for a profile without samples",
+			Profile: &pprof.Profile{
+				SampleType: sampleTypes,
+			},
+			Expected: profile.EdgeReport{
+				Threshold: profile.HotCallSiteThreshold,
+				Edges:     []profile.CallEdge{},
+			},
+		},
+		{
+			Name:          "should return an error for profiles without start lines",
+			Profile:       noStartLines,
+			ExpectedError: profile.ErrMissingStartLine,
+		},
+		{
+			Name: "should return an error for non-cpu profiles",
+			Profile: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "alloc_objects", Unit: "count"},
+				},
+			},
+			ExpectedError: profile.ErrNotCPUProfile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := profile.HotCallEdges(tc.Profile)
+			require.ErrorIs(t, err, tc.ExpectedError)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
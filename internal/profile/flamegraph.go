@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+type (
+	// The FlameGraph type describes a single frame within a flame graph of a CPU profile, along with the frames it
+	// calls. The root frame contains every sample within the profile.
+	FlameGraph struct {
+		// The name of the function.
+		Name string `json:"name"`
+		// The CPU time spent within the function and the functions it calls from this stack, in nanoseconds.
+		Value time.Duration `json:"value"`
+		// The frames called from this stack, ordered by name.
+		Children []FlameGraph `json:"children,omitempty"`
+	}
+)
+
+// FlameGraphRoot is the name of the root frame of a FlameGraph.
+const FlameGraphRoot = "root"
+
+// NewFlameGraph returns a FlameGraph of the CPU profile, where each frame describes a function within the stacks of its
+// samples, including inlined functions. Returns ErrNotCPUProfile if the profile does not contain both samples/count and
+// cpu/nanoseconds sample types.
+func NewFlameGraph(p *profile.Profile) (FlameGraph, error) {
+	if _, _, err := CPUUsage(p); err != nil {
+		return FlameGraph{}, err
+	}
+
+	cpuIndex := slices.IndexFunc(p.SampleType, func(st *profile.ValueType) bool {
+		return st.Type == "cpu" && st.Unit == "nanoseconds"
+	})
+
+	type (
+		frame struct {
+			value    time.Duration
+			children map[string]*frame
+		}
+	)
+
+	root := &frame{children: make(map[string]*frame)}
+	for _, sample := range p.Sample {
+		value := time.Duration(sample.Value[cpuIndex])
+		root.value += value
+
+		current := root
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			location := sample.Location[i]
+
+			// Lines within a location are ordered from the innermost inlined function, so they are walked in reverse
+			// to produce frames from caller to callee.
+			for j := len(location.Line) - 1; j >= 0; j-- {
+				name := "unknown"
+				if fn := location.Line[j].Function; fn != nil {
+					name = fn.Name
+				}
+
+				child, ok := current.children[name]
+				if !ok {
+					child = &frame{children: make(map[string]*frame)}
+					current.children[name] = child
+				}
+
+				child.value += value
+				current = child
+			}
+		}
+	}
+
+	var build func(name string, f *frame) FlameGraph
+	build = func(name string, f *frame) FlameGraph {
+		graph := FlameGraph{Name: name, Value: f.value}
+		for childName, child := range f.children {
+			graph.Children = append(graph.Children, build(childName, child))
+		}
+
+		slices.SortFunc(graph.Children, func(a, b FlameGraph) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+
+		return graph
+	}
+
+	return build(FlameGraphRoot, root), nil
+}
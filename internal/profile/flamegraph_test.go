@@ -0,0 +1,85 @@
+package profile_test
+
+import (
+	"testing"
+
+	pprof "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+func TestNewFlameGraph(t *testing.T) {
+	t.Parallel()
+
+	mainFn := &pprof.Function{ID: 1, Name: "main.main"}
+	fooFn := &pprof.Function{ID: 2, Name: "main.foo"}
+	barFn := &pprof.Function{ID: 3, Name: "main.bar"}
+	bazFn := &pprof.Function{ID: 4, Name: "main.baz"}
+
+	mainLoc := &pprof.Location{ID: 1, Line: []pprof.Line{{Function: mainFn}}}
+	fooLoc := &pprof.Location{ID: 2, Line: []pprof.Line{{Function: fooFn}}}
+	// The main.baz function has been inlined into main.bar.
+	barLoc := &pprof.Location{ID: 3, Line: []pprof.Line{{Function: bazFn}, {Function: barFn}}}
+
+	tt := []struct {
+		Name          string
+		Profile       *pprof.Profile
+		Expected      profile.FlameGraph
+		ExpectedError error
+	}{
+		{
+			Name: "should build a flame graph",
+			Profile: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "samples", Unit: "count"},
+					{Type: "cpu", Unit: "nanoseconds"},
+				},
+				Sample: []*pprof.Sample{
+					{Location: []*pprof.Location{fooLoc, mainLoc}, Value: []int64{2, 20}},
+					{Location: []*pprof.Location{barLoc, mainLoc}, Value: []int64{3, 30}},
+					{Location: []*pprof.Location{fooLoc, mainLoc}, Value: []int64{1, 10}},
+					{Location: []*pprof.Location{mainLoc}, Value: []int64{1, 5}},
+				},
+			},
+			Expected: profile.FlameGraph{
+				Name:  profile.FlameGraphRoot,
+				Value: 65,
+				Children: []profile.FlameGraph{
+					{
+						Name:  "main.main",
+						Value: 65,
+						Children: []profile.FlameGraph{
+							{
+								Name:  "main.bar",
+								Value: 30,
+								Children: []profile.FlameGraph{
+									{Name: "main.baz", Value: 30},
+								},
+							},
+							{Name: "main.foo", Value: 30},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "should return an error for non-cpu profiles",
+			Profile: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "alloc_objects", Unit: "count"},
+				},
+			},
+			ExpectedError: profile.ErrNotCPUProfile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := profile.NewFlameGraph(tc.Profile)
+			require.ErrorIs(t, err, tc.ExpectedError)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	pprof "github.com/google/pprof/profile"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -72,3 +73,23 @@ func mustMarshal(t *testing.T, v interface{}) []byte {
 	require.NoError(t, err)
 	return b
 }
+
+func mustParse(t *testing.T, b []byte) *pprof.Profile {
+	t.Helper()
+
+	p, err := pprof.ParseData(b)
+	require.NoError(t, err)
+	return p
+}
+
+// modifiedProfile returns the contents of validProfile after applying the given modification.
+func modifiedProfile(t *testing.T, modify func(p *pprof.Profile)) []byte {
+	t.Helper()
+
+	p := mustParse(t, validProfile)
+	modify(p)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, p.Write(buf))
+	return buf.Bytes()
+}
@@ -0,0 +1,207 @@
+package profile
+
+import (
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Constants for reasons a scrape can fail, used as the "reason" label on scrapeFailures.
+const (
+	failureReasonAddress = "address"
+	failureReasonFetch   = "fetch"
+	failureReasonParse   = "parse"
+	failureReasonInvalid = "invalid"
+	failureReasonUpload  = "upload"
+)
+
+var (
+	targetsDiscovered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "targets_discovered",
+		Help:      "The number of targets found during the most recent discovery, per source.",
+	}, []string{"app", "source"})
+
+	discoveryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "discovery_errors_total",
+		Help:      "The number of times listing targets from a source has failed.",
+	}, []string{"app", "source"})
+
+	targetsSampled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "targets_sampled_total",
+		Help:      "The number of targets selected for scraping.",
+	}, []string{"app"})
+
+	scrapeAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "scrape_attempts_total",
+		Help:      "The number of attempts made to scrape a profile from a target.",
+	}, []string{"app"})
+
+	scrapeSuccesses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "scrape_successes_total",
+		Help:      "The number of profiles successfully scraped and uploaded.",
+	}, []string{"app"})
+
+	scrapeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "scrape_failures_total",
+		Help:      "The number of failed scrapes, by reason.",
+	}, []string{"app", "reason"})
+
+	profilesSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "profiles_skipped_total",
+		Help:      "The number of profiles dropped for containing too few samples or too little CPU time.",
+	}, []string{"app"})
+
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "fetch_duration_seconds",
+		Help:      "How long it took to obtain a profile from a target, including the profile duration.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 9),
+	}, []string{"app"})
+
+	uploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "upload_duration_seconds",
+		Help:      "How long it took to upload a profile to the server.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"app"})
+
+	profileBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "profile_bytes",
+		Help:      "The size of profiles uploaded to the server.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 8),
+	}, []string{"app"})
+
+	lastUpload = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "autopgo",
+		Subsystem: "scraper",
+		Name:      "last_upload_timestamp_seconds",
+		Help:      "The unix timestamp of the most recent successful profile upload.",
+	}, []string{"app"})
+)
+
+var (
+	uploadBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "server",
+		Name:      "upload_bytes",
+		Help:      "The size of profiles uploaded to the server.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 8),
+	})
+
+	uploadParseFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "server",
+		Name:      "upload_parse_failures_total",
+		Help:      "The number of uploaded profiles that could not be parsed.",
+	})
+
+	uploadRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "server",
+		Name:      "upload_rejections_total",
+		Help:      "The number of uploaded profiles rejected by validation, by reason.",
+	}, []string{"reason"})
+
+	uploadDuplicates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "server",
+		Name:      "upload_duplicates_total",
+		Help:      "The number of uploaded profiles that were not merged as they had already been uploaded.",
+	})
+)
+
+// Constants for event handling outcomes, used as the "outcome" label on eventsHandled.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// Constants for the actions taken on staged profiles during reconciliation, used as the "action" label on
+// stagedReconciled.
+const (
+	reconcileRequeued  = "requeued"
+	reconcileRemoved   = "removed"
+	reconcileAbandoned = "abandoned"
+)
+
+var (
+	eventsHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "worker",
+		Name:      "events_handled_total",
+		Help:      "The number of events handled by the worker, by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	mergeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "worker",
+		Name:      "merge_duration_seconds",
+		Help:      "How long it took to merge an uploaded profile into the base profile.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	pruneDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "autopgo",
+		Subsystem: "worker",
+		Name:      "prune_duration_seconds",
+		Help:      "How long it took to apply pruning rules to a merged profile.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	mergedProfileBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "autopgo",
+		Subsystem: "worker",
+		Name:      "merged_profile_bytes",
+		Help:      "The size of the most recently written merged profile, per application and channel.",
+	}, []string{"app", "channel"})
+
+	stagedReconciled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "autopgo",
+		Subsystem: "worker",
+		Name:      "staged_reconciled_total",
+		Help:      "The number of staged profiles found waiting beyond the reconciliation threshold, by action taken.",
+	}, []string{"app", "action"})
+)
+
+type (
+	countingReader struct {
+		io.Reader
+		n int64
+	}
+
+	countingWriter struct {
+		io.WriteCloser
+		n int64
+	}
+)
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.WriteCloser.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,199 @@
+package profile_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pprof "github.com/google/pprof/profile"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+	"github.com/davidsbond/autopgo/internal/event"
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/target"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestScraper_Metrics(t *testing.T) {
+	t.Parallel()
+
+	const app = "scraper-metrics"
+
+	client := mocks.NewMockClient(t)
+	source := mocks.NewMockTargetSource(t)
+	source.EXPECT().Name().Return("test")
+
+	// The first discovery fails, which should not stop the next from scraping the targets.
+	source.EXPECT().
+		List(mock.Anything).
+		Return(nil, io.EOF).
+		Once()
+
+	source.EXPECT().
+		List(mock.Anything).
+		Return([]target.Target{
+			{Address: "http://localhost:8080"},
+			{Address: "http://localhost:8081"},
+		}, nil).
+		Once()
+
+	client.EXPECT().
+		Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", 30*time.Second).
+		Return(validProfile, nil)
+
+	client.EXPECT().
+		Profile(mock.Anything, "http://localhost:8081/debug/pprof/profile", 30*time.Second).
+		Return(nil, io.EOF)
+
+	client.EXPECT().
+		UploadChannel(mock.Anything, app, "", mock.Anything).
+		Return(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	err := profile.NewScraper(client, profile.ScrapeConfig{
+		SampleSize:      2,
+		ProfileDuration: 30 * time.Second,
+		App:             app,
+		ScrapeFrequency: time.Second,
+	}).Scrape(ctx, source)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	labels := map[string]string{"app": app}
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_scraper_discovery_errors_total", map[string]string{"app": app, "source": "test"}))
+	assert.EqualValues(t, 2, testutil.MetricValue(t, "autopgo_scraper_targets_discovered", map[string]string{"app": app, "source": "test"}))
+	assert.EqualValues(t, 2, testutil.MetricValue(t, "autopgo_scraper_targets_sampled_total", labels))
+	assert.EqualValues(t, 2, testutil.MetricValue(t, "autopgo_scraper_scrape_attempts_total", labels))
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_scraper_scrape_successes_total", labels))
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_scraper_scrape_failures_total", map[string]string{"app": app, "reason": "fetch"}))
+	assert.EqualValues(t, 1, testutil.MetricValue(t, "autopgo_scraper_profile_bytes", labels))
+	assert.EqualValues(t, len(validProfile), testutil.HistogramSum(t, "autopgo_scraper_profile_bytes", labels))
+	assert.NotZero(t, testutil.MetricValue(t, "autopgo_scraper_last_upload_timestamp_seconds", labels))
+}
+
+func TestHTTPController_UploadMetrics(t *testing.T) {
+	// Server metrics have no labels to distinguish tests by, so this test does not run in parallel and checks the
+	// change in each metric instead.
+	upload := func(t *testing.T, data []byte, setup func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)) {
+		t.Helper()
+
+		blobs := mocks.NewMockBlobRepository(t)
+		events := mocks.NewMockEventWriter(t)
+		if setup != nil {
+			setup(blobs, events)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+		r.SetPathValue("app", "test-app")
+
+		profile.NewHTTPController(blobs, events, profile.UploadConfig{}).Upload(httptest.NewRecorder(), r)
+	}
+
+	noSamples := modifiedProfile(t, func(p *pprof.Profile) {
+		p.Sample = nil
+	})
+
+	bytesCount := testutil.MetricValue(t, "autopgo_server_upload_bytes", nil)
+	bytesSum := testutil.HistogramSum(t, "autopgo_server_upload_bytes", nil)
+	parseFailures := testutil.MetricValue(t, "autopgo_server_upload_parse_failures_total", nil)
+	rejections := testutil.MetricValue(t, "autopgo_server_upload_rejections_total", map[string]string{"reason": "no_samples"})
+
+	upload(t, validProfile, func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+		blobs.EXPECT().
+			NewWriter(mock.Anything, appKeyMatcher("test-app")).
+			Return(&WriteCloser{}, nil)
+
+		events.EXPECT().
+			Write(mock.Anything, uploadedEventMatcher("test-app")).
+			Return(nil)
+	})
+	upload(t, []byte("invalid profile"), nil)
+	upload(t, noSamples, nil)
+
+	// Profiles that cannot be parsed are not observed, so only the valid profile and the one without samples are.
+	assert.EqualValues(t, bytesCount+2, testutil.MetricValue(t, "autopgo_server_upload_bytes", nil))
+	assert.EqualValues(t, bytesSum+float64(len(validProfile)+len(noSamples)), testutil.HistogramSum(t, "autopgo_server_upload_bytes", nil))
+	assert.EqualValues(t, parseFailures+1, testutil.MetricValue(t, "autopgo_server_upload_parse_failures_total", nil))
+	assert.EqualValues(t, rejections+1, testutil.MetricValue(t, "autopgo_server_upload_rejections_total", map[string]string{"reason": "no_samples"}))
+}
+
+func TestWorker_Metrics(t *testing.T) {
+	// Events handled are labelled only by type and outcome, so this test does not run in parallel and checks the
+	// change in each metric instead.
+	const app = "worker-metrics"
+
+	blobs := mocks.NewMockBlobRepository(t)
+	events := mocks.NewMockEventWriter(t)
+	merged := &bufferWriteCloser{}
+
+	blobs.EXPECT().
+		Exists(mock.Anything, app+"/merges/12345").
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, app+"/staging/12345").
+		Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, app+"/default.pgo").
+		Return(nil, blob.ErrNotExist)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, app+"/default.pgo").
+		Return(merged, nil)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, app+"/merges/12345").
+		Return(&WriteCloser{}, nil)
+
+	events.EXPECT().
+		Write(mock.Anything, mock.Anything).
+		Return(nil)
+
+	blobs.EXPECT().
+		Exists(mock.Anything, app+"/merges/67890").
+		Return(false, io.EOF)
+
+	successes := testutil.MetricValue(t, "autopgo_worker_events_handled_total", map[string]string{"type": profile.EventTypeUploaded, "outcome": "success"})
+	failures := testutil.MetricValue(t, "autopgo_worker_events_handled_total", map[string]string{"type": profile.EventTypeUploaded, "outcome": "failure"})
+
+	uploaded := func(key string) event.Envelope {
+		return event.Envelope{
+			ID:        uuid.NewString(),
+			Timestamp: time.Now(),
+			Type:      profile.EventTypeUploaded,
+			Payload: mustMarshal(t, profile.UploadedEvent{
+				App:        app,
+				ProfileKey: key,
+			}),
+		}
+	}
+
+	worker := profile.NewWorker(blobs, events, nil, 0)
+	require.NoError(t, worker.HandleEvent(context.Background(), uploaded(app+"/staging/12345")))
+	require.Error(t, worker.HandleEvent(context.Background(), uploaded(app+"/staging/67890")))
+
+	assert.EqualValues(t, successes+1, testutil.MetricValue(t, "autopgo_worker_events_handled_total", map[string]string{"type": profile.EventTypeUploaded, "outcome": "success"}))
+	assert.EqualValues(t, failures+1, testutil.MetricValue(t, "autopgo_worker_events_handled_total", map[string]string{"type": profile.EventTypeUploaded, "outcome": "failure"}))
+	assert.EqualValues(t, merged.Len(), testutil.MetricValue(t, "autopgo_worker_merged_profile_bytes", map[string]string{"app": app, "channel": "default"}))
+}
+
+type (
+	bufferWriteCloser struct {
+		bytes.Buffer
+	}
+)
+
+func (b *bufferWriteCloser) Close() error {
+	return nil
+}
@@ -27,6 +27,54 @@ func (_m *MockBlobRepository) EXPECT() *MockBlobRepository_Expecter {
 	return &MockBlobRepository_Expecter{mock: &_m.Mock}
 }
 
+// Create provides a mock function with given fields: ctx, key, data
+func (_m *MockBlobRepository) Create(ctx context.Context, key string, data []byte) error {
+	ret := _m.Called(ctx, key, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(ctx, key, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockBlobRepository_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockBlobRepository_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - data []byte
+func (_e *MockBlobRepository_Expecter) Create(ctx interface{}, key interface{}, data interface{}) *MockBlobRepository_Create_Call {
+	return &MockBlobRepository_Create_Call{Call: _e.mock.On("Create", ctx, key, data)}
+}
+
+func (_c *MockBlobRepository_Create_Call) Run(run func(ctx context.Context, key string, data []byte)) *MockBlobRepository_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]byte))
+	})
+	return _c
+}
+
+func (_c *MockBlobRepository_Create_Call) Return(_a0 error) *MockBlobRepository_Create_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockBlobRepository_Create_Call) RunAndReturn(run func(context.Context, string, []byte) error) *MockBlobRepository_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Delete provides a mock function with given fields: ctx, key
 func (_m *MockBlobRepository) Delete(ctx context.Context, key string) error {
 	ret := _m.Called(ctx, key)
@@ -131,17 +179,17 @@ func (_c *MockBlobRepository_Exists_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
-// List provides a mock function with given fields: ctx, filter
-func (_m *MockBlobRepository) List(ctx context.Context, filter blob.Filter) iter.Seq2[blob.Object, error] {
-	ret := _m.Called(ctx, filter)
+// List provides a mock function with given fields: ctx, opts
+func (_m *MockBlobRepository) List(ctx context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error] {
+	ret := _m.Called(ctx, opts)
 
 	if len(ret) == 0 {
 		panic("no return value specified for List")
 	}
 
 	var r0 iter.Seq2[blob.Object, error]
-	if rf, ok := ret.Get(0).(func(context.Context, blob.Filter) iter.Seq2[blob.Object, error]); ok {
-		r0 = rf(ctx, filter)
+	if rf, ok := ret.Get(0).(func(context.Context, blob.ListOptions) iter.Seq2[blob.Object, error]); ok {
+		r0 = rf(ctx, opts)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(iter.Seq2[blob.Object, error])
@@ -158,14 +206,14 @@ type MockBlobRepository_List_Call struct {
 
 // List is a helper method to define mock.On call
 //   - ctx context.Context
-//   - filter blob.Filter
-func (_e *MockBlobRepository_Expecter) List(ctx interface{}, filter interface{}) *MockBlobRepository_List_Call {
-	return &MockBlobRepository_List_Call{Call: _e.mock.On("List", ctx, filter)}
+//   - opts blob.ListOptions
+func (_e *MockBlobRepository_Expecter) List(ctx interface{}, opts interface{}) *MockBlobRepository_List_Call {
+	return &MockBlobRepository_List_Call{Call: _e.mock.On("List", ctx, opts)}
 }
 
-func (_c *MockBlobRepository_List_Call) Run(run func(ctx context.Context, filter blob.Filter)) *MockBlobRepository_List_Call {
+func (_c *MockBlobRepository_List_Call) Run(run func(ctx context.Context, opts blob.ListOptions)) *MockBlobRepository_List_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(blob.Filter))
+		run(args[0].(context.Context), args[1].(blob.ListOptions))
 	})
 	return _c
 }
@@ -175,7 +223,7 @@ func (_c *MockBlobRepository_List_Call) Return(_a0 iter.Seq2[blob.Object, error]
 	return _c
 }
 
-func (_c *MockBlobRepository_List_Call) RunAndReturn(run func(context.Context, blob.Filter) iter.Seq2[blob.Object, error]) *MockBlobRepository_List_Call {
+func (_c *MockBlobRepository_List_Call) RunAndReturn(run func(context.Context, blob.ListOptions) iter.Seq2[blob.Object, error]) *MockBlobRepository_List_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -298,6 +346,63 @@ func (_c *MockBlobRepository_NewWriter_Call) RunAndReturn(run func(context.Conte
 	return _c
 }
 
+// Stat provides a mock function with given fields: ctx, key
+func (_m *MockBlobRepository) Stat(ctx context.Context, key string) (blob.Object, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stat")
+	}
+
+	var r0 blob.Object
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (blob.Object, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) blob.Object); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(blob.Object)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockBlobRepository_Stat_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stat'
+type MockBlobRepository_Stat_Call struct {
+	*mock.Call
+}
+
+// Stat is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *MockBlobRepository_Expecter) Stat(ctx interface{}, key interface{}) *MockBlobRepository_Stat_Call {
+	return &MockBlobRepository_Stat_Call{Call: _e.mock.On("Stat", ctx, key)}
+}
+
+func (_c *MockBlobRepository_Stat_Call) Run(run func(ctx context.Context, key string)) *MockBlobRepository_Stat_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockBlobRepository_Stat_Call) Return(_a0 blob.Object, _a1 error) *MockBlobRepository_Stat_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockBlobRepository_Stat_Call) RunAndReturn(run func(context.Context, string) (blob.Object, error)) *MockBlobRepository_Stat_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockBlobRepository creates a new instance of MockBlobRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockBlobRepository(t interface {
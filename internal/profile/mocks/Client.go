@@ -72,66 +72,77 @@ func (_c *MockClient_Download_Call) RunAndReturn(run func(context.Context, strin
 	return _c
 }
 
-// ProfileAndUpload provides a mock function with given fields: ctx, app, src, duration
-func (_m *MockClient) ProfileAndUpload(ctx context.Context, app string, src string, duration time.Duration) error {
-	ret := _m.Called(ctx, app, src, duration)
+// Profile provides a mock function with given fields: ctx, src, duration
+func (_m *MockClient) Profile(ctx context.Context, src string, duration time.Duration) ([]byte, error) {
+	ret := _m.Called(ctx, src, duration)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ProfileAndUpload")
+		panic("no return value specified for Profile")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) error); ok {
-		r0 = rf(ctx, app, src, duration)
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) ([]byte, error)); ok {
+		return rf(ctx, src, duration)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) []byte); ok {
+		r0 = rf(ctx, src, duration)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
+		r1 = rf(ctx, src, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// MockClient_ProfileAndUpload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ProfileAndUpload'
-type MockClient_ProfileAndUpload_Call struct {
+// MockClient_Profile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Profile'
+type MockClient_Profile_Call struct {
 	*mock.Call
 }
 
-// ProfileAndUpload is a helper method to define mock.On call
+// Profile is a helper method to define mock.On call
 //   - ctx context.Context
-//   - app string
 //   - src string
 //   - duration time.Duration
-func (_e *MockClient_Expecter) ProfileAndUpload(ctx interface{}, app interface{}, src interface{}, duration interface{}) *MockClient_ProfileAndUpload_Call {
-	return &MockClient_ProfileAndUpload_Call{Call: _e.mock.On("ProfileAndUpload", ctx, app, src, duration)}
+func (_e *MockClient_Expecter) Profile(ctx interface{}, src interface{}, duration interface{}) *MockClient_Profile_Call {
+	return &MockClient_Profile_Call{Call: _e.mock.On("Profile", ctx, src, duration)}
 }
 
-func (_c *MockClient_ProfileAndUpload_Call) Run(run func(ctx context.Context, app string, src string, duration time.Duration)) *MockClient_ProfileAndUpload_Call {
+func (_c *MockClient_Profile_Call) Run(run func(ctx context.Context, src string, duration time.Duration)) *MockClient_Profile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(time.Duration))
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Duration))
 	})
 	return _c
 }
 
-func (_c *MockClient_ProfileAndUpload_Call) Return(_a0 error) *MockClient_ProfileAndUpload_Call {
-	_c.Call.Return(_a0)
+func (_c *MockClient_Profile_Call) Return(_a0 []byte, _a1 error) *MockClient_Profile_Call {
+	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MockClient_ProfileAndUpload_Call) RunAndReturn(run func(context.Context, string, string, time.Duration) error) *MockClient_ProfileAndUpload_Call {
+func (_c *MockClient_Profile_Call) RunAndReturn(run func(context.Context, string, time.Duration) ([]byte, error)) *MockClient_Profile_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Upload provides a mock function with given fields: ctx, app, r
-func (_m *MockClient) Upload(ctx context.Context, app string, r io.Reader) error {
-	ret := _m.Called(ctx, app, r)
+// UploadChannel provides a mock function with given fields: ctx, app, channel, r
+func (_m *MockClient) UploadChannel(ctx context.Context, app string, channel string, r io.Reader) error {
+	ret := _m.Called(ctx, app, channel, r)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Upload")
+		panic("no return value specified for UploadChannel")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader) error); ok {
-		r0 = rf(ctx, app, r)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, io.Reader) error); ok {
+		r0 = rf(ctx, app, channel, r)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -139,32 +150,33 @@ func (_m *MockClient) Upload(ctx context.Context, app string, r io.Reader) error
 	return r0
 }
 
-// MockClient_Upload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Upload'
-type MockClient_Upload_Call struct {
+// MockClient_UploadChannel_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UploadChannel'
+type MockClient_UploadChannel_Call struct {
 	*mock.Call
 }
 
-// Upload is a helper method to define mock.On call
+// UploadChannel is a helper method to define mock.On call
 //   - ctx context.Context
 //   - app string
+//   - channel string
 //   - r io.Reader
-func (_e *MockClient_Expecter) Upload(ctx interface{}, app interface{}, r interface{}) *MockClient_Upload_Call {
-	return &MockClient_Upload_Call{Call: _e.mock.On("Upload", ctx, app, r)}
+func (_e *MockClient_Expecter) UploadChannel(ctx interface{}, app interface{}, channel interface{}, r interface{}) *MockClient_UploadChannel_Call {
+	return &MockClient_UploadChannel_Call{Call: _e.mock.On("UploadChannel", ctx, app, channel, r)}
 }
 
-func (_c *MockClient_Upload_Call) Run(run func(ctx context.Context, app string, r io.Reader)) *MockClient_Upload_Call {
+func (_c *MockClient_UploadChannel_Call) Run(run func(ctx context.Context, app string, channel string, r io.Reader)) *MockClient_UploadChannel_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(io.Reader))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(io.Reader))
 	})
 	return _c
 }
 
-func (_c *MockClient_Upload_Call) Return(_a0 error) *MockClient_Upload_Call {
+func (_c *MockClient_UploadChannel_Call) Return(_a0 error) *MockClient_UploadChannel_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockClient_Upload_Call) RunAndReturn(run func(context.Context, string, io.Reader) error) *MockClient_Upload_Call {
+func (_c *MockClient_UploadChannel_Call) RunAndReturn(run func(context.Context, string, string, io.Reader) error) *MockClient_UploadChannel_Call {
 	_c.Call.Return(run)
 	return _c
 }
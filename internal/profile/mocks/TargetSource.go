@@ -81,6 +81,51 @@ func (_c *MockTargetSource_List_Call) RunAndReturn(run func(context.Context) ([]
 	return _c
 }
 
+// Name provides a mock function with given fields:
+func (_m *MockTargetSource) Name() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Name")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MockTargetSource_Name_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Name'
+type MockTargetSource_Name_Call struct {
+	*mock.Call
+}
+
+// Name is a helper method to define mock.On call
+func (_e *MockTargetSource_Expecter) Name() *MockTargetSource_Name_Call {
+	return &MockTargetSource_Name_Call{Call: _e.mock.On("Name")}
+}
+
+func (_c *MockTargetSource_Name_Call) Run(run func()) *MockTargetSource_Name_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockTargetSource_Name_Call) Return(_a0 string) *MockTargetSource_Name_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockTargetSource_Name_Call) RunAndReturn(run func() string) *MockTargetSource_Name_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockTargetSource creates a new instance of MockTargetSource. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockTargetSource(t interface {
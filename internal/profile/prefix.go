@@ -0,0 +1,100 @@
+package profile
+
+import (
+	"context"
+	"io"
+	"iter"
+	"strings"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+)
+
+type (
+	// The PrefixedBlobRepository type is a BlobRepository implementation that stores all objects beneath a prefix
+	// within an underlying BlobRepository, allowing autopgo to share blob storage with other applications. Keys given
+	// to and returned from its methods do not include the prefix.
+	PrefixedBlobRepository struct {
+		blobs  BlobRepository
+		prefix string
+	}
+)
+
+// NewPrefixedBlobRepository returns a new instance of the PrefixedBlobRepository type that wraps the provided
+// BlobRepository implementation. A trailing slash is added to the prefix if it does not have one, so that objects are
+// stored within a directory. An empty prefix stores objects at the root of the underlying BlobRepository.
+func NewPrefixedBlobRepository(blobs BlobRepository, prefix string) *PrefixedBlobRepository {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &PrefixedBlobRepository{blobs: blobs, prefix: prefix}
+}
+
+// NewWriter calls NewWriter on the underlying BlobRepository with the prefixed key.
+func (p *PrefixedBlobRepository) NewWriter(ctx context.Context, key string) (io.WriteCloser, error) {
+	return p.blobs.NewWriter(ctx, p.prefix+key)
+}
+
+// NewReader calls NewReader on the underlying BlobRepository with the prefixed key.
+func (p *PrefixedBlobRepository) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.blobs.NewReader(ctx, p.prefix+key)
+}
+
+// Create calls Create on the underlying BlobRepository with the prefixed key.
+func (p *PrefixedBlobRepository) Create(ctx context.Context, key string, data []byte) error {
+	return p.blobs.Create(ctx, p.prefix+key, data)
+}
+
+// Delete calls Delete on the underlying BlobRepository with the prefixed key.
+func (p *PrefixedBlobRepository) Delete(ctx context.Context, key string) error {
+	return p.blobs.Delete(ctx, p.prefix+key)
+}
+
+// List calls List on the underlying BlobRepository, listing only objects beneath the prefix. The prefix is removed
+// from the keys of listed objects before they are filtered.
+func (p *PrefixedBlobRepository) List(ctx context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error] {
+	return func(yield func(blob.Object, error) bool) {
+		prefixed := blob.ListOptions{
+			Prefix:    p.prefix + opts.Prefix,
+			Delimiter: opts.Delimiter,
+		}
+
+		for object, err := range p.blobs.List(ctx, prefixed) {
+			if err != nil {
+				yield(blob.Object{}, err)
+				return
+			}
+
+			key, ok := strings.CutPrefix(object.Key, p.prefix)
+			if !ok {
+				continue
+			}
+
+			object.Key = key
+			if opts.Filter != nil && !opts.Filter(object) {
+				continue
+			}
+
+			if !yield(object, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Exists calls Exists on the underlying BlobRepository with the prefixed key.
+func (p *PrefixedBlobRepository) Exists(ctx context.Context, key string) (bool, error) {
+	return p.blobs.Exists(ctx, p.prefix+key)
+}
+
+// Stat calls Stat on the underlying BlobRepository with the prefixed key, removing the prefix from the key of the
+// returned object.
+func (p *PrefixedBlobRepository) Stat(ctx context.Context, key string) (blob.Object, error) {
+	object, err := p.blobs.Stat(ctx, p.prefix+key)
+	if err != nil {
+		return blob.Object{}, err
+	}
+
+	object.Key = strings.TrimPrefix(object.Key, p.prefix)
+	return object, nil
+}
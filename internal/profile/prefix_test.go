@@ -0,0 +1,133 @@
+package profile_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/testutil"
+)
+
+func TestPrefixedBlobRepository_Keys(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		Prefix   string
+		Expected string
+	}{
+		{
+			Name:     "no prefix",
+			Expected: "test/default.pgo",
+		},
+		{
+			Name:     "prefix with trailing slash",
+			Prefix:   "autopgo/",
+			Expected: "autopgo/test/default.pgo",
+		},
+		{
+			Name:     "prefix without trailing slash",
+			Prefix:   "shared/autopgo",
+			Expected: "shared/autopgo/test/default.pgo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := context.Background()
+			blobs := mocks.NewMockBlobRepository(t)
+			blobs.EXPECT().NewWriter(mock.Anything, tc.Expected).Return(nil, nil)
+			blobs.EXPECT().NewReader(mock.Anything, tc.Expected).Return(io.NopCloser(strings.NewReader("")), nil)
+			blobs.EXPECT().Create(mock.Anything, tc.Expected, []byte("test")).Return(nil)
+			blobs.EXPECT().Delete(mock.Anything, tc.Expected).Return(nil)
+			blobs.EXPECT().Exists(mock.Anything, tc.Expected).Return(true, nil)
+			blobs.EXPECT().Stat(mock.Anything, tc.Expected).Return(blob.Object{Key: tc.Expected, Size: 10}, nil)
+
+			prefixed := profile.NewPrefixedBlobRepository(blobs, tc.Prefix)
+
+			_, err := prefixed.NewWriter(ctx, "test/default.pgo")
+			require.NoError(t, err)
+
+			_, err = prefixed.NewReader(ctx, "test/default.pgo")
+			require.NoError(t, err)
+
+			require.NoError(t, prefixed.Create(ctx, "test/default.pgo", []byte("test")))
+			require.NoError(t, prefixed.Delete(ctx, "test/default.pgo"))
+
+			exists, err := prefixed.Exists(ctx, "test/default.pgo")
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			object, err := prefixed.Stat(ctx, "test/default.pgo")
+			require.NoError(t, err)
+			assert.EqualValues(t, blob.Object{Key: "test/default.pgo", Size: 10}, object)
+		})
+	}
+}
+
+func TestPrefixedBlobRepository_List(t *testing.T) {
+	t.Parallel()
+
+	objects := []blob.Object{
+		{Key: "autopgo/test/default.pgo"},
+		{Key: "autopgo/test/staging/12345"},
+		{Key: "autopgo/test/channels/prod/default.pgo"},
+		{Key: "other/test/default.pgo"},
+	}
+
+	tt := []struct {
+		Name     string
+		Options  blob.ListOptions
+		Expected []blob.Object
+	}{
+		{
+			Name: "lists objects beneath the prefix",
+			Expected: []blob.Object{
+				{Key: "test/channels/prod/default.pgo"},
+				{Key: "test/default.pgo"},
+				{Key: "test/staging/12345"},
+			},
+		},
+		{
+			Name:    "lists with a delimiter",
+			Options: blob.ListOptions{Prefix: "test/", Delimiter: "/"},
+			Expected: []blob.Object{
+				{Key: "test/channels/", IsDir: true},
+				{Key: "test/default.pgo"},
+				{Key: "test/staging/", IsDir: true},
+			},
+		},
+		{
+			Name:    "filters keys without the prefix",
+			Options: blob.ListOptions{Filter: profile.IsStaged("test", "")},
+			Expected: []blob.Object{
+				{Key: "test/staging/12345"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			blobs.EXPECT().
+				List(mock.Anything, mock.Anything).
+				RunAndReturn(testutil.ListObjects(objects...))
+
+			actual := make([]blob.Object, 0)
+			for object, err := range profile.NewPrefixedBlobRepository(blobs, "autopgo").List(context.Background(), tc.Options) {
+				require.NoError(t, err)
+				actual = append(actual, object)
+			}
+
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
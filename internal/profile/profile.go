@@ -2,12 +2,20 @@ package profile
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
 	"iter"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/google/pprof/profile"
+
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/event"
 )
@@ -21,13 +29,19 @@ type (
 		// NewReader should return an io.ReadCloser implementation that will read data from blob storage at the
 		// provided key. It should return blob.ErrNotExist if no data exists at the given key.
 		NewReader(ctx context.Context, key string) (io.ReadCloser, error)
+		// Create should store the data under the given key only if no object exists there, returning blob.ErrExist if
+		// one does.
+		Create(ctx context.Context, key string, data []byte) error
 		// Delete should remove data stored under the given key from the blob store. It should return blob.ErrNotExist
 		// if no object exists at the given key.
 		Delete(ctx context.Context, key string) error
-		// List should return all objects within the repository that match the provided filter.
-		List(ctx context.Context, filter blob.Filter) iter.Seq2[blob.Object, error]
+		// List should return all objects within the repository that match the provided options.
+		List(ctx context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error]
 		// Exists should return true if an object exists at the given path.
 		Exists(ctx context.Context, path string) (bool, error)
+		// Stat should return metadata on the object at the given key. It should return blob.ErrNotExist if no object
+		// exists at the given key.
+		Stat(ctx context.Context, key string) (blob.Object, error)
 	}
 
 	// The EventWriter interface describes types that can publish events onto an event bus such as Kafka, NATS, SQS
@@ -39,21 +53,23 @@ type (
 
 	// The Client interface describes types that can interact with the profile server and targets for profiling.
 	Client interface {
-		// Upload should write the profile data stored within the io.Reader implementation to the profile server for
-		// a specified application.
-		Upload(ctx context.Context, app string, r io.Reader) error
+		// UploadChannel should write the profile data stored within the io.Reader implementation to the profile server
+		// for a specified application and channel. An empty channel uploads to the default channel.
+		UploadChannel(ctx context.Context, app, channel string, r io.Reader) error
 		// Download should write the contents of a pprof profile from the profile server to the io.Writer implementation
 		// for the specified application.
 		Download(ctx context.Context, app string, w io.Writer) error
-		// ProfileAndUpload should obtain a profile from the given src URL for the specified duration and upload it
-		// to the server for the specified application.
-		ProfileAndUpload(ctx context.Context, app, src string, duration time.Duration) error
+		// Profile should obtain a profile from the given src URL for the specified duration and return its raw
+		// contents.
+		Profile(ctx context.Context, src string, duration time.Duration) ([]byte, error)
 	}
 
 	// The UploadedEvent type is an event.Payload implementation describing a single profile that has been uploaded.
 	UploadedEvent struct {
 		// The application the profile relates to.
 		App string `json:"app"`
+		// The channel the profile was uploaded to, empty for the default channel.
+		Channel string `json:"channel,omitempty"`
 		// The location of the profile within blob storage.
 		ProfileKey string `json:"profileKey"`
 	}
@@ -63,24 +79,71 @@ type (
 	MergedEvent struct {
 		// The application the profile relates to.
 		App string `json:"app"`
+		// The channel the profile was merged into, empty for the default channel.
+		Channel string `json:"channel,omitempty"`
 		// The location of the uploaded profile within blob storage.
 		ProfileKey string `json:"profileKey"`
 		// The location of the base profile that has been merged.
 		MergedKey string `json:"mergedKey"`
+		// The generation of the merged profile, zero if generations are not being kept.
+		Version int `json:"version,omitempty"`
 	}
 
 	// The DeletedEvent type is an event.Payload implementation describing a profile that has been deleted.
 	DeletedEvent struct {
 		// The application profile that has been deleted.
 		App string `json:"app"`
+		// The channel that has been deleted, empty if all of the application's channels have been deleted.
+		Channel string `json:"channel,omitempty"`
+	}
+
+	// The RolledBackEvent type is an event.Payload implementation describing a request to restore a previous
+	// generation of an application's profile.
+	RolledBackEvent struct {
+		// The application whose profile is being rolled back.
+		App string `json:"app"`
+		// The channel whose profile is being rolled back, empty for the default channel.
+		Channel string `json:"channel,omitempty"`
+		// The generation of the profile to restore.
+		Version int `json:"version"`
+	}
+
+	// The PromotedEvent type is an event.Payload implementation describing a request to promote a generation of a
+	// channel's profile to the application's stable profile, served from the default channel.
+	PromotedEvent struct {
+		// The application whose profile is being promoted.
+		App string `json:"app"`
+		// The channel the promoted generation belongs to.
+		Channel string `json:"channel"`
+		// The generation of the channel's profile being promoted.
+		Version int `json:"version"`
+		// The principal that requested the promotion, empty if authentication is not enabled.
+		PromotedBy string `json:"promotedBy,omitempty"`
+		// When the promotion was requested.
+		PromotedAt time.Time `json:"promotedAt"`
+	}
+
+	// The Promotion type describes a single promotion of a channel's profile to an application's stable profile, as
+	// recorded within blob storage.
+	Promotion struct {
+		// The channel the promoted generation belongs to.
+		Channel string `json:"channel"`
+		// The generation of the channel's profile that was promoted.
+		Version int `json:"version"`
+		// The principal that requested the promotion, empty if authentication is not enabled.
+		PromotedBy string `json:"promotedBy,omitempty"`
+		// When the promotion was requested.
+		PromotedAt time.Time `json:"promotedAt"`
 	}
 )
 
 // Constants for event types.
 const (
-	EventTypeUploaded = "profile.uploaded"
-	EventTypeMerged   = "profile.merged"
-	EventTypeDeleted  = "profile.deleted"
+	EventTypeUploaded   = "profile.uploaded"
+	EventTypeMerged     = "profile.merged"
+	EventTypeDeleted    = "profile.deleted"
+	EventTypeRolledBack = "profile.rolledback"
+	EventTypePromoted   = "profile.promoted"
 )
 
 // Type returns EventTypeUploaded.
@@ -113,6 +176,63 @@ func (e DeletedEvent) Key() string {
 	return e.App
 }
 
+// Type returns EventTypeRolledBack.
+func (e RolledBackEvent) Type() string {
+	return EventTypeRolledBack
+}
+
+// Key returns the application name.
+func (e RolledBackEvent) Key() string {
+	return e.App
+}
+
+// Type returns EventTypePromoted.
+func (e PromotedEvent) Type() string {
+	return EventTypePromoted
+}
+
+// Key returns the application name.
+func (e PromotedEvent) Key() string {
+	return e.App
+}
+
+var (
+	// ErrNotCPUProfile is the error given when a profile does not contain the sample types expected of a CPU profile.
+	ErrNotCPUProfile = errors.New("not a cpu profile")
+)
+
+// CPUUsage returns the total number of samples and the total CPU time recorded within a CPU profile. Returns
+// ErrNotCPUProfile if the profile does not contain both samples/count and cpu/nanoseconds sample types.
+func CPUUsage(p *profile.Profile) (int64, time.Duration, error) {
+	samplesIndex, cpuIndex := -1, -1
+	for i, st := range p.SampleType {
+		switch {
+		case st.Type == "samples" && st.Unit == "count":
+			samplesIndex = i
+		case st.Type == "cpu" && st.Unit == "nanoseconds":
+			cpuIndex = i
+		}
+	}
+
+	if samplesIndex == -1 || cpuIndex == -1 {
+		return 0, 0, ErrNotCPUProfile
+	}
+
+	var samples, cpu int64
+	for _, sample := range p.Sample {
+		samples += sample.Value[samplesIndex]
+		cpu += sample.Value[cpuIndex]
+	}
+
+	return samples, time.Duration(cpu), nil
+}
+
+// ETag returns a strong entity tag for the given profile contents, derived from its SHA-256 hash.
+func ETag(content []byte) string {
+	hash := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
 // IsValidAppName returns false if the application name contains any characters that are not a-z, 0-9 or hyphens.
 func IsValidAppName(app string) bool {
 	for _, r := range app {
@@ -130,18 +250,355 @@ func IsValidAppName(app string) bool {
 	return true
 }
 
-// IsMergedProfile returns a blob.Filter that returns true for any object keys that match those of a merged
+// DefaultChannel is the name of the channel used when one is not specified. Its profiles are stored at the root of
+// the application, so "default" and an empty channel are interchangeable.
+const DefaultChannel = "default"
+
+// reservedChannels contains names that cannot be used as channels as they would conflict with other endpoints
+// beneath /api/profile/{app}.
+var reservedChannels = []string{
+	"versions", "rollback", "promote", "promotions", "pin", "unpin", "summary", "edges", "flamegraph", "diff", "staging",
+}
+
+// IsValidChannelName returns false if the channel name is empty, contains any characters that are not a-z, 0-9 or
+// hyphens, or conflicts with an API endpoint.
+func IsValidChannelName(channel string) bool {
+	return channel != "" && IsValidAppName(channel) && !slices.Contains(reservedChannels, channel)
+}
+
+// channelName returns the name of the channel for use in logs, metrics & traces, where an empty channel is the
+// default channel.
+func channelName(channel string) string {
+	if channel == "" {
+		return DefaultChannel
+	}
+
+	return channel
+}
+
+// channelRoot returns the location in blob storage beneath which all profiles for the channel are stored.
+func channelRoot(app, channel string) string {
+	if channel == "" || channel == DefaultChannel {
+		return app
+	}
+
+	return path.Join(app, "channels", channel)
+}
+
+// The appChannel type identifies a single channel of an application, where an empty channel is the default channel.
+type appChannel struct {
+	app     string
+	channel string
+	// The merged profile of the default channel, found while listing the application. Nil for other channels and
+	// when the default channel has no merged profile.
+	merged *blob.Object
+}
+
+// listChannels returns an iterator over every channel of the applications whose names begin with the prefix,
+// including the default channel of each. Rather than listing every object, the directories of each application are
+// listed using a delimiter, so the number of requests made to the blob store grows with the number of applications &
+// channels rather than the number of staged profiles, versions & other objects. Each application's directory is
+// listed once to find the merged profile of its default channel and whether it has other channels, which are only
+// listed when it does. Applications are listed in the order of their keys, skipping those whose keys come before that
+// of the application named by after, and the default channel of each is followed by its other channels in the order
+// of their keys.
+func listChannels(ctx context.Context, blobs BlobRepository, prefix, after string) iter.Seq2[appChannel, error] {
+	return func(yield func(appChannel, error) bool) {
+		for dir, err := range blobs.List(ctx, blob.ListOptions{Prefix: prefix, Delimiter: "/"}) {
+			if err != nil {
+				yield(appChannel{}, err)
+				return
+			}
+
+			app, ok := strings.CutSuffix(dir.Key, "/")
+			if !dir.IsDir || !ok || !IsValidAppName(app) || dir.Key < keyOrder(after) {
+				continue
+			}
+
+			var merged *blob.Object
+			var channels bool
+			for object, err := range blobs.List(ctx, blob.ListOptions{Prefix: dir.Key, Delimiter: "/"}) {
+				if err != nil {
+					yield(appChannel{}, err)
+					return
+				}
+
+				switch {
+				case !object.IsDir && object.Key == MergedKey(app, ""):
+					merged = &object
+				case object.IsDir && object.Key == channelsPrefix(app):
+					channels = true
+				}
+			}
+
+			if !yield(appChannel{app: app, merged: merged}, nil) {
+				return
+			}
+
+			if !channels {
+				continue
+			}
+
+			for dir, err := range blobs.List(ctx, blob.ListOptions{Prefix: channelsPrefix(app), Delimiter: "/"}) {
+				if err != nil {
+					yield(appChannel{}, err)
+					return
+				}
+
+				channel, ok := strings.CutSuffix(strings.TrimPrefix(dir.Key, channelsPrefix(app)), "/")
+				if !dir.IsDir || !ok || !IsValidChannelName(channel) {
+					continue
+				}
+
+				if !yield(appChannel{app: app, channel: channel}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// keyOrder returns the string that an application or channel name is ordered by when listed from blob storage, where
+// the keys of its objects follow the name with a "/". This differs from the order of the names themselves when one is
+// the prefix of another followed by a "-", such as "app" & "app-2". An empty name, the default channel, comes first.
+func keyOrder(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return name + "/"
+}
+
+// channelPrefix returns the prefix of the keys of every object in blob storage belonging to an application's channel.
+// An empty channel returns the prefix of every object belonging to the application, including those of all its
+// channels.
+func channelPrefix(app, channel string) string {
+	return channelRoot(app, channel) + "/"
+}
+
+// channelsPrefix returns the prefix of the keys of every object in blob storage belonging to an application's
+// channels, other than the default channel.
+func channelsPrefix(app string) string {
+	return path.Join(app, "channels") + "/"
+}
+
+// MergedKey returns the location in blob storage of the merged profile for an application's channel.
+func MergedKey(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "default.pgo")
+}
+
+// PinnedKey returns the location in blob storage of the pinned profile for an application's channel, which is served
+// in place of the merged profile while it exists.
+func PinnedKey(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "pinned.pgo")
+}
+
+// StableKey returns the location in blob storage of an application's stable profile, which is written only by
+// promotions and is served in place of the default channel's merged profile while it exists.
+func StableKey(app string) string {
+	return path.Join(app, "stable.pgo")
+}
+
+// servedKeys returns the locations in blob storage of the profiles that may be served for an application's channel,
+// in the order they are preferred: the pinned profile, the stable profile for the default channel, then the merged
 // profile.
-func IsMergedProfile() blob.Filter {
+func servedKeys(app, channel string) []string {
+	if channelName(channel) == DefaultChannel {
+		return []string{PinnedKey(app, channel), StableKey(app), MergedKey(app, channel)}
+	}
+
+	return []string{PinnedKey(app, channel), MergedKey(app, channel)}
+}
+
+// UploadRecordKey returns the location in blob storage of the record used to detect duplicate uploads to an
+// application's channel, where the id identifies the upload by its content or idempotency key.
+func UploadRecordKey(app, channel, id string) string {
+	return path.Join(channelRoot(app, channel), "uploads", id+".json")
+}
+
+// IsUploadRecord returns a blob.Filter that returns true for any object keys that match those of the records used to
+// detect duplicate uploads to an application's channel.
+func IsUploadRecord(app, channel string) blob.Filter {
 	return func(obj blob.Object) bool {
-		return strings.HasSuffix(obj.Key, "default.pgo")
+		name, ok := strings.CutPrefix(obj.Key, uploadRecordsPrefix(app, channel))
+		return ok && strings.HasSuffix(name, ".json") && !strings.Contains(name, "/")
 	}
 }
 
-// IsApplication returns a blob.Filter that returns true for any object keys that have the provided application
-// name as a prefix.
-func IsApplication(app string) blob.Filter {
+// uploadRecordsPrefix returns the prefix of the keys of the records used to detect duplicate uploads to an
+// application's channel.
+func uploadRecordsPrefix(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "uploads") + "/"
+}
+
+// StagingKey returns the location in blob storage for a profile uploaded to an application's channel at the given
+// time, where it waits to be merged.
+func StagingKey(app, channel string, t time.Time) string {
+	return stagingPrefix(app, channel) + strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// stagingPrefix returns the prefix of the keys of profiles uploaded to an application's channel that are waiting to be
+// merged.
+func stagingPrefix(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "staging") + "/"
+}
+
+// ParseStagingID returns the time a staged profile was uploaded from its identifier, which is the final element of its
+// key. Returns false if the identifier is not that of a staged profile.
+func ParseStagingID(id string) (time.Time, bool) {
+	nanos, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || nanos <= 0 || strconv.FormatInt(nanos, 10) != id {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, nanos).UTC(), true
+}
+
+// IsStaged returns a blob.Filter that returns true for any object keys that match those of a profile uploaded to an
+// application's channel that is waiting to be merged.
+func IsStaged(app, channel string) blob.Filter {
+	return func(obj blob.Object) bool {
+		id, ok := strings.CutPrefix(obj.Key, stagingPrefix(app, channel))
+		if !ok {
+			return false
+		}
+
+		_, ok = ParseStagingID(id)
+		return ok
+	}
+}
+
+// MergeRecordKey returns the location in blob storage of the record written once the staged profile at the given key
+// has been merged into an application's channel, so that it is not merged again if the event announcing the merge is
+// lost.
+func MergeRecordKey(app, channel, stagingKey string) string {
+	return mergeRecordsPrefix(app, channel) + path.Base(stagingKey)
+}
+
+// mergeRecordsPrefix returns the prefix of the keys of the records of staged profiles that have been merged into an
+// application's channel.
+func mergeRecordsPrefix(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "merges") + "/"
+}
+
+// IsMergeRecord returns a blob.Filter that returns true for any object keys that match those of the records of staged
+// profiles that have been merged into an application's channel.
+func IsMergeRecord(app, channel string) blob.Filter {
+	return func(obj blob.Object) bool {
+		id, ok := strings.CutPrefix(obj.Key, mergeRecordsPrefix(app, channel))
+		if !ok {
+			return false
+		}
+
+		_, ok = ParseStagingID(id)
+		return ok
+	}
+}
+
+// RequeueRecordKey returns the location in blob storage of the record of the times the staged profile at the given
+// key has been requeued by reconciliation. It is stored alongside the profile's merge record.
+func RequeueRecordKey(app, channel, stagingKey string) string {
+	return MergeRecordKey(app, channel, stagingKey) + requeueRecordSuffix
+}
+
+const requeueRecordSuffix = ".requeue"
+
+// IsRequeueRecord returns a blob.Filter that returns true for any object keys that match those of the records of
+// staged profiles requeued by reconciliation within an application's channel.
+func IsRequeueRecord(app, channel string) blob.Filter {
+	return func(obj blob.Object) bool {
+		id, ok := strings.CutPrefix(obj.Key, mergeRecordsPrefix(app, channel))
+		if !ok {
+			return false
+		}
+
+		id, ok = strings.CutSuffix(id, requeueRecordSuffix)
+		if !ok {
+			return false
+		}
+
+		_, ok = ParseStagingID(id)
+		return ok
+	}
+}
+
+// ParseMergedKey returns the application and channel of the merged profile stored at the given key. The channel is
+// empty for the default channel. Returns false if the key is not that of a merged profile.
+func ParseMergedKey(key string) (string, string, bool) {
+	dir, ok := strings.CutSuffix(key, "/default.pgo")
+	if !ok {
+		return "", "", false
+	}
+
+	app, channel, ok := strings.Cut(dir, "/channels/")
+	switch {
+	case !ok && IsValidAppName(app):
+		return app, "", true
+	case ok && IsValidAppName(app) && IsValidChannelName(channel):
+		return app, channel, true
+	default:
+		return "", "", false
+	}
+}
+
+// IsVersion returns a blob.Filter that returns true for any object keys that match those of a generation of the
+// merged profile for an application's channel.
+func IsVersion(app, channel string) blob.Filter {
+	return func(obj blob.Object) bool {
+		_, ok := ParseVersionKey(app, channel, obj.Key)
+		return ok
+	}
+}
+
+// VersionKey returns the location in blob storage of a generation of the merged profile for an application's
+// channel.
+func VersionKey(app, channel string, version int) string {
+	return path.Join(channelRoot(app, channel), "versions", strconv.Itoa(version)+".pgo")
+}
+
+// versionsPrefix returns the prefix of the keys of every generation of the merged profile for an application's
+// channel.
+func versionsPrefix(app, channel string) string {
+	return path.Join(channelRoot(app, channel), "versions") + "/"
+}
+
+// ParseVersionKey returns the generation number of the profile stored at the given key. Returns false if the key is
+// not that of a generation of the merged profile for the application's channel.
+func ParseVersionKey(app, channel, key string) (int, bool) {
+	name, ok := strings.CutPrefix(key, versionsPrefix(app, channel))
+	if !ok {
+		return 0, false
+	}
+
+	name, ok = strings.CutSuffix(name, ".pgo")
+	if !ok {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(name)
+	if err != nil || version <= 0 || strconv.Itoa(version) != name {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// PromotionKey returns the location in blob storage of the record of a promotion to an application's stable profile
+// requested at the given time.
+func PromotionKey(app string, t time.Time) string {
+	return path.Join(app, "promotions", strconv.FormatInt(t.UnixNano(), 10)+".json")
+}
+
+// promotionsPrefix returns the prefix of the keys of the promotion records for an application.
+func promotionsPrefix(app string) string {
+	return path.Join(app, "promotions") + "/"
+}
+
+// IsPromotion returns a blob.Filter that returns true for any object keys that match those of a promotion record for
+// an application.
+func IsPromotion(app string) blob.Filter {
 	return func(obj blob.Object) bool {
-		return strings.HasPrefix(obj.Key, app+"/")
+		name, ok := strings.CutPrefix(obj.Key, promotionsPrefix(app))
+		return ok && strings.HasSuffix(name, ".json") && !strings.Contains(name, "/")
 	}
 }
@@ -2,47 +2,87 @@ package profile_test
 
 import (
 	"testing"
+	"time"
 
+	pprof "github.com/google/pprof/profile"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/profile"
 )
 
-func TestIsMergedProfile(t *testing.T) {
+func TestIsVersion(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
 		Name     string
+		App      string
+		Channel  string
 		Object   blob.Object
 		Expected bool
 	}{
 		{
-			Name:     "should return true for merged profiles",
+			Name:     "should return true for a profile version",
 			Expected: true,
+			App:      "test",
+			Object: blob.Object{
+				Key: "test/versions/12.pgo",
+			},
+		},
+		{
+			Name:     "should return false for the merged profile",
+			App:      "test",
+			Expected: false,
 			Object: blob.Object{
 				Key: "test/default.pgo",
 			},
 		},
 		{
-			Name:     "should return false for non-merged profiles",
+			Name:     "should return false for another application",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test-1/versions/12.pgo",
+			},
+		},
+		{
+			Name:     "should return true for a profile version in a channel",
+			Expected: true,
+			App:      "test",
+			Channel:  "prod",
+			Object: blob.Object{
+				Key: "test/channels/prod/versions/12.pgo",
+			},
+		},
+		{
+			Name:     "should return false for a profile version in another channel",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/channels/prod/versions/12.pgo",
+			},
+		},
+		{
+			Name:     "should return false for a non-numeric version",
+			App:      "test",
 			Expected: false,
 			Object: blob.Object{
-				Key: "test/staging/010101010",
+				Key: "test/versions/latest.pgo",
 			},
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			filter := profile.IsMergedProfile()
+			filter := profile.IsVersion(tc.App, tc.Channel)
 			actual := filter(tc.Object)
 			assert.EqualValues(t, tc.Expected, actual)
 		})
 	}
 }
 
-func TestIsApplication(t *testing.T) {
+func TestIsPromotion(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
@@ -52,28 +92,259 @@ func TestIsApplication(t *testing.T) {
 		Expected bool
 	}{
 		{
-			Name:     "should return true for the correct application name",
+			Name:     "should return true for a promotion record",
 			Expected: true,
 			App:      "test",
+			Object: blob.Object{
+				Key: "test/promotions/1577836800000000000.json",
+			},
+		},
+		{
+			Name:     "should return false for the merged profile",
+			App:      "test",
+			Expected: false,
 			Object: blob.Object{
 				Key: "test/default.pgo",
 			},
 		},
 		{
-			Name:     "should return false for for an incorrect application name",
-			App:      "test-1",
+			Name:     "should return false for another application",
+			App:      "test",
 			Expected: false,
 			Object: blob.Object{
-				Key: "test/staging/010101010",
+				Key: "test-1/promotions/1577836800000000000.json",
 			},
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			filter := profile.IsApplication(tc.App)
-			actual := filter(tc.Object)
-			assert.EqualValues(t, tc.Expected, actual)
+			assert.Equal(t, tc.Expected, profile.IsPromotion(tc.App)(tc.Object))
+		})
+	}
+}
+
+func TestIsStaged(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		App      string
+		Channel  string
+		Object   blob.Object
+		Expected bool
+	}{
+		{
+			Name:     "should return true for a staged profile",
+			Expected: true,
+			App:      "test",
+			Object: blob.Object{
+				Key: "test/staging/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return true for a staged profile of a channel",
+			Expected: true,
+			App:      "test",
+			Channel:  "prod",
+			Object: blob.Object{
+				Key: "test/channels/prod/staging/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return false for another channel",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/channels/prod/staging/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return false for an invalid identifier",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/staging/latest",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, profile.IsStaged(tc.App, tc.Channel)(tc.Object))
+		})
+	}
+}
+
+func TestIsMergeRecord(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		App      string
+		Channel  string
+		Object   blob.Object
+		Expected bool
+	}{
+		{
+			Name:     "should return true for a merge record",
+			Expected: true,
+			App:      "test",
+			Object: blob.Object{
+				Key: "test/merges/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return true for a merge record of a channel",
+			Expected: true,
+			App:      "test",
+			Channel:  "prod",
+			Object: blob.Object{
+				Key: "test/channels/prod/merges/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return false for a staged profile",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/staging/1577836800000000000",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, profile.IsMergeRecord(tc.App, tc.Channel)(tc.Object))
+		})
+	}
+}
+
+func TestIsUploadRecord(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		App      string
+		Channel  string
+		Object   blob.Object
+		Expected bool
+	}{
+		{
+			Name:     "should return true for an upload record",
+			Expected: true,
+			App:      "test",
+			Object: blob.Object{
+				Key: "test/uploads/sha256-abc.json",
+			},
+		},
+		{
+			Name:     "should return true for an upload record in a channel",
+			Expected: true,
+			App:      "test",
+			Channel:  "prod",
+			Object: blob.Object{
+				Key: "test/channels/prod/uploads/sha256-abc.json",
+			},
+		},
+		{
+			Name:     "should return false for a staged profile",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/staging/1577836800000000000",
+			},
+		},
+		{
+			Name:     "should return false for another channel",
+			App:      "test",
+			Expected: false,
+			Object: blob.Object{
+				Key: "test/channels/prod/uploads/sha256-abc.json",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, profile.IsUploadRecord(tc.App, tc.Channel)(tc.Object))
+		})
+	}
+}
+
+func TestParseMergedKey(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name            string
+		Key             string
+		ExpectedApp     string
+		ExpectedChannel string
+		ExpectedOK      bool
+	}{
+		{
+			Name:        "default channel",
+			Key:         "test/default.pgo",
+			ExpectedApp: "test",
+			ExpectedOK:  true,
+		},
+		{
+			Name:            "named channel",
+			Key:             "test/channels/prod/default.pgo",
+			ExpectedApp:     "test",
+			ExpectedChannel: "prod",
+			ExpectedOK:      true,
+		},
+		{
+			Name: "not a merged profile",
+			Key:  "test/staging/010101010",
+		},
+		{
+			Name: "nested beneath another path",
+			Key:  "test/other/default.pgo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			app, channel, ok := profile.ParseMergedKey(tc.Key)
+			assert.Equal(t, tc.ExpectedOK, ok)
+			assert.Equal(t, tc.ExpectedApp, app)
+			assert.Equal(t, tc.ExpectedChannel, channel)
+		})
+	}
+}
+
+func TestIsValidChannelName(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		Channel  string
+		Expected bool
+	}{
+		{
+			Name:     "valid channel",
+			Channel:  "load-test",
+			Expected: true,
+		},
+		{
+			Name:    "empty channel",
+			Channel: "",
+		},
+		{
+			Name:    "invalid characters",
+			Channel: "Prod/1",
+		},
+		{
+			Name:    "reserved name",
+			Channel: "versions",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, profile.IsValidChannelName(tc.Channel))
 		})
 	}
 }
@@ -105,3 +376,41 @@ func TestIsValidAppName(t *testing.T) {
 		})
 	}
 }
+
+func TestCPUUsage(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name            string
+		Profile         *pprof.Profile
+		ExpectedSamples int64
+		ExpectedCPU     time.Duration
+		ExpectedError   error
+	}{
+		{
+			Name:            "should sum samples and cpu time",
+			Profile:         mustParse(t, validProfile),
+			ExpectedSamples: 224780,
+			ExpectedCPU:     2247800 * time.Millisecond,
+		},
+		{
+			Name: "should return an error for non-cpu profiles",
+			Profile: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "alloc_objects", Unit: "count"},
+					{Type: "alloc_space", Unit: "bytes"},
+				},
+			},
+			ExpectedError: profile.ErrNotCPUProfile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			samples, cpu, err := profile.CPUUsage(tc.Profile)
+			require.ErrorIs(t, err, tc.ExpectedError)
+			assert.EqualValues(t, tc.ExpectedSamples, samples)
+			assert.EqualValues(t, tc.ExpectedCPU, cpu)
+		})
+	}
+}
@@ -1,16 +1,26 @@
 package profile
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"iter"
 	"log/slog"
+	"maps"
 	"math/rand"
 	"net/url"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/pprof/profile"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/davidsbond/autopgo/internal/logger"
 	"github.com/davidsbond/autopgo/internal/target"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 type (
@@ -25,25 +35,65 @@ type (
 		ScrapeFrequency time.Duration
 		// The application this scraper instance is collecting profiles for.
 		App string
+		// The channel profiles are uploaded to, the default channel is used when empty.
+		Channel string
+		// The minimum number of samples a profile must contain to be uploaded.
+		MinSamples int64
+		// The minimum total CPU time a profile must contain to be uploaded.
+		MinCPUTime time.Duration
+		// Optional configuration for adjusting the ProfileDuration and SampleSize based on the number of samples
+		// obtained. When nil, the ProfileDuration and SampleSize remain fixed.
+		Adaptive *AdaptiveConfig
 	}
 
 	// The Scraper type is used to perform periodic sampling of pprof profiles given a selection of valid
 	// targets. These profiles are then forwarded to the configured profile server.
 	Scraper struct {
 		app             string
+		channel         string
 		sampleSize      uint
 		scrapeFrequency time.Duration
 		profileDuration time.Duration
+		minSamples      int64
+		minCPUTime      time.Duration
+		adaptive        *AdaptiveConfig
 
 		client Client
 		rand   *rand.Rand
+
+		mux    sync.RWMutex
+		status map[string]TargetStatus
 	}
 
 	// The TargetSource interface describes types that can list scraping targets.
 	TargetSource interface {
+		// Name should return a name that describes the source of targets.
+		Name() string
 		// List should return all targets that are available to be scraped.
 		List(ctx context.Context) ([]target.Target, error)
 	}
+
+	// The TargetStatus type describes a single target discovered by the Scraper and the outcome of the last attempt
+	// to scrape it.
+	TargetStatus struct {
+		// The target address.
+		Address string `json:"address"`
+		// The path to the pprof profile endpoint.
+		Path string `json:"path,omitempty"`
+		// Labels describing the target.
+		Labels map[string]string `json:"labels,omitempty"`
+		// When the target was last scraped. This is zero if the target has not been scraped since it was discovered.
+		LastScrape time.Time `json:"lastScrape,omitzero"`
+		// How long the last scrape took, including the profile upload.
+		LastScrapeDuration time.Duration `json:"lastScrapeDuration"`
+		// The number of bytes uploaded to the profile server by the last scrape.
+		BytesUploaded int64 `json:"bytesUploaded"`
+		// The error message from the last scrape, if it failed.
+		LastError string `json:"lastError,omitempty"`
+		// The number of profiles obtained from the target that were dropped for containing too few samples or too
+		// little CPU time.
+		Skipped uint64 `json:"skipped"`
+	}
 )
 
 // NewScraper returns a new instance of the Scraper type using the provided configuration.
@@ -51,10 +101,15 @@ func NewScraper(client Client, config ScrapeConfig) *Scraper {
 	return &Scraper{
 		sampleSize:      config.SampleSize,
 		profileDuration: config.ProfileDuration,
+		minSamples:      config.MinSamples,
+		minCPUTime:      config.MinCPUTime,
+		adaptive:        config.Adaptive,
 		scrapeFrequency: config.ScrapeFrequency,
 		rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
 		client:          client,
 		app:             config.App,
+		channel:         config.Channel,
+		status:          make(map[string]TargetStatus),
 	}
 }
 
@@ -68,20 +123,105 @@ func (s *Scraper) Scrape(ctx context.Context, source TargetSource) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
+			// Sources are often temporarily unavailable, so a failed discovery is retried on the next scrape rather
+			// than stopping the scraper.
 			targets, err := source.List(ctx)
 			if err != nil {
-				return err
+				discoveryErrors.WithLabelValues(s.app, source.Name()).Inc()
+				logger.FromContext(ctx).
+					With(slog.String("error", err.Error()), slog.String("source", source.Name())).
+					ErrorContext(ctx, "failed to discover targets")
+				continue
 			}
 
+			targetsDiscovered.WithLabelValues(s.app, source.Name()).Set(float64(len(targets)))
+			s.discovered(targets)
+
 			var group sync.WaitGroup
+			var totals scrapeTotals
 			for t := range s.sample(ctx, targets) {
+				targetsSampled.WithLabelValues(s.app).Inc()
 				group.Add(1)
-				go s.forwardProfile(ctx, &group, t)
+				go s.forwardProfile(ctx, &group, t, &totals)
 			}
 
 			group.Wait()
+
+			// Failed scrapes say nothing about the number of samples the targets produce, so the configuration is
+			// only adapted when every scrape succeeded.
+			if s.adaptive != nil && ctx.Err() == nil && totals.failures.Load() == 0 {
+				s.adapt(ctx, totals.samples.Load())
+			}
+		}
+	}
+}
+
+// Targets returns the status of all targets discovered during the most recent scrape, ordered by address.
+func (s *Scraper) Targets() []TargetStatus {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	targets := slices.AppendSeq(make([]TargetStatus, 0, len(s.status)), maps.Values(s.status))
+	slices.SortFunc(targets, func(a, b TargetStatus) int {
+		return cmp.Or(cmp.Compare(a.Address, b.Address), cmp.Compare(a.Path, b.Path))
+	})
+
+	return targets
+}
+
+func (s *Scraper) discovered(targets []target.Target) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	status := make(map[string]TargetStatus, len(targets))
+	for _, t := range targets {
+		key := targetKey(t)
+
+		current, ok := s.status[key]
+		if !ok {
+			current = TargetStatus{
+				Address: t.Address,
+				Path:    t.Path,
+			}
 		}
+
+		current.Labels = t.Labels
+		status[key] = current
 	}
+
+	s.status = status
+}
+
+func (s *Scraper) scraped(t target.Target, start time.Time, result scrapeResult, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	key := targetKey(t)
+
+	// The target may have been removed from the source while it was being scraped, in which case there's no status
+	// to update.
+	current, ok := s.status[key]
+	if !ok {
+		return
+	}
+
+	current.LastScrape = start
+	current.LastScrapeDuration = time.Since(start)
+	current.BytesUploaded = result.size
+	current.LastError = ""
+	if err != nil {
+		current.LastError = err.Error()
+	}
+
+	if result.skipped {
+		current.Skipped++
+	}
+
+	s.status[key] = current
+}
+
+func targetKey(t target.Target) string {
+	return t.Address + t.Path
 }
 
 func (s *Scraper) sample(ctx context.Context, targets []target.Target) iter.Seq[target.Target] {
@@ -108,19 +248,62 @@ func (s *Scraper) sample(ctx context.Context, targets []target.Target) iter.Seq[
 	}
 }
 
-func (s *Scraper) forwardProfile(ctx context.Context, group *sync.WaitGroup, target target.Target) {
+func (s *Scraper) forwardProfile(ctx context.Context, group *sync.WaitGroup, target target.Target, totals *scrapeTotals) {
 	defer group.Done()
 
+	ctx, span := tracing.Start(ctx, "scrape",
+		trace.WithAttributes(
+			attribute.String("target.address", target.Address),
+			attribute.String("target.app", s.app),
+		),
+	)
+
+	start := time.Now()
+	result, err := s.profileAndUpload(ctx, target)
+	s.scraped(target, start, result, err)
+
+	span.SetAttributes(
+		attribute.Int64("profile.samples", result.samples),
+		attribute.Bool("profile.skipped", result.skipped),
+	)
+	tracing.End(span, err)
+
+	switch {
+	case err != nil:
+		totals.failures.Add(1)
+	case !result.skipped:
+		totals.samples.Add(result.samples)
+	}
+}
+
+type (
+	// The scrapeTotals type accumulates the outcomes of the profiles forwarded during a single scrape.
+	scrapeTotals struct {
+		samples  atomic.Int64
+		failures atomic.Int64
+	}
+
+	scrapeResult struct {
+		size    int64
+		samples int64
+		skipped bool
+	}
+)
+
+func (s *Scraper) profileAndUpload(ctx context.Context, target target.Target) (scrapeResult, error) {
 	log := logger.FromContext(ctx).With(
 		slog.String("target.address", target.Address),
 		slog.String("target.app", s.app),
 	)
 
+	scrapeAttempts.WithLabelValues(s.app).Inc()
+
 	u, err := url.Parse(target.Address)
 	if err != nil {
 		log.With(slog.String("error", err.Error())).
 			ErrorContext(ctx, "failed to parse target address")
-		return
+		scrapeFailures.WithLabelValues(s.app, failureReasonAddress).Inc()
+		return scrapeResult{}, err
 	}
 
 	u.Path = "/debug/pprof/profile"
@@ -129,11 +312,55 @@ func (s *Scraper) forwardProfile(ctx context.Context, group *sync.WaitGroup, tar
 	}
 
 	log.DebugContext(ctx, "profiling target")
-	if err = s.client.ProfileAndUpload(ctx, s.app, u.String(), s.profileDuration); err != nil {
+	start := time.Now()
+	data, err := s.client.Profile(ctx, u.String(), s.profileDuration)
+	if err != nil {
 		log.With(slog.String("error", err.Error())).
 			ErrorContext(ctx, "failed to profile target")
-		return
+		scrapeFailures.WithLabelValues(s.app, failureReasonFetch).Inc()
+		return scrapeResult{}, err
 	}
 
+	fetchDuration.WithLabelValues(s.app).Observe(time.Since(start).Seconds())
+
+	p, err := profile.ParseData(data)
+	if err != nil {
+		log.With(slog.String("error", err.Error())).
+			ErrorContext(ctx, "failed to parse profile")
+		scrapeFailures.WithLabelValues(s.app, failureReasonParse).Inc()
+		return scrapeResult{}, err
+	}
+
+	samples, cpu, err := CPUUsage(p)
+	if err != nil {
+		log.With(slog.String("error", err.Error())).
+			ErrorContext(ctx, "invalid profile")
+		scrapeFailures.WithLabelValues(s.app, failureReasonInvalid).Inc()
+		return scrapeResult{}, err
+	}
+
+	if samples < s.minSamples || cpu < s.minCPUTime {
+		log.With(
+			slog.Int64("profile.samples", samples),
+			slog.Duration("profile.cpu", cpu),
+		).InfoContext(ctx, "skipping profile with insufficient samples")
+		profilesSkipped.WithLabelValues(s.app).Inc()
+		return scrapeResult{samples: samples, skipped: true}, nil
+	}
+
+	start = time.Now()
+	if err = s.client.UploadChannel(ctx, s.app, s.channel, bytes.NewReader(data)); err != nil {
+		log.With(slog.String("error", err.Error())).
+			ErrorContext(ctx, "failed to upload profile")
+		scrapeFailures.WithLabelValues(s.app, failureReasonUpload).Inc()
+		return scrapeResult{}, err
+	}
+
+	uploadDuration.WithLabelValues(s.app).Observe(time.Since(start).Seconds())
+	profileBytes.WithLabelValues(s.app).Observe(float64(len(data)))
+	scrapeSuccesses.WithLabelValues(s.app).Inc()
+	lastUpload.WithLabelValues(s.app).SetToCurrentTime()
+
 	log.DebugContext(ctx, "uploaded profile")
+	return scrapeResult{size: int64(len(data)), samples: samples}, nil
 }
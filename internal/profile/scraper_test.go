@@ -3,11 +3,13 @@ package profile_test
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/profile/mocks"
@@ -22,6 +24,7 @@ func TestScraper_Scrape(t *testing.T) {
 		Config   profile.ScrapeConfig
 		Setup    func(client *mocks.MockClient, source *mocks.MockTargetSource)
 		Duration time.Duration
+		Expected []profile.TargetStatus
 	}{
 		{
 			Name:     "successful scrape",
@@ -51,17 +54,221 @@ func TestScraper_Scrape(t *testing.T) {
 					}, nil)
 
 				client.EXPECT().
-					ProfileAndUpload(mock.Anything, "test", "http://localhost:8080/debug/pprof/profile", time.Second*30).
-					Return(nil)
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*30).
+					Return(validProfile, nil)
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8081/debug/pprof/profile", time.Second*30).
+					Return(validProfile, nil)
 
 				client.EXPECT().
-					ProfileAndUpload(mock.Anything, "test", "http://localhost:8081/debug/pprof/profile", time.Second*30).
+					Profile(mock.Anything, "http://localhost:8082/debug/pprof/profile", time.Second*30).
+					Return(validProfile, nil)
+
+				client.EXPECT().
+					UploadChannel(mock.Anything, "test", "", mock.Anything).
 					Return(nil)
+			},
+			Expected: []profile.TargetStatus{
+				{
+					Address:       "http://localhost:8080",
+					Path:          "/debug/pprof/profile",
+					BytesUploaded: int64(len(validProfile)),
+				},
+				{
+					Address:       "http://localhost:8081",
+					Path:          "/debug/pprof/profile",
+					BytesUploaded: int64(len(validProfile)),
+				},
+				{
+					Address:       "http://localhost:8082",
+					Path:          "/debug/pprof/profile",
+					BytesUploaded: int64(len(validProfile)),
+				},
+			},
+		},
+		{
+			Name:     "records scrape errors",
+			Duration: 3 * time.Second,
+			Config: profile.ScrapeConfig{
+				SampleSize:      1,
+				ProfileDuration: time.Second * 30,
+				App:             "test",
+				ScrapeFrequency: time.Second,
+			},
+			Setup: func(client *mocks.MockClient, source *mocks.MockTargetSource) {
+				source.EXPECT().
+					List(mock.Anything).
+					Return([]target.Target{
+						{
+							Address: "http://localhost:8080",
+							Path:    "/debug/pprof/profile",
+							Labels:  map[string]string{"pod": "test"},
+						},
+					}, nil)
 
 				client.EXPECT().
-					ProfileAndUpload(mock.Anything, "test", "http://localhost:8082/debug/pprof/profile", time.Second*30).
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*30).
+					Return(nil, io.EOF)
+			},
+			Expected: []profile.TargetStatus{
+				{
+					Address:   "http://localhost:8080",
+					Path:      "/debug/pprof/profile",
+					Labels:    map[string]string{"pod": "test"},
+					LastError: io.EOF.Error(),
+				},
+			},
+		},
+		{
+			Name:     "skips profiles with insufficient samples",
+			Duration: 1500 * time.Millisecond,
+			Config: profile.ScrapeConfig{
+				SampleSize:      1,
+				ProfileDuration: time.Second * 30,
+				App:             "test",
+				ScrapeFrequency: time.Second,
+				MinSamples:      1000000,
+			},
+			Setup: func(client *mocks.MockClient, source *mocks.MockTargetSource) {
+				source.EXPECT().
+					List(mock.Anything).
+					Return([]target.Target{
+						{
+							Address: "http://localhost:8080",
+							Path:    "/debug/pprof/profile",
+						},
+					}, nil)
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*30).
+					Return(validProfile, nil)
+			},
+			Expected: []profile.TargetStatus{
+				{
+					Address: "http://localhost:8080",
+					Path:    "/debug/pprof/profile",
+					Skipped: 1,
+				},
+			},
+		},
+		{
+			Name:     "adapts profile duration to sample volume",
+			Duration: 2500 * time.Millisecond,
+			Config: profile.ScrapeConfig{
+				SampleSize:      1,
+				ProfileDuration: time.Second * 10,
+				App:             "test",
+				ScrapeFrequency: time.Second,
+				Adaptive: &profile.AdaptiveConfig{
+					// The test profile contains 224780 samples and each scrape takes at least the profile duration of
+					// 10 seconds, so aim for half the samples scraped in that time.
+					TargetSamplesPerHour: 224780 * 360 / 2,
+					MinProfileDuration:   time.Second * 5,
+					MaxProfileDuration:   time.Minute,
+					MinSampleSize:        1,
+					MaxSampleSize:        1,
+				},
+			},
+			Setup: func(client *mocks.MockClient, source *mocks.MockTargetSource) {
+				source.EXPECT().
+					List(mock.Anything).
+					Return([]target.Target{
+						{
+							Address: "http://localhost:8080",
+							Path:    "/debug/pprof/profile",
+						},
+					}, nil)
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*10).
+					Return(validProfile, nil).
+					Once()
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*5).
+					Return(validProfile, nil)
+
+				client.EXPECT().
+					UploadChannel(mock.Anything, "test", "", mock.Anything).
 					Return(nil)
 			},
+			Expected: []profile.TargetStatus{
+				{
+					Address:       "http://localhost:8080",
+					Path:          "/debug/pprof/profile",
+					BytesUploaded: int64(len(validProfile)),
+				},
+			},
+		},
+		{
+			Name:     "does not adapt after failed scrapes",
+			Duration: 2500 * time.Millisecond,
+			Config: profile.ScrapeConfig{
+				SampleSize:      1,
+				ProfileDuration: time.Second * 10,
+				App:             "test",
+				ScrapeFrequency: time.Second,
+				Adaptive: &profile.AdaptiveConfig{
+					TargetSamplesPerHour: 224780 * 360,
+					MinProfileDuration:   time.Second * 5,
+					MaxProfileDuration:   time.Minute,
+					MinSampleSize:        1,
+					MaxSampleSize:        1,
+				},
+			},
+			Setup: func(client *mocks.MockClient, source *mocks.MockTargetSource) {
+				source.EXPECT().
+					List(mock.Anything).
+					Return([]target.Target{
+						{
+							Address: "http://localhost:8080",
+							Path:    "/debug/pprof/profile",
+						},
+					}, nil)
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*10).
+					Return(nil, io.ErrUnexpectedEOF)
+			},
+			Expected: []profile.TargetStatus{
+				{
+					Address:   "http://localhost:8080",
+					Path:      "/debug/pprof/profile",
+					LastError: io.ErrUnexpectedEOF.Error(),
+				},
+			},
+		},
+		{
+			Name:     "rejects invalid profiles",
+			Duration: 3 * time.Second,
+			Config: profile.ScrapeConfig{
+				SampleSize:      1,
+				ProfileDuration: time.Second * 30,
+				App:             "test",
+				ScrapeFrequency: time.Second,
+			},
+			Setup: func(client *mocks.MockClient, source *mocks.MockTargetSource) {
+				source.EXPECT().
+					List(mock.Anything).
+					Return([]target.Target{
+						{
+							Address: "http://localhost:8080",
+							Path:    "/debug/pprof/profile",
+						},
+					}, nil)
+
+				client.EXPECT().
+					Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", time.Second*30).
+					Return([]byte("invalid"), nil)
+			},
+			Expected: []profile.TargetStatus{
+				{
+					Address:   "http://localhost:8080",
+					Path:      "/debug/pprof/profile",
+					LastError: "parsing profile: unrecognized profile format",
+				},
+			},
 		},
 	}
 
@@ -70,6 +277,7 @@ func TestScraper_Scrape(t *testing.T) {
 			client := mocks.NewMockClient(t)
 			source := mocks.NewMockTargetSource(t)
 
+			source.EXPECT().Name().Return("test").Maybe()
 			if tc.Setup != nil {
 				tc.Setup(client, source)
 			}
@@ -77,13 +285,20 @@ func TestScraper_Scrape(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), tc.Duration)
 			defer cancel()
 
-			err := profile.NewScraper(client, tc.Config).Scrape(ctx, source)
-			switch {
-			case errors.Is(err, context.DeadlineExceeded):
-				return
-			case err != nil:
-				assert.Fail(t, err.Error())
+			scraper := profile.NewScraper(client, tc.Config)
+			err := scraper.Scrape(ctx, source)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				require.NoError(t, err)
 			}
+
+			actual := scraper.Targets()
+			for i := range actual {
+				assert.False(t, actual[i].LastScrape.IsZero())
+				actual[i].LastScrape = time.Time{}
+				actual[i].LastScrapeDuration = 0
+			}
+
+			assert.EqualValues(t, tc.Expected, actual)
 		})
 	}
 }
@@ -2,18 +2,31 @@
 package profile
 
 import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"path"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/pprof/profile"
 
 	"github.com/davidsbond/autopgo/internal/api"
+	"github.com/davidsbond/autopgo/internal/auth"
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/closers"
+	"github.com/davidsbond/autopgo/internal/logger"
 )
 
 type (
@@ -21,24 +34,79 @@ type (
 	HTTPController struct {
 		blobs  BlobRepository
 		events EventWriter
+		upload UploadConfig
+	}
+
+	// The UploadConfig type describes the limits applied to profiles uploaded to the server, in addition to the
+	// requirement that they are CPU profiles containing at least one sample.
+	UploadConfig struct {
+		// The maximum size of an uploaded profile in bytes, no limit is applied when zero.
+		MaxBytes int64
+		// The minimum duration an uploaded profile must cover, no limit is applied when zero.
+		MinDuration time.Duration
+		// The maximum duration an uploaded profile may cover, no limit is applied when zero.
+		MaxDuration time.Duration
+		// How long uploads are remembered for, during which uploads with the same content or Idempotency-Key header
+		// are treated as duplicates and not merged again. Duplicates are not detected when zero.
+		DedupWindow time.Duration
 	}
 )
 
 // NewHTTPController returns a new instance of the HTTPController type that will read and write profiles via the
-// given BlobRepository implementation and publish events via the EventWriter implementation.
-func NewHTTPController(blobs BlobRepository, events EventWriter) *HTTPController {
+// given BlobRepository implementation and publish events via the EventWriter implementation. Uploaded profiles are
+// validated against the UploadConfig.
+func NewHTTPController(blobs BlobRepository, events EventWriter, upload UploadConfig) *HTTPController {
 	return &HTTPController{
 		blobs:  blobs,
 		events: events,
+		upload: upload,
 	}
 }
 
 // Register HTTP endpoints onto the http.ServeMux.
 func (h *HTTPController) Register(m *http.ServeMux) {
-	m.HandleFunc("POST /api/profile/{app}", h.Upload)
-	m.HandleFunc("GET /api/profile/{app}", h.Download)
-	m.HandleFunc("GET /api/profile", h.List)
-	m.HandleFunc("DELETE /api/profile/{app}", h.Delete)
+	// Every endpoint requires a scope, which is checked when authentication is enabled.
+	handle := func(pattern string, scope auth.Scope, handler http.HandlerFunc) {
+		m.Handle(pattern, auth.Require(scope, handler))
+	}
+
+	handle("GET /api/profile", auth.ScopeList, h.List)
+
+	// Each profile endpoint is available with and without a channel, omitting the channel uses the default channel.
+	// GET patterns also match HEAD requests.
+	handle("POST /api/profile/{app}", auth.ScopeUpload, h.Upload)
+	handle("POST /api/profile/{app}/{channel}", auth.ScopeUpload, h.Upload)
+	handle("GET /api/profile/{app}", auth.ScopeDownload, h.Download)
+	handle("GET /api/profile/{app}/{channel}", auth.ScopeDownload, h.Download)
+	handle("DELETE /api/profile/{app}", auth.ScopeDelete, h.Delete)
+	handle("DELETE /api/profile/{app}/{channel}", auth.ScopeDelete, h.Delete)
+	handle("GET /api/profile/{app}/versions", auth.ScopeDownload, h.Versions)
+	handle("GET /api/profile/{app}/{channel}/versions", auth.ScopeDownload, h.Versions)
+	handle("GET /api/profile/{app}/summary", auth.ScopeDownload, h.Summary)
+	handle("GET /api/profile/{app}/{channel}/summary", auth.ScopeDownload, h.Summary)
+	handle("GET /api/profile/{app}/edges", auth.ScopeDownload, h.Edges)
+	handle("GET /api/profile/{app}/{channel}/edges", auth.ScopeDownload, h.Edges)
+	handle("GET /api/profile/{app}/flamegraph", auth.ScopeDownload, h.FlameGraph)
+	handle("GET /api/profile/{app}/{channel}/flamegraph", auth.ScopeDownload, h.FlameGraph)
+	handle("GET /api/profile/{app}/diff", auth.ScopeDownload, h.Diff)
+	handle("GET /api/profile/{app}/{channel}/diff", auth.ScopeDownload, h.Diff)
+	handle("POST /api/profile/{app}/rollback", auth.ScopeUpload, h.Rollback)
+	handle("POST /api/profile/{app}/{channel}/rollback", auth.ScopeUpload, h.Rollback)
+
+	// Promotions always target the stable profile served from the default channel.
+	handle("POST /api/profile/{app}/promote", auth.ScopePromote, h.Promote)
+	handle("GET /api/profile/{app}/promotions", auth.ScopeDownload, h.Promotions)
+
+	handle("POST /api/profile/{app}/pin", auth.ScopePromote, h.Pin)
+	handle("POST /api/profile/{app}/{channel}/pin", auth.ScopePromote, h.Pin)
+	handle("POST /api/profile/{app}/unpin", auth.ScopePromote, h.Unpin)
+	handle("POST /api/profile/{app}/{channel}/unpin", auth.ScopePromote, h.Unpin)
+	handle("GET /api/profile/{app}/staging", auth.ScopeDownload, h.Staged)
+	handle("GET /api/profile/{app}/{channel}/staging", auth.ScopeDownload, h.Staged)
+	handle("POST /api/profile/{app}/staging/{id}/requeue", auth.ScopeUpload, h.Requeue)
+	handle("POST /api/profile/{app}/{channel}/staging/{id}/requeue", auth.ScopeUpload, h.Requeue)
+	handle("DELETE /api/profile/{app}/staging/{id}", auth.ScopeDelete, h.Discard)
+	handle("DELETE /api/profile/{app}/{channel}/staging/{id}", auth.ScopeDelete, h.Discard)
 }
 
 type (
@@ -46,11 +114,24 @@ type (
 	UploadResponse struct {
 		// The location in blob storage the profile is stored at.
 		Key string `json:"key"`
+		// Whether the profile had already been uploaded, in which case Key is the location of the original upload.
+		Duplicate bool `json:"duplicate,omitempty"`
+	}
+
+	// The uploadRecord type is stored in blob storage for each upload so that duplicate uploads can be detected.
+	uploadRecord struct {
+		Key        string    `json:"key"`
+		UploadedAt time.Time `json:"uploadedAt"`
 	}
 )
 
-// Upload handles an inbound HTTP request containing a pprof profile for a given application. The profile is parsed
-// uploaded to blob storage and an event is published.
+// IdempotencyKeyHeader is the request header clients can use to identify an upload, so that retries of the same
+// upload are treated as duplicates even if the profile content differs.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Upload handles an inbound HTTP request containing a pprof profile for a given application. The profile is parsed,
+// validated against the UploadConfig, uploaded to blob storage and an event is published. Profiles that are not CPU
+// profiles, contain no samples or fall outside the configured size & duration limits are rejected.
 func (h *HTTPController) Upload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -60,36 +141,80 @@ func (h *HTTPController) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := profile.Parse(r.Body)
-	if err != nil {
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	reader := r.Body
+	if h.upload.MaxBytes > 0 {
+		reader = http.MaxBytesReader(w, r.Body, h.upload.MaxBytes)
+	}
+
+	hash := sha256.New()
+	body := &countingReader{Reader: io.TeeReader(reader, hash)}
+	p, err := profile.Parse(body)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		uploadRejections.WithLabelValues(rejectionSize).Inc()
+		api.ErrorResponse(ctx, w, fmt.Sprintf("profile exceeds the maximum size of %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		uploadParseFailures.Inc()
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	now := time.Now().UnixNano()
-	key := path.Join(app, "staging", strconv.FormatInt(now, 10))
-	writer, err := h.blobs.NewWriter(ctx, key)
-	if err != nil {
-		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+	uploadBytes.Observe(float64(body.n))
+
+	if reason, err := h.upload.validate(p); err != nil {
+		uploadRejections.WithLabelValues(reason).Inc()
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err = p.Write(writer); err != nil {
+	// Uploads are identified by their content and, when provided, their idempotency key, which is checked first.
+	var ids []string
+	if h.upload.DedupWindow > 0 {
+		if v := r.Header.Get(IdempotencyKeyHeader); v != "" {
+			ids = append(ids, "idempotency-"+hashString(v))
+		}
+
+		ids = append(ids, "sha256-"+hex.EncodeToString(hash.Sum(nil)))
+	}
+
+	now := time.Now()
+	key := StagingKey(app, channel, now)
+
+	// The upload is recorded under each of its ids before it is published, so that only one of many concurrent
+	// identical uploads is merged.
+	claimed, record, err := h.claimUpload(ctx, app, channel, ids, uploadRecord{Key: key, UploadedAt: now})
+	switch {
+	case errors.Is(err, blob.ErrExist):
+		uploadDuplicates.Inc()
+		api.Respond(ctx, w, http.StatusOK, UploadResponse{Key: record.Key, Duplicate: true})
+		return
+	case err != nil:
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err = writer.Close(); err != nil {
+	if err = h.stageUpload(ctx, key, p); err != nil {
+		h.releaseUpload(ctx, claimed)
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	payload := UploadedEvent{
 		App:        app,
+		Channel:    channel,
 		ProfileKey: key,
 	}
 
 	if err = h.events.Write(ctx, payload); err != nil {
+		h.releaseUpload(ctx, append(claimed, key))
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -97,8 +222,159 @@ func (h *HTTPController) Upload(w http.ResponseWriter, r *http.Request) {
 	api.Respond(ctx, w, http.StatusCreated, UploadResponse{Key: key})
 }
 
+func (h *HTTPController) stageUpload(ctx context.Context, key string, p *profile.Profile) error {
+	writer, err := h.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err = p.Write(writer); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// claimUpload creates the record of an upload under each of its ids. If another upload has recorded one of the ids
+// within the deduplication window, the records created so far are removed and the existing record is returned
+// alongside blob.ErrExist. Returns the keys of the created records.
+func (h *HTTPController) claimUpload(ctx context.Context, app, channel string, ids []string, record uploadRecord) ([]string, uploadRecord, error) {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return nil, uploadRecord{}, err
+	}
+
+	claimed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		key := UploadRecordKey(app, channel, id)
+
+		err = h.blobs.Create(ctx, key, content)
+		if errors.Is(err, blob.ErrExist) {
+			var existing uploadRecord
+			existing, err = h.findUpload(ctx, key)
+			switch {
+			case err == nil:
+				h.releaseUpload(ctx, claimed)
+				return nil, existing, blob.ErrExist
+			case errors.Is(err, blob.ErrNotExist):
+				// Records outside the deduplication window are replaced until the worker removes them.
+				err = h.writeUpload(ctx, key, content)
+			}
+		}
+
+		if err != nil {
+			h.releaseUpload(ctx, claimed)
+			return nil, uploadRecord{}, err
+		}
+
+		claimed = append(claimed, key)
+	}
+
+	return claimed, record, nil
+}
+
+// findUpload returns the record of a previous upload stored at the given key. Returns blob.ErrNotExist if there is
+// no record or it is older than the deduplication window.
+func (h *HTTPController) findUpload(ctx context.Context, key string) (uploadRecord, error) {
+	reader, err := h.blobs.NewReader(ctx, key)
+	if err != nil {
+		return uploadRecord{}, err
+	}
+	defer closers.Close(ctx, reader)
+
+	var record uploadRecord
+	if err = json.NewDecoder(reader).Decode(&record); err != nil {
+		return uploadRecord{}, fmt.Errorf("failed to decode upload record at %s: %w", key, err)
+	}
+
+	if time.Since(record.UploadedAt) > h.upload.DedupWindow {
+		return uploadRecord{}, blob.ErrNotExist
+	}
+
+	return record, nil
+}
+
+func (h *HTTPController) writeUpload(ctx context.Context, key string, content []byte) error {
+	writer, err := h.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(content); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// releaseUpload deletes the objects written for an upload that has failed, so that retrying it is not treated as a
+// duplicate. Failures are only logged, as the upload has already failed.
+func (h *HTTPController) releaseUpload(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		err := h.blobs.Delete(ctx, key)
+		if err != nil && !errors.Is(err, blob.ErrNotExist) {
+			logger.FromContext(ctx).
+				With(slog.String("error", err.Error()), slog.String("key", key)).
+				ErrorContext(ctx, "failed to remove object for failed upload")
+		}
+	}
+}
+
+func hashString(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+// Constants for the reasons an uploaded profile is rejected, used as the "reason" label on uploadRejections.
+const (
+	rejectionSize      = "size"
+	rejectionNotCPU    = "not_cpu"
+	rejectionNoSamples = "no_samples"
+	rejectionDuration  = "duration"
+)
+
+// validate checks that the profile is a CPU profile that can be merged, containing at least one sample and covering
+// a duration within the configured limits. Returns the reason for rejection alongside an error describing it.
+func (c UploadConfig) validate(p *profile.Profile) (string, error) {
+	if p.PeriodType == nil || p.PeriodType.Type != "cpu" || p.PeriodType.Unit != "nanoseconds" {
+		return rejectionNotCPU, errors.New("profile period type must be cpu/nanoseconds, only cpu profiles are accepted")
+	}
+
+	if !isCPUSampleTypes(p.SampleType) {
+		return rejectionNotCPU, errors.New("profile sample types must be samples/count & cpu/nanoseconds, only cpu profiles are accepted")
+	}
+
+	samples, _, err := CPUUsage(p)
+	switch {
+	case err != nil:
+		return rejectionNotCPU, err
+	case samples == 0:
+		return rejectionNoSamples, errors.New("profile contains no samples")
+	}
+
+	duration := time.Duration(p.DurationNanos)
+	switch {
+	case c.MinDuration > 0 && duration < c.MinDuration:
+		return rejectionDuration, fmt.Errorf("profile duration of %s is shorter than the minimum of %s", duration, c.MinDuration)
+	case c.MaxDuration > 0 && duration > c.MaxDuration:
+		return rejectionDuration, fmt.Errorf("profile duration of %s is longer than the maximum of %s", duration, c.MaxDuration)
+	}
+
+	return "", nil
+}
+
+// isCPUSampleTypes returns true if the sample types are exactly those of a CPU profile produced by the Go runtime,
+// which all merged profiles share.
+func isCPUSampleTypes(types []*profile.ValueType) bool {
+	return len(types) == 2 &&
+		types[0].Type == "samples" && types[0].Unit == "count" &&
+		types[1].Type == "cpu" && types[1].Unit == "nanoseconds"
+}
+
 // Download handles an inbound HTTP request to download a pprof profile for the application specified within the
-// URL path.
+// URL path. A previous generation of the profile can be downloaded by providing the version query parameter, otherwise
+// the pinned profile is served in place of the merged profile while the channel is pinned. Responses include ETag and
+// Last-Modified headers and conditional requests are answered with a 304 when the profile is unchanged.
 func (h *HTTPController) Download(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -108,7 +384,79 @@ func (h *HTTPController) Download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	key := path.Join(app, "default.pgo")
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var version int
+	if v := query.Get("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			api.ErrorResponse(ctx, w, "invalid version", http.StatusBadRequest)
+			return
+		}
+
+		version = parsed
+	}
+
+	channels := []string{channel}
+	if v := query.Get("fallback"); v != "" {
+		if version > 0 {
+			api.ErrorResponse(ctx, w, "version and fallback cannot be combined", http.StatusBadRequest)
+			return
+		}
+
+		for _, fallback := range strings.Split(v, ",") {
+			if !IsValidChannelName(fallback) {
+				api.ErrorResponse(ctx, w, "invalid fallback channel name", http.StatusBadRequest)
+				return
+			}
+
+			channels = append(channels, fallback)
+		}
+	}
+
+	// The first channel in the fallback chain that has a profile is served, preferring a channel's pinned profile over
+	// its merged profile. The default channel serves the promoted stable profile, if there is one, before its merged
+	// profile.
+	var (
+		key    string
+		object blob.Object
+		found  bool
+		pinned bool
+	)
+
+channels:
+	for _, channel = range channels {
+		keys := servedKeys(app, channel)
+		if version > 0 {
+			keys = []string{VersionKey(app, channel, version)}
+		}
+
+		for _, key = range keys {
+			var err error
+			object, err = h.blobs.Stat(ctx, key)
+			switch {
+			case errors.Is(err, blob.ErrNotExist):
+				continue
+			case err != nil:
+				api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			found = true
+			pinned = key == PinnedKey(app, channel)
+			break channels
+		}
+	}
+
+	if !found {
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 
 	reader, err := h.blobs.NewReader(ctx, key)
 	switch {
@@ -121,48 +469,309 @@ func (h *HTTPController) Download(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer closers.Close(ctx, reader)
-	if _, err = io.Copy(w, reader); err != nil {
+
+	// The profile is read in full so that its hash can be used as the ETag before any of the body is written.
+	content, err := io.ReadAll(reader)
+	if err != nil {
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", ETag(content))
+	w.Header().Set(ChannelHeader, channelName(channel))
+	if pinned {
+		w.Header().Set(PinnedHeader, "true")
+	}
+
+	// ServeContent handles the If-None-Match, If-Modified-Since & HEAD semantics for us.
+	http.ServeContent(w, r, "", object.LastModified, bytes.NewReader(content))
 }
 
 type (
 	// The ListResponse type is the response given when listing profiles.
 	ListResponse struct {
 		Profiles []Profile `json:"profiles"`
+		// The token used to request the next page of profiles, empty when there are no more profiles.
+		NextPageToken string `json:"nextPageToken,omitempty"`
 	}
 
 	// The Profile type describes a single profile stored by the server.
 	Profile struct {
 		// The location in blob storage of the profile.
 		Key string `json:"key"`
+		// The channel of the profile, empty for the default channel.
+		Channel string `json:"channel,omitempty"`
 		// The profile size in bytes.
 		Size int64 `json:"size"`
 		// When the profile was last modified.
 		LastModified time.Time `json:"lastModified"`
 	}
+
+	// The listCursor type is encoded within page tokens to describe the last profile of a page, so that the next page
+	// starts after it even if profiles have since been added or removed.
+	listCursor struct {
+		Sort         string    `json:"sort"`
+		Key          string    `json:"key"`
+		Channel      string    `json:"channel,omitempty"`
+		Size         int64     `json:"size,omitempty"`
+		LastModified time.Time `json:"lastModified"`
+	}
+)
+
+// Values of the sort query parameter accepted when listing profiles. Prefixing a value with a hyphen sorts in
+// descending order.
+const (
+	ListSortName         = "name"
+	ListSortSize         = "size"
+	ListSortLastModified = "lastModified"
 )
 
-// List handles an inbound HTTP request to list all profiles stored by the server.
+// MaxListLimit is the maximum number of profiles returned in a single page when listing profiles, and the number
+// returned when no limit is given.
+const MaxListLimit = 1000
+
+// List handles an inbound HTTP request to list the profiles stored by the server. Profiles can be filtered by the prefix
+// of their application name using the prefix query parameter, and to those modified at or after an RFC 3339 timestamp
+// using the modified_since query parameter. The sort query parameter orders profiles by ListSortName (the default),
+// ListSortSize or ListSortLastModified, descending when prefixed with a hyphen. The limit query parameter restricts the
+// number of profiles returned, up to & defaulting to MaxListLimit. When more profiles remain, the response contains a
+// token that is provided as the page_token query parameter to request the next page.
+//
+// Profiles are listed from the blob store in ascending order of name, so a page sorted that way only lists the
+// applications after its page token until the page is full. Every other sort must read the merged profile of every
+// channel of every application to fill each page.
 func (h *HTTPController) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	query := r.URL.Query()
+
+	prefix := query.Get("prefix")
+
+	var since time.Time
+	if v := query.Get("modified_since"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			api.ErrorResponse(ctx, w, "invalid modified since", http.StatusBadRequest)
+			return
+		}
+
+		since = parsed
+	}
+
+	sort := cmp.Or(query.Get("sort"), ListSortName)
+	if !slices.Contains([]string{ListSortName, ListSortSize, ListSortLastModified}, strings.TrimPrefix(sort, "-")) {
+		api.ErrorResponse(ctx, w, "invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	limit := MaxListLimit
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > MaxListLimit {
+			api.ErrorResponse(ctx, w, fmt.Sprintf("limit must be between 1 and %d", MaxListLimit), http.StatusBadRequest)
+			return
+		}
+
+		limit = parsed
+	}
+
+	var cursor *listCursor
+	if v := query.Get("page_token"); v != "" {
+		decoded, err := decodeListCursor(v)
+		if err != nil || decoded.Sort != sort {
+			api.ErrorResponse(ctx, w, "invalid page token", http.StatusBadRequest)
+			return
+		}
+
+		cursor = &decoded
+	}
+
+	// Listing does not target a single application, so tokens permitted to list only some applications are shown only
+	// those.
+	principal, authenticated := auth.FromContext(ctx)
+
+	// Applications before the cursor cannot appear on a page sorted by ascending name, so they are not listed.
+	var after string
+	if cursor != nil && sort == ListSortName {
+		after = cursor.Key
+	}
 
-	profiles := make([]Profile, 0)
-	for item, err := range h.blobs.List(ctx, IsMergedProfile()) {
+	// Only the first limit profiles after the cursor, plus one to tell whether another page follows, are kept while
+	// listing.
+	page := &profileHeap{sort: sort}
+	for ch, err := range listChannels(ctx, h.blobs, prefix, after) {
 		if err != nil {
 			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		profiles = append(profiles, Profile{
-			Key:          path.Dir(item.Key),
-			Size:         item.Size,
-			LastModified: item.LastModified,
-		})
+		if authenticated && !principal.Allows(auth.ScopeList, ch.app) {
+			continue
+		}
+
+		// Profiles sorted by name can be compared against the cursor & the page before the blob store is asked for
+		// their size.
+		p := Profile{Key: ch.app, Channel: ch.channel}
+		if strings.TrimPrefix(sort, "-") == ListSortName {
+			if cursor != nil && compareProfiles(sort, p, cursor.profile()) <= 0 {
+				continue
+			}
+
+			if page.Len() > limit && compareProfiles(sort, p, page.profiles[0]) >= 0 {
+				continue
+			}
+		}
+
+		item, err := h.mergedProfile(ctx, ch)
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			continue
+		case err != nil:
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if item.LastModified.Before(since) {
+			continue
+		}
+
+		p.Size = item.Size
+		p.LastModified = item.LastModified
+		if cursor != nil && compareProfiles(sort, p, cursor.profile()) <= 0 {
+			continue
+		}
+
+		page.add(p, limit+1)
+
+		// Every profile listed after a full page sorted by ascending name comes after those already on it.
+		if sort == ListSortName && page.Len() > limit {
+			break
+		}
+	}
+
+	profiles := page.profiles
+	slices.SortFunc(profiles, func(a, b Profile) int {
+		return compareProfiles(sort, a, b)
+	})
+
+	var response ListResponse
+	if len(profiles) > limit {
+		profiles = profiles[:limit]
+		response.NextPageToken = encodeListCursor(sort, profiles[limit-1])
+	}
+
+	response.Profiles = profiles
+	api.Respond(ctx, w, http.StatusOK, response)
+}
+
+// mergedProfile returns the merged profile of the channel. The merged profile of a default channel is found while
+// listing its application, so the blob store is only asked for those of other channels.
+func (h *HTTPController) mergedProfile(ctx context.Context, ch appChannel) (blob.Object, error) {
+	switch {
+	case ch.channel != "":
+		return h.blobs.Stat(ctx, MergedKey(ch.app, ch.channel))
+	case ch.merged == nil:
+		return blob.Object{}, blob.ErrNotExist
+	default:
+		return *ch.merged, nil
+	}
+}
+
+// compareProfiles orders profiles according to the sort query parameter given when listing profiles. Profiles that
+// are equal by the sorted field are ordered by application name & channel, so that every profile has a unique position
+// within the list. Names are ordered as they are listed from the blob store, see keyOrder.
+func compareProfiles(sort string, a, b Profile) int {
+	field, descending := strings.CutPrefix(sort, "-")
+	if descending {
+		a, b = b, a
+	}
+
+	var result int
+	switch field {
+	case ListSortSize:
+		result = cmp.Compare(a.Size, b.Size)
+	case ListSortLastModified:
+		result = a.LastModified.Compare(b.LastModified)
+	}
+
+	return cmp.Or(result, cmp.Compare(keyOrder(a.Key), keyOrder(b.Key)), cmp.Compare(keyOrder(a.Channel), keyOrder(b.Channel)))
+}
+
+// The profileHeap type is a heap.Interface implementation whose root is the last of its profiles according to the
+// sort query parameter given when listing profiles.
+type profileHeap struct {
+	sort     string
+	profiles []Profile
+}
+
+// add the profile to the heap, replacing the last profile if the heap already holds n profiles and the profile comes
+// before it.
+func (h *profileHeap) add(p Profile, n int) {
+	switch {
+	case h.Len() < n:
+		heap.Push(h, p)
+	case compareProfiles(h.sort, p, h.profiles[0]) < 0:
+		h.profiles[0] = p
+		heap.Fix(h, 0)
+	}
+}
+
+func (h *profileHeap) Len() int {
+	return len(h.profiles)
+}
+
+func (h *profileHeap) Less(i, j int) bool {
+	return compareProfiles(h.sort, h.profiles[i], h.profiles[j]) > 0
+}
+
+func (h *profileHeap) Swap(i, j int) {
+	h.profiles[i], h.profiles[j] = h.profiles[j], h.profiles[i]
+}
+
+func (h *profileHeap) Push(x any) {
+	h.profiles = append(h.profiles, x.(Profile))
+}
+
+func (h *profileHeap) Pop() any {
+	last := h.profiles[len(h.profiles)-1]
+	h.profiles = h.profiles[:len(h.profiles)-1]
+	return last
+}
+
+func (c listCursor) profile() Profile {
+	return Profile{
+		Key:          c.Key,
+		Channel:      c.Channel,
+		Size:         c.Size,
+		LastModified: c.LastModified,
+	}
+}
+
+func encodeListCursor(sort string, p Profile) string {
+	// Marshalling this type cannot fail.
+	data, _ := json.Marshal(listCursor{
+		Sort:         sort,
+		Key:          p.Key,
+		Channel:      p.Channel,
+		Size:         p.Size,
+		LastModified: p.LastModified,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(token string) (listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return listCursor{}, err
+	}
+
+	var cursor listCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return listCursor{}, err
 	}
 
-	api.Respond(ctx, w, http.StatusOK, ListResponse{Profiles: profiles})
+	return cursor, nil
 }
 
 type (
@@ -171,7 +780,8 @@ type (
 )
 
 // Delete handles an inbound HTTP request to delete the profile for an application. It will also delete any profiles
-// awaiting merge for the application.
+// awaiting merge for the application. When a channel is specified only that channel is deleted, otherwise all of the
+// application's channels are deleted.
 func (h *HTTPController) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	app := r.PathValue("app")
@@ -181,20 +791,887 @@ func (h *HTTPController) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	exists, err := h.blobs.Exists(ctx, path.Join(app, "default.pgo"))
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	// Profiles that are pinned or promoted can be deleted even if the channel has no merged profile.
+	_, err := h.servedKey(ctx, app, channel)
 	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
 	case err != nil:
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
-	case !exists:
-		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		return
 	}
 
-	if err = h.events.Write(ctx, DeletedEvent{App: app}); err != nil {
+	// Naming the default channel deletes only the default channel, rather than the entire application.
+	if r.PathValue("channel") == DefaultChannel {
+		channel = DefaultChannel
+	}
+
+	if err = h.events.Write(ctx, DeletedEvent{App: app, Channel: channel}); err != nil {
 		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	api.Respond(ctx, w, http.StatusOK, DeleteResponse{})
 }
+
+// servedKey returns the location in blob storage of the profile served for an application's channel, see servedKeys.
+// Returns blob.ErrNotExist if the channel has no profile to serve.
+func (h *HTTPController) servedKey(ctx context.Context, app, channel string) (string, error) {
+	for _, key := range servedKeys(app, channel) {
+		exists, err := h.blobs.Exists(ctx, key)
+		switch {
+		case err != nil:
+			return "", err
+		case exists:
+			return key, nil
+		}
+	}
+
+	return "", blob.ErrNotExist
+}
+
+type (
+	// The VersionsResponse type is the response given when listing the generations of an application's profile.
+	VersionsResponse struct {
+		Versions []Version `json:"versions"`
+	}
+
+	// The Version type describes a single generation of an application's merged profile.
+	Version struct {
+		// The generation number, the highest of which is the current profile.
+		Version int `json:"version"`
+		// The profile size in bytes.
+		Size int64 `json:"size"`
+		// When the generation was created.
+		LastModified time.Time `json:"lastModified"`
+	}
+)
+
+// Versions handles an inbound HTTP request to list the retained generations of an application's profile, newest
+// first.
+func (h *HTTPController) Versions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	versions := make([]Version, 0)
+	for item, err := range h.blobs.List(ctx, blob.ListOptions{
+		Prefix: versionsPrefix(app, channel),
+		Filter: IsVersion(app, channel),
+	}) {
+		if err != nil {
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		version, _ := ParseVersionKey(app, channel, item.Key)
+		versions = append(versions, Version{
+			Version:      version,
+			Size:         item.Size,
+			LastModified: item.LastModified,
+		})
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int {
+		return cmp.Compare(b.Version, a.Version)
+	})
+
+	api.Respond(ctx, w, http.StatusOK, VersionsResponse{Versions: versions})
+}
+
+// DefaultSummaryTop is the number of functions included in each list of top functions within a Summary when the top
+// query parameter is not provided.
+const DefaultSummaryTop = 10
+
+// Summary handles an inbound HTTP request to summarise the profile served for an application, including statistics
+// on its contents and its top functions by flat & cumulative CPU time. The profile is the one that is downloaded,
+// preferring a pinned or stable profile over the merged profile. The number of top functions can be set using the top
+// query parameter.
+func (h *HTTPController) Summary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	top := DefaultSummaryTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			api.ErrorResponse(ctx, w, "invalid top", http.StatusBadRequest)
+			return
+		}
+
+		top = parsed
+	}
+
+	p, err := h.readServed(ctx, app, channel)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summary, err := Summarize(p, top)
+	if err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, summary)
+}
+
+// Edges handles an inbound HTTP request to report the hot call sites of the profile served for an application,
+// weighted as the compiler does when building with PGO. These are the call sites the compiler considers for inlining &
+// devirtualization. The profile is the one that is downloaded, preferring a pinned or stable profile over the merged
+// profile.
+func (h *HTTPController) Edges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.readServed(ctx, app, channel)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := HotCallEdges(p)
+	switch {
+	case errors.Is(err, ErrMissingStartLine):
+		api.ErrorResponse(ctx, w, "profile is missing function start lines, which are included from Go 1.20", http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, report)
+}
+
+// FlameGraph handles an inbound HTTP request to build a flame graph of the profile served for an application,
+// describing the CPU time spent within each stack of its samples. The profile is the one that is downloaded, preferring
+// a pinned or stable profile over the merged profile.
+func (h *HTTPController) FlameGraph(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.readServed(ctx, app, channel)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	graph, err := NewFlameGraph(p)
+	if err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, graph)
+}
+
+// DefaultDiffTop is the number of functions included in each list of functions within a Diff when the top query
+// parameter is not provided.
+const DefaultDiffTop = 10
+
+// DiffFormatPprof is the value of the format query parameter used to request a Diff as a pprof diff-base profile.
+const DiffFormatPprof = "pprof"
+
+// Diff handles an inbound HTTP request to compare two profiles of an application. The target profile is the current
+// profile of the channel within the URL path, or a previous generation of it when the version query parameter is
+// provided. The base profile is selected using the base_channel & base_version query parameters, defaulting to the
+// target channel & current profile. By default, a Diff is returned containing the number of functions given by the top
+// query parameter. Providing a format query parameter of DiffFormatPprof returns a pprof diff-base profile instead.
+func (h *HTTPController) Diff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+
+	baseChannel := channel
+	switch v := query.Get("base_channel"); {
+	case v == "":
+		break
+	case v == DefaultChannel:
+		baseChannel = ""
+	case IsValidChannelName(v):
+		baseChannel = v
+	default:
+		api.ErrorResponse(ctx, w, "invalid base channel name", http.StatusBadRequest)
+		return
+	}
+
+	versions := make(map[string]int)
+	for _, param := range []string{"version", "base_version"} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			api.ErrorResponse(ctx, w, "invalid "+strings.ReplaceAll(param, "_", " "), http.StatusBadRequest)
+			return
+		}
+
+		versions[param] = parsed
+	}
+
+	top := DefaultDiffTop
+	if v := query.Get("top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			api.ErrorResponse(ctx, w, "invalid top", http.StatusBadRequest)
+			return
+		}
+
+		top = parsed
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != DiffFormatPprof {
+		api.ErrorResponse(ctx, w, "invalid format", http.StatusBadRequest)
+		return
+	}
+
+	if baseChannel == channel && versions["base_version"] == versions["version"] {
+		api.ErrorResponse(ctx, w, "base and target profiles are the same", http.StatusBadRequest)
+		return
+	}
+
+	profiles := make([]*profile.Profile, 0, 2)
+	for _, target := range []struct {
+		name    string
+		channel string
+		version int
+	}{
+		{name: "base", channel: baseChannel, version: versions["base_version"]},
+		{name: "target", channel: channel, version: versions["version"]},
+	} {
+		key := MergedKey(app, target.channel)
+		if target.version > 0 {
+			key = VersionKey(app, target.channel, target.version)
+		}
+
+		p, err := h.readProfile(ctx, key)
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			api.ErrorResponse(ctx, w, target.name+" profile does not exist", http.StatusNotFound)
+			return
+		case err != nil:
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	base, target := profiles[0], profiles[1]
+	if format != DiffFormatPprof {
+		diff, err := Compare(base, target, top)
+		if err != nil {
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		api.Respond(ctx, w, http.StatusOK, diff)
+		return
+	}
+
+	diff, err := NewDiffBase(base, target)
+	if err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The profile is encoded in full before any of the body is written, so that encoding errors can be reported.
+	var buf bytes.Buffer
+	if err = diff.Write(&buf); err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	if _, err = buf.WriteTo(w); err != nil {
+		logger.FromContext(ctx).With(slog.String("error", err.Error())).ErrorContext(ctx, "failed to write diff")
+	}
+}
+
+// readServed parses the profile served for an application's channel, the same profile that is downloaded, see
+// servedKeys. Returns blob.ErrNotExist if the channel has no profile to serve.
+func (h *HTTPController) readServed(ctx context.Context, app, channel string) (*profile.Profile, error) {
+	for _, key := range servedKeys(app, channel) {
+		p, err := h.readProfile(ctx, key)
+		if errors.Is(err, blob.ErrNotExist) {
+			continue
+		}
+
+		return p, err
+	}
+
+	return nil, blob.ErrNotExist
+}
+
+// readProfile parses the profile stored at the given key. Returns blob.ErrNotExist if no profile exists at the key.
+func (h *HTTPController) readProfile(ctx context.Context, key string) (*profile.Profile, error) {
+	reader, err := h.blobs.NewReader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Close(ctx, reader)
+
+	return profile.Parse(reader)
+}
+
+type (
+	// The RollbackRequest type is the request body given when rolling back an application's profile.
+	RollbackRequest struct {
+		// The generation of the profile to restore.
+		Version int `json:"version"`
+	}
+
+	// The RollbackResponse type is the response given when a profile rollback has been requested.
+	RollbackResponse struct{}
+)
+
+// Rollback handles an inbound HTTP request to restore a previous generation of an application's profile as its
+// current profile. The rollback is performed asynchronously by the worker.
+func (h *HTTPController) Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	request, err := api.Decode[RollbackRequest](r.Body)
+	switch {
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	case request.Version <= 0:
+		api.ErrorResponse(ctx, w, "invalid version", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.blobs.Exists(ctx, VersionKey(app, channel, request.Version))
+	switch {
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	case !exists:
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if err = h.events.Write(ctx, RolledBackEvent{App: app, Channel: channel, Version: request.Version}); err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, RollbackResponse{})
+}
+
+type (
+	// The PromoteRequest type is the request body given when promoting a channel's profile to an application's stable
+	// profile.
+	PromoteRequest struct {
+		// The channel to promote the profile from.
+		Channel string `json:"channel"`
+		// The generation of the channel's profile to promote.
+		Version int `json:"version"`
+	}
+
+	// The PromoteResponse type is the response given when a profile promotion has been requested.
+	PromoteResponse struct{}
+)
+
+// Promote handles an inbound HTTP request to copy a generation of a channel's profile to the application's stable
+// profile, which is served from the default channel in place of its merged profile. The promotion is performed and
+// recorded asynchronously by the worker.
+func (h *HTTPController) Promote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	request, err := api.Decode[PromoteRequest](r.Body)
+	switch {
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	case request.Channel == DefaultChannel || !IsValidChannelName(request.Channel):
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	case request.Version <= 0:
+		api.ErrorResponse(ctx, w, "invalid version", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.blobs.Exists(ctx, VersionKey(app, request.Channel, request.Version))
+	switch {
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	case !exists:
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	payload := PromotedEvent{
+		App:        app,
+		Channel:    request.Channel,
+		Version:    request.Version,
+		PromotedAt: time.Now().UTC(),
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		payload.PromotedBy = principal.Name
+	}
+
+	if err = h.events.Write(ctx, payload); err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, PromoteResponse{})
+}
+
+type (
+	// The PinRequest type is the request body given when pinning an application's profile.
+	PinRequest struct {
+		// The generation of the profile to pin, the current profile is pinned when zero.
+		Version int `json:"version,omitempty"`
+	}
+
+	// The PinResponse type is the response given when an application's profile has been pinned.
+	PinResponse struct{}
+
+	// The UnpinResponse type is the response given when an application's profile has been unpinned.
+	UnpinResponse struct{}
+)
+
+// Pin handles an inbound HTTP request to pin an application's profile, copying either the current profile or the
+// requested generation to the pinned profile that downloads are served from. The worker continues to merge uploads
+// into the current profile, which is served again once the profile is unpinned. Pinning an already pinned profile
+// replaces the pinned profile.
+func (h *HTTPController) Pin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	// An empty body pins the current profile.
+	request, err := api.Decode[PinRequest](r.Body)
+	switch {
+	case err != nil && !errors.Is(err, io.EOF):
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	case request.Version < 0:
+		api.ErrorResponse(ctx, w, "invalid version", http.StatusBadRequest)
+		return
+	}
+
+	keys := []string{MergedKey(app, channel)}
+	switch {
+	case request.Version > 0:
+		keys = []string{VersionKey(app, channel, request.Version)}
+	case channelName(channel) == DefaultChannel:
+		// The default channel serves its stable profile while there is one, so that is the current profile.
+		keys = []string{StableKey(app), MergedKey(app, channel)}
+	}
+
+	for _, key := range keys {
+		if err = h.copyProfile(ctx, key, PinnedKey(app, channel)); !errors.Is(err, blob.ErrNotExist) {
+			break
+		}
+	}
+
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, PinResponse{})
+}
+
+// Unpin handles an inbound HTTP request to unpin an application's profile, so that downloads are served from the
+// current profile once more.
+func (h *HTTPController) Unpin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	err := h.blobs.Delete(ctx, PinnedKey(app, channel))
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, "profile is not pinned", http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, UnpinResponse{})
+}
+
+func (h *HTTPController) copyProfile(ctx context.Context, from, to string) error {
+	reader, err := h.blobs.NewReader(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, reader)
+
+	// The profile is read in full before writing so that a failed read cannot leave a partial profile behind.
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	writer, err := h.blobs.NewWriter(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(content); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+type (
+	// The PromotionsResponse type is the response given when listing the promotions of an application's profile.
+	PromotionsResponse struct {
+		Promotions []Promotion `json:"promotions"`
+	}
+)
+
+// Promotions handles an inbound HTTP request to list the promotions made to an application's stable profile, newest
+// first.
+func (h *HTTPController) Promotions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	promotions := make([]Promotion, 0)
+	for item, err := range h.blobs.List(ctx, blob.ListOptions{
+		Prefix: promotionsPrefix(app),
+		Filter: IsPromotion(app),
+	}) {
+		if err != nil {
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		promotion, err := h.readPromotion(ctx, item.Key)
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			continue
+		case err != nil:
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	slices.SortFunc(promotions, func(a, b Promotion) int {
+		return b.PromotedAt.Compare(a.PromotedAt)
+	})
+
+	api.Respond(ctx, w, http.StatusOK, PromotionsResponse{Promotions: promotions})
+}
+
+func (h *HTTPController) readPromotion(ctx context.Context, key string) (Promotion, error) {
+	reader, err := h.blobs.NewReader(ctx, key)
+	if err != nil {
+		return Promotion{}, err
+	}
+	defer closers.Close(ctx, reader)
+
+	var promotion Promotion
+	if err = json.NewDecoder(reader).Decode(&promotion); err != nil {
+		return Promotion{}, fmt.Errorf("failed to decode promotion at %s: %w", key, err)
+	}
+
+	return promotion, nil
+}
+
+type (
+	// The StagedResponse type is the response given when listing the profiles uploaded to an application's channel
+	// that are waiting to be merged.
+	StagedResponse struct {
+		Uploads []StagedUpload `json:"uploads"`
+	}
+
+	// The StagedUpload type describes a single profile uploaded to an application's channel that is waiting to be
+	// merged.
+	StagedUpload struct {
+		// The identifier of the upload, used to requeue or discard it.
+		ID string `json:"id"`
+		// The location in blob storage the profile is stored at.
+		Key string `json:"key"`
+		// The profile size in bytes.
+		Size int64 `json:"size"`
+		// When the profile was uploaded.
+		UploadedAt time.Time `json:"uploadedAt"`
+	}
+
+	// The RequeueResponse type is the response given when a staged upload has been requeued.
+	RequeueResponse struct{}
+
+	// The DiscardResponse type is the response given when a staged upload has been discarded.
+	DiscardResponse struct{}
+)
+
+// Staged handles an inbound HTTP request to list the profiles uploaded to an application's channel that are waiting
+// to be merged by the worker, oldest first. Uploads are removed from the list once merged, so a growing list
+// indicates that the worker is falling behind or failing to merge them.
+func (h *HTTPController) Staged(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	app := r.PathValue("app")
+
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	uploads := make([]StagedUpload, 0)
+	for item, err := range h.blobs.List(ctx, blob.ListOptions{
+		Prefix: stagingPrefix(app, channel),
+		Filter: IsStaged(app, channel),
+	}) {
+		if err != nil {
+			api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		id := strings.TrimPrefix(item.Key, stagingPrefix(app, channel))
+		uploadedAt, _ := ParseStagingID(id)
+
+		uploads = append(uploads, StagedUpload{
+			ID:         id,
+			Key:        item.Key,
+			Size:       item.Size,
+			UploadedAt: uploadedAt,
+		})
+	}
+
+	slices.SortFunc(uploads, func(a, b StagedUpload) int {
+		return a.UploadedAt.Compare(b.UploadedAt)
+	})
+
+	api.Respond(ctx, w, http.StatusOK, StagedResponse{Uploads: uploads})
+}
+
+// Requeue handles an inbound HTTP request to publish the upload event of a staged profile again, so that the worker
+// attempts to merge it. This is used to recover uploads whose events were lost or failed to be handled.
+func (h *HTTPController) Requeue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	app, channel, key, ok := stagedKeyFromPath(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	exists, err := h.blobs.Exists(ctx, key)
+	switch {
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	case !exists:
+		api.ErrorResponse(ctx, w, "staged upload does not exist", http.StatusNotFound)
+		return
+	}
+
+	if err = h.events.Write(ctx, UploadedEvent{App: app, Channel: channel, ProfileKey: key}); err != nil {
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, RequeueResponse{})
+}
+
+// Discard handles an inbound HTTP request to delete a staged profile without merging it. This is used to remove
+// uploads the worker is unable to merge.
+func (h *HTTPController) Discard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	_, _, key, ok := stagedKeyFromPath(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.blobs.Delete(ctx, key)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		api.ErrorResponse(ctx, w, "staged upload does not exist", http.StatusNotFound)
+		return
+	case err != nil:
+		api.ErrorResponse(ctx, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.Respond(ctx, w, http.StatusOK, DiscardResponse{})
+}
+
+// stagedKeyFromPath returns the application, channel & location in blob storage of the staged profile specified
+// within the URL path. Returns false after writing an error response if any of them are invalid.
+func stagedKeyFromPath(ctx context.Context, w http.ResponseWriter, r *http.Request) (string, string, string, bool) {
+	app := r.PathValue("app")
+	if !IsValidAppName(app) {
+		api.ErrorResponse(ctx, w, "invalid app name", http.StatusBadRequest)
+		return "", "", "", false
+	}
+
+	channel, ok := channelFromPath(r)
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid channel name", http.StatusBadRequest)
+		return "", "", "", false
+	}
+
+	uploadedAt, ok := ParseStagingID(r.PathValue("id"))
+	if !ok {
+		api.ErrorResponse(ctx, w, "invalid staged upload id", http.StatusBadRequest)
+		return "", "", "", false
+	}
+
+	return app, channel, StagingKey(app, channel, uploadedAt), true
+}
+
+// Constants for response headers given when downloading profiles.
+const (
+	// ChannelHeader describes the channel a downloaded profile was served from, which may differ from the requested
+	// channel when a fallback chain is used.
+	ChannelHeader = "Autopgo-Channel"
+	// PinnedHeader is set to "true" when the downloaded profile is the channel's pinned profile.
+	PinnedHeader = "Autopgo-Pinned"
+)
+
+// channelFromPath returns the channel specified within the URL path, where the default channel is returned as an
+// empty string. Returns false if the channel name is invalid.
+func channelFromPath(r *http.Request) (string, bool) {
+	channel := r.PathValue("channel")
+	switch {
+	case channel == "" || channel == DefaultChannel:
+		return "", true
+	case IsValidChannelName(channel):
+		return channel, true
+	default:
+		return "", false
+	}
+}
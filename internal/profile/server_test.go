@@ -2,32 +2,123 @@ package profile_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	pprof "github.com/google/pprof/profile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/davidsbond/autopgo/internal/api"
+	"github.com/davidsbond/autopgo/internal/auth"
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/testutil"
 )
 
+func TestHTTPController_Register(t *testing.T) {
+	t.Parallel()
+
+	assert.NotPanics(t, func() {
+		profile.NewHTTPController(nil, nil, profile.UploadConfig{}).Register(http.NewServeMux())
+	})
+}
+
+func TestHTTPController_Scopes(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Method string
+		Path   string
+		Scope  auth.Scope
+	}{
+		{Method: http.MethodGet, Path: "/api/profile", Scope: auth.ScopeList},
+		{Method: http.MethodPost, Path: "/api/profile/test-app", Scope: auth.ScopeUpload},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/canary", Scope: auth.ScopeUpload},
+		{Method: http.MethodGet, Path: "/api/profile/test-app", Scope: auth.ScopeDownload},
+		{Method: http.MethodHead, Path: "/api/profile/test-app/canary", Scope: auth.ScopeDownload},
+		{Method: http.MethodDelete, Path: "/api/profile/test-app", Scope: auth.ScopeDelete},
+		{Method: http.MethodDelete, Path: "/api/profile/test-app/canary", Scope: auth.ScopeDelete},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/canary/versions", Scope: auth.ScopeDownload},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/summary", Scope: auth.ScopeDownload},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/edges", Scope: auth.ScopeDownload},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/flamegraph", Scope: auth.ScopeDownload},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/canary/diff", Scope: auth.ScopeDownload},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/rollback", Scope: auth.ScopeUpload},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/promote", Scope: auth.ScopePromote},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/promotions", Scope: auth.ScopeDownload},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/canary/pin", Scope: auth.ScopePromote},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/unpin", Scope: auth.ScopePromote},
+		{Method: http.MethodGet, Path: "/api/profile/test-app/staging", Scope: auth.ScopeDownload},
+		{Method: http.MethodPost, Path: "/api/profile/test-app/canary/staging/1/requeue", Scope: auth.ScopeUpload},
+		{Method: http.MethodDelete, Path: "/api/profile/test-app/staging/1", Scope: auth.ScopeDelete},
+	}
+
+	scopes := []auth.Scope{auth.ScopeUpload, auth.ScopeDownload, auth.ScopeList, auth.ScopeDelete, auth.ScopePromote}
+
+	mux := http.NewServeMux()
+	profile.NewHTTPController(nil, nil, profile.UploadConfig{}).Register(mux)
+
+	for _, tc := range tt {
+		t.Run(tc.Method+" "+tc.Path, func(t *testing.T) {
+			// Every scope other than the one required, so that handlers are never called.
+			verifier := auth.NewTokenVerifier([]auth.Token{
+				{
+					Token:  "token",
+					Scopes: slices.DeleteFunc(slices.Clone(scopes), func(s auth.Scope) bool { return s == tc.Scope }),
+					Apps:   []string{"*"},
+				},
+			})
+
+			handler := auth.Middleware(verifier)(mux)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tc.Method, tc.Path, nil))
+			assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+
+			r := httptest.NewRequest(tc.Method, tc.Path, nil)
+			r.Header.Set("Authorization", "Bearer token")
+
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.EqualValues(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
 func TestHTTPController_Upload(t *testing.T) {
 	t.Parallel()
 
+	contentHash := sha256.Sum256(validProfile)
+	contentKey := "test-app/uploads/sha256-" + hex.EncodeToString(contentHash[:]) + ".json"
+	idempotencyHash := sha256.Sum256([]byte("abc"))
+	idempotencyKey := "test-app/uploads/idempotency-" + hex.EncodeToString(idempotencyHash[:]) + ".json"
+
 	tt := []struct {
-		Name           string
-		App            string
-		Profile        []byte
-		Setup          func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
-		ExpectedStatus int
+		Name              string
+		App               string
+		Channel           string
+		Profile           []byte
+		Headers           map[string]string
+		Config            profile.UploadConfig
+		ExpectedDuplicate bool
+		Setup             func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
+		ExpectedStatus    int
+		ExpectedError     string
 	}{
 		{
 			Name:           "invalid app name",
@@ -40,6 +131,203 @@ func TestHTTPController_Upload(t *testing.T) {
 			ExpectedStatus: http.StatusBadRequest,
 			Profile:        []byte("invalid profile"),
 		},
+		{
+			Name:           "profile too large",
+			App:            "test-app",
+			ExpectedStatus: http.StatusRequestEntityTooLarge,
+			ExpectedError:  "profile exceeds the maximum size of 1024 bytes",
+			Profile:        validProfile,
+			Config:         profile.UploadConfig{MaxBytes: 1024},
+		},
+		{
+			Name:           "not a cpu profile",
+			App:            "test-app",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedError:  "profile period type must be cpu/nanoseconds, only cpu profiles are accepted",
+			Profile: modifiedProfile(t, func(p *pprof.Profile) {
+				p.PeriodType = &pprof.ValueType{Type: "space", Unit: "bytes"}
+			}),
+		},
+		{
+			Name:           "unexpected sample types",
+			App:            "test-app",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedError:  "profile sample types must be samples/count & cpu/nanoseconds, only cpu profiles are accepted",
+			Profile: modifiedProfile(t, func(p *pprof.Profile) {
+				p.SampleType[1] = &pprof.ValueType{Type: "alloc_space", Unit: "bytes"}
+			}),
+		},
+		{
+			Name:           "no samples",
+			App:            "test-app",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedError:  "profile contains no samples",
+			Profile: modifiedProfile(t, func(p *pprof.Profile) {
+				p.Sample = nil
+			}),
+		},
+		{
+			Name:           "duration too short",
+			App:            "test-app",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedError:  "profile duration of 1s is shorter than the minimum of 5s",
+			Config:         profile.UploadConfig{MinDuration: 5 * time.Second},
+			Profile: modifiedProfile(t, func(p *pprof.Profile) {
+				p.DurationNanos = int64(time.Second)
+			}),
+		},
+		{
+			Name:           "duration too long",
+			App:            "test-app",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedError:  "profile duration of 2m0s is longer than the maximum of 1m0s",
+			Config:         profile.UploadConfig{MaxDuration: time.Minute},
+			Profile: modifiedProfile(t, func(p *pprof.Profile) {
+				p.DurationNanos = int64(2 * time.Minute)
+			}),
+		},
+		{
+			Name:              "duplicate content",
+			App:               "test-app",
+			ExpectedStatus:    http.StatusOK,
+			ExpectedDuplicate: true,
+			Profile:           validProfile,
+			Config:            profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, contentKey, mock.Anything).
+					Return(blob.ErrExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, contentKey).
+					Return(&ReadCloser{data: bytes.NewBufferString(`{"key": "test-app/staging/1", "uploadedAt": "` + time.Now().Format(time.RFC3339) + `"}`)}, nil)
+			},
+		},
+		{
+			Name:              "duplicate idempotency key",
+			App:               "test-app",
+			ExpectedStatus:    http.StatusOK,
+			ExpectedDuplicate: true,
+			Profile:           validProfile,
+			Headers:           map[string]string{profile.IdempotencyKeyHeader: "abc"},
+			Config:            profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, idempotencyKey, mock.Anything).
+					Return(blob.ErrExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, idempotencyKey).
+					Return(&ReadCloser{data: bytes.NewBufferString(`{"key": "test-app/staging/1", "uploadedAt": "` + time.Now().Format(time.RFC3339) + `"}`)}, nil)
+			},
+		},
+		{
+			Name:              "duplicate content with new idempotency key",
+			App:               "test-app",
+			ExpectedStatus:    http.StatusOK,
+			ExpectedDuplicate: true,
+			Profile:           validProfile,
+			Headers:           map[string]string{profile.IdempotencyKeyHeader: "abc"},
+			Config:            profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, idempotencyKey, mock.Anything).
+					Return(nil)
+
+				blobs.EXPECT().
+					Create(mock.Anything, contentKey, mock.Anything).
+					Return(blob.ErrExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, contentKey).
+					Return(&ReadCloser{data: bytes.NewBufferString(`{"key": "test-app/staging/1", "uploadedAt": "` + time.Now().Format(time.RFC3339) + `"}`)}, nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, idempotencyKey).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "new upload is recorded",
+			App:            "test-app",
+			ExpectedStatus: http.StatusCreated,
+			Profile:        validProfile,
+			Headers:        map[string]string{profile.IdempotencyKeyHeader: "abc"},
+			Config:         profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, idempotencyKey, mock.Anything).
+					Return(nil)
+
+				blobs.EXPECT().
+					Create(mock.Anything, contentKey, mock.Anything).
+					Return(nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app/staging/")).
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, uploadedEventMatcher("test-app")).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "expired duplicate is uploaded",
+			App:            "test-app",
+			ExpectedStatus: http.StatusCreated,
+			Profile:        validProfile,
+			Config:         profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, contentKey, mock.Anything).
+					Return(blob.ErrExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, contentKey).
+					Return(&ReadCloser{data: bytes.NewBufferString(`{"key": "test-app/staging/1", "uploadedAt": "2020-01-01T00:00:00Z"}`)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, contentKey).
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app/staging/")).
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, uploadedEventMatcher("test-app")).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "failed upload is not recorded",
+			App:            "test-app",
+			ExpectedStatus: http.StatusInternalServerError,
+			Profile:        validProfile,
+			Config:         profile.UploadConfig{DedupWindow: time.Hour},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Create(mock.Anything, contentKey, mock.Anything).
+					Return(nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app/staging/")).
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, uploadedEventMatcher("test-app")).
+					Return(io.EOF)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, contentKey).
+					Return(nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, appKeyMatcher("test-app/staging/")).
+					Return(nil)
+			},
+		},
 		{
 			Name:           "error opening writer",
 			App:            "test-app",
@@ -64,43 +352,1789 @@ func TestHTTPController_Upload(t *testing.T) {
 				events.EXPECT().
 					Write(mock.Anything, uploadedEventMatcher("test-app")).
 					Return(io.EOF)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, appKeyMatcher("test-app")).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "error closing writer",
+			App:            "test-app",
+			ExpectedStatus: http.StatusInternalServerError,
+			Profile:        validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app")).
+					Return(&WriteCloser{closeError: io.EOF}, nil)
+			},
+		},
+		{
+			Name:           "error on profile write",
+			App:            "test-app",
+			ExpectedStatus: http.StatusInternalServerError,
+			Profile:        validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app")).
+					Return(&WriteCloser{writeError: io.EOF}, nil)
+			},
+		},
+		{
+			Name:           "invalid channel name",
+			App:            "test-app",
+			Channel:        "versions",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "success with channel",
+			App:            "test-app",
+			Channel:        "prod",
+			ExpectedStatus: http.StatusCreated,
+			Profile:        validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app/channels/prod/staging/")).
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, mock.MatchedBy(func(e profile.UploadedEvent) bool {
+						return e.App == "test-app" && e.Channel == "prod"
+					})).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "success",
+			App:            "test-app",
+			ExpectedStatus: http.StatusCreated,
+			Profile:        validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewWriter(mock.Anything, appKeyMatcher("test-app")).
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, uploadedEventMatcher("test-app")).
+					Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			events := mocks.NewMockEventWriter(t)
+
+			if tc.Setup != nil {
+				tc.Setup(blobs, events)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.Profile))
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+			for k, v := range tc.Headers {
+				r.Header.Set(k, v)
+			}
+
+			profile.NewHTTPController(blobs, events, tc.Config).Upload(w, r)
+
+			assert.EqualValues(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedError != "" {
+				var actual api.Error
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+				assert.EqualValues(t, tc.ExpectedError, actual.Message)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK || tc.ExpectedStatus == http.StatusCreated {
+				var actual profile.UploadResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+				assert.EqualValues(t, tc.ExpectedDuplicate, actual.Duplicate)
+			}
+		})
+	}
+}
+
+func TestHTTPController_Download(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	object := blob.Object{Key: "test-app/default.pgo", Size: int64(len(validProfile)), LastModified: modified}
+
+	tt := []struct {
+		Name            string
+		App             string
+		Channel         string
+		Method          string
+		Query           string
+		Headers         map[string]string
+		ExpectedStatus  int
+		ExpectedProfile []byte
+		ExpectedChannel string
+		ExpectedPinned  bool
+		Setup           func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "invalid app name",
+			App:            "// not a valid string",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:            "success",
+			App:             "test-app",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:            "pinned",
+			App:             "test-app",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			ExpectedPinned:  true,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/pinned.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:            "promoted",
+			App:             "test-app",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/stable.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:            "previous version",
+			App:             "test-app",
+			Query:           "?version=2",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/versions/2.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/versions/2.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "invalid version",
+			App:            "test-app",
+			Query:          "?version=latest",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "matching etag",
+			App:            "test-app",
+			Headers:        map[string]string{"If-None-Match": profile.ETag(validProfile)},
+			ExpectedStatus: http.StatusNotModified,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:            "stale etag",
+			App:             "test-app",
+			Headers:         map[string]string{"If-None-Match": profile.ETag([]byte("stale"))},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "not modified since",
+			App:            "test-app",
+			Headers:        map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			ExpectedStatus: http.StatusNotModified,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "head request",
+			App:            "test-app",
+			Method:         http.MethodHead,
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:            "channel with fallback",
+			App:             "test-app",
+			Channel:         "canary",
+			Query:           "?fallback=prod,default",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedProfile: validProfile,
+			ExpectedChannel: "prod",
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/canary/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/canary/default.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/prod/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "fallback exhausted",
+			App:            "test-app",
+			Channel:        "canary",
+			Query:          "?fallback=prod",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/canary/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/canary/default.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/prod/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+			},
+		},
+		{
+			Name:           "invalid fallback",
+			App:            "test-app",
+			Query:          "?fallback=Prod",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "fallback with version",
+			App:            "test-app",
+			Query:          "?fallback=prod&version=2",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "profile does not exist",
+			App:            "test-app",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+			},
+		},
+		{
+			Name:           "error opening reader",
+			App:            "test-app",
+			ExpectedStatus: http.StatusInternalServerError,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(nil, io.EOF)
+			},
+		},
+		{
+			Name:           "error on profile read",
+			App:            "test-app",
+			ExpectedStatus: http.StatusInternalServerError,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/pinned.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/stable.pgo").
+					Return(blob.Object{}, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Stat(mock.Anything, "test-app/default.pgo").
+					Return(object, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{readError: io.ErrClosedPipe}, nil)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			method := http.MethodGet
+			if tc.Method != "" {
+				method = tc.Method
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(method, "/"+tc.Query, nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+			for k, v := range tc.Headers {
+				r.Header.Set(k, v)
+			}
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Download(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedProfile != nil {
+				assert.Equal(t, tc.ExpectedProfile, w.Body.Bytes())
+			}
+
+			if tc.ExpectedStatus == http.StatusOK {
+				expectedChannel := profile.DefaultChannel
+				if tc.ExpectedChannel != "" {
+					expectedChannel = tc.ExpectedChannel
+				}
+
+				assert.Equal(t, expectedChannel, w.Header().Get(profile.ChannelHeader))
+				assert.Equal(t, tc.ExpectedPinned, w.Header().Get(profile.PinnedHeader) == "true")
+				assert.Equal(t, profile.ETag(validProfile), w.Header().Get("ETag"))
+				assert.Equal(t, modified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+			}
+
+			if method == http.MethodHead {
+				assert.Empty(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestHTTPController_List(t *testing.T) {
+	t.Parallel()
+
+	objects := []blob.Object{
+		{Key: "alpha/default.pgo", Size: 300, LastModified: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{Key: "beta/default.pgo", Size: 100, LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "alpha/channels/prod/default.pgo", Size: 200, LastModified: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Key: "alpha/staging/12345"},
+		{Key: "alpha/versions/1.pgo"},
+		{Key: "alpha/channels/canary/staging/12345"},
+	}
+
+	listObjects := func(blobs *mocks.MockBlobRepository) {
+		blobs.EXPECT().
+			List(mock.Anything, mock.Anything).
+			RunAndReturn(testutil.ListObjects(objects...))
+
+		blobs.EXPECT().
+			Stat(mock.Anything, mock.Anything).
+			RunAndReturn(testutil.StatObjects(objects...)).
+			Maybe()
+	}
+
+	alpha := profile.Profile{Key: "alpha", Size: 300, LastModified: objects[0].LastModified}
+	beta := profile.Profile{Key: "beta", Size: 100, LastModified: objects[1].LastModified}
+	alphaProd := profile.Profile{Key: "alpha", Channel: "prod", Size: 200, LastModified: objects[2].LastModified}
+
+	tt := []struct {
+		Name           string
+		Query          string
+		ExpectedStatus int
+		ExpectsError   bool
+		Expected       profile.ListResponse
+		Principal      *auth.Principal
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{
+					{
+						Key:          "test",
+						Size:         1000,
+						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+					{
+						Key:          "test",
+						Channel:      "prod",
+						Size:         2000,
+						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				objects := []blob.Object{
+					{
+						Key:          "test/default.pgo",
+						Size:         1000,
+						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+					{
+						Key:          "test/channels/prod/default.pgo",
+						Size:         2000,
+						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				}
+
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					RunAndReturn(testutil.ListObjects(objects...))
+
+				blobs.EXPECT().
+					Stat(mock.Anything, mock.Anything).
+					RunAndReturn(testutil.StatObjects(objects...))
+			},
+		},
+		{
+			Name:           "returns errors",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectsError:   true,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						yield(blob.Object{}, io.ErrClosedPipe)
+					})
+			},
+		},
+		{
+			Name:           "sorts by name",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{alpha, alphaProd, beta},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "sorts by descending size",
+			Query:          "?sort=-size",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{alpha, alphaProd, beta},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "sorts by last modified",
+			Query:          "?sort=lastModified",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{beta, alphaProd, alpha},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "filters by prefix",
+			Query:          "?prefix=be",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{beta},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "filters by modified since",
+			Query:          "?modified_since=2020-01-02T00:00:00Z",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{alpha, alphaProd},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "filters by permitted apps",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.ListResponse{
+				Profiles: []profile.Profile{alpha, alphaProd},
+			},
+			Principal: &auth.Principal{
+				Grants: []auth.Grant{
+					{Scopes: []auth.Scope{auth.ScopeList}, Apps: []string{"al*"}},
+					{Scopes: []auth.Scope{auth.ScopeDownload}, Apps: []string{"*"}},
+				},
+			},
+			Setup: listObjects,
+		},
+		{
+			Name:           "invalid sort",
+			Query:          "?sort=colour",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+		{
+			Name:           "invalid limit",
+			Query:          "?limit=1001",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+		{
+			Name:           "invalid modified since",
+			Query:          "?modified_since=yesterday",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+		{
+			Name:           "invalid page token",
+			Query:          "?page_token=nope",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/"+tc.Query, nil)
+			if tc.Principal != nil {
+				r = r.WithContext(auth.ToContext(r.Context(), *tc.Principal))
+			}
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).List(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			decoder := json.NewDecoder(w.Body)
+			if tc.ExpectsError {
+				var apiErr api.Error
+				require.NoError(t, decoder.Decode(&apiErr))
+				assert.EqualValues(t, tc.ExpectedStatus, apiErr.Code)
+				return
+			}
+
+			var actual profile.ListResponse
+			require.NoError(t, decoder.Decode(&actual))
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestHTTPController_ListPages(t *testing.T) {
+	t.Parallel()
+
+	objects := make([]blob.Object, 0)
+	for i := range 10 {
+		objects = append(objects, blob.Object{
+			Key:          fmt.Sprintf("app-%d/default.pgo", i),
+			Size:         int64(i % 3),
+			LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+
+		if i%2 == 0 {
+			objects = append(objects, blob.Object{
+				Key:          fmt.Sprintf("app-%d/channels/prod/default.pgo", i),
+				Size:         int64(i % 4),
+				LastModified: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			})
+		}
+	}
+
+	// The keys of "app" are listed after those of "app-0" to "app-9", as "-" comes before "/".
+	objects = append(objects, blob.Object{
+		Key:          "app/default.pgo",
+		Size:         1,
+		LastModified: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+	})
+
+	for _, sort := range []string{"name", "-name", "-size", "lastModified"} {
+		t.Run(sort, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			blobs.EXPECT().
+				List(mock.Anything, mock.Anything).
+				RunAndReturn(testutil.ListObjects(objects...))
+
+			blobs.EXPECT().
+				Stat(mock.Anything, mock.Anything).
+				RunAndReturn(testutil.StatObjects(objects...))
+
+			controller := profile.NewHTTPController(blobs, nil, profile.UploadConfig{})
+
+			list := func(query url.Values) profile.ListResponse {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+
+				controller.List(w, r)
+
+				require.Equal(t, http.StatusOK, w.Code)
+
+				var response profile.ListResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				return response
+			}
+
+			expected := list(url.Values{"sort": {sort}}).Profiles
+			require.Len(t, expected, len(objects))
+
+			var actual []profile.Profile
+			query := url.Values{"sort": {sort}, "limit": {"3"}}
+			for {
+				page := list(query)
+				assert.LessOrEqual(t, len(page.Profiles), 3)
+				actual = append(actual, page.Profiles...)
+
+				if page.NextPageToken == "" {
+					break
+				}
+
+				query.Set("page_token", page.NextPageToken)
+			}
+
+			assert.EqualValues(t, expected, actual)
+		})
+	}
+}
+
+func TestHTTPController_ListDefaultLimit(t *testing.T) {
+	t.Parallel()
+
+	objects := make([]blob.Object, 0)
+	for i := range profile.MaxListLimit + 5 {
+		objects = append(objects, blob.Object{Key: fmt.Sprintf("app-%04d/default.pgo", i)})
+	}
+
+	var requests atomic.Int64
+	listObjects := testutil.ListObjects(objects...)
+
+	blobs := mocks.NewMockBlobRepository(t)
+	blobs.EXPECT().
+		List(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error] {
+			requests.Add(1)
+			return listObjects(ctx, opts)
+		})
+
+	controller := profile.NewHTTPController(blobs, nil, profile.UploadConfig{})
+
+	w := httptest.NewRecorder()
+	controller.List(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response profile.ListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Len(t, response.Profiles, profile.MaxListLimit)
+	assert.NotEmpty(t, response.NextPageToken)
+
+	// None of the applications have other channels, so each page lists the applications followed by the directory of
+	// each one it needs: those up to one past the page for the first, and those from the page token for the second.
+	assert.EqualValues(t, 1+profile.MaxListLimit+1, requests.Load())
+
+	requests.Store(0)
+	w = httptest.NewRecorder()
+	controller.List(w, httptest.NewRequest(http.MethodGet, "/?page_token="+response.NextPageToken, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var next profile.ListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&next))
+	assert.Len(t, next.Profiles, 5)
+	assert.Empty(t, next.NextPageToken)
+	assert.EqualValues(t, 1+6, requests.Load())
+}
+
+func TestHTTPController_Delete(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Channel        string
+		ExpectedStatus int
+		ExpectsError   bool
+		Setup          func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
+	}{
+		{
+			Name:           "success",
+			ExpectedStatus: http.StatusOK,
+			App:            "test",
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/pinned.pgo").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/stable.pgo").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/default.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, deletedEventMatcher("test")).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "success with channel",
+			ExpectedStatus: http.StatusOK,
+			App:            "test",
+			Channel:        "prod",
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/channels/prod/pinned.pgo").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/channels/prod/default.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, profile.DeletedEvent{App: "test", Channel: "prod"}).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "success with default channel",
+			ExpectedStatus: http.StatusOK,
+			App:            "test",
+			Channel:        profile.DefaultChannel,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/pinned.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, profile.DeletedEvent{App: "test", Channel: profile.DefaultChannel}).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "success with only a stable profile",
+			ExpectedStatus: http.StatusOK,
+			App:            "test",
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/pinned.pgo").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/stable.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, deletedEventMatcher("test")).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+		{
+			Name:           "does not exist",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			ExpectsError:   true,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, mock.Anything).
+					Return(false, nil).
+					Times(3)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			events := mocks.NewMockEventWriter(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs, events)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+
+			profile.NewHTTPController(blobs, events, profile.UploadConfig{}).Delete(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			decoder := json.NewDecoder(w.Body)
+			if tc.ExpectsError {
+				var apiErr api.Error
+				require.NoError(t, decoder.Decode(&apiErr))
+				assert.EqualValues(t, tc.ExpectedStatus, apiErr.Code)
+				return
+			}
+		})
+	}
+}
+
+func TestHTTPController_Versions(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		ExpectedStatus int
+		ExpectsError   bool
+		Expected       profile.VersionsResponse
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.VersionsResponse{
+				Versions: []profile.Version{
+					{
+						Version:      2,
+						Size:         2000,
+						LastModified: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+					},
+					{
+						Version:      1,
+						Size:         1000,
+						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						_ = yield(blob.Object{
+							Key:          "test/versions/1.pgo",
+							Size:         1000,
+							LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						}, nil) && yield(blob.Object{
+							Key:          "test/versions/2.pgo",
+							Size:         2000,
+							LastModified: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+						}, nil)
+					})
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectsError:   true,
+		},
+		{
+			Name:           "returns errors",
+			App:            "test",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectsError:   true,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						yield(blob.Object{}, io.ErrClosedPipe)
+					})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetPathValue("app", tc.App)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Versions(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			decoder := json.NewDecoder(w.Body)
+			if tc.ExpectsError {
+				var apiErr api.Error
+				require.NoError(t, decoder.Decode(&apiErr))
+				assert.EqualValues(t, tc.ExpectedStatus, apiErr.Code)
+				return
+			}
+
+			var actual profile.VersionsResponse
+			require.NoError(t, decoder.Decode(&actual))
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestHTTPController_Rollback(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Body           string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			Body:           `{"version": 2}`,
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/versions/2.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, profile.RolledBackEvent{App: "test", Version: 2}).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid version",
+			App:            "test",
+			Body:           `{"version": 0}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid body",
+			App:            "test",
+			Body:           `not json`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "version does not exist",
+			App:            "test",
+			Body:           `{"version": 2}`,
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/versions/2.pgo").
+					Return(false, nil)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			events := mocks.NewMockEventWriter(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs, events)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.Body))
+			r.SetPathValue("app", tc.App)
+
+			profile.NewHTTPController(blobs, events, profile.UploadConfig{}).Rollback(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHTTPController_Promote(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Body           string
+		Principal      *auth.Principal
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			Body:           `{"channel": "candidate", "version": 2}`,
+			Principal:      &auth.Principal{Name: "release-bot"},
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/channels/candidate/versions/2.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, mock.MatchedBy(func(evt profile.PromotedEvent) bool {
+						return evt.App == "test" &&
+							evt.Channel == "candidate" &&
+							evt.Version == 2 &&
+							evt.PromotedBy == "release-bot" &&
+							!evt.PromotedAt.IsZero()
+					})).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "success without authentication",
+			App:            "test",
+			Body:           `{"channel": "candidate", "version": 2}`,
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/channels/candidate/versions/2.pgo").
+					Return(true, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, mock.MatchedBy(func(evt profile.PromotedEvent) bool {
+						return evt.PromotedBy == ""
+					})).
+					Return(nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "missing channel",
+			App:            "test",
+			Body:           `{"version": 2}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "default channel",
+			App:            "test",
+			Body:           `{"channel": "default", "version": 2}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid version",
+			App:            "test",
+			Body:           `{"channel": "candidate", "version": 0}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "version does not exist",
+			App:            "test",
+			Body:           `{"channel": "candidate", "version": 2}`,
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test/channels/candidate/versions/2.pgo").
+					Return(false, nil)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			events := mocks.NewMockEventWriter(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs, events)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.Body))
+			r.SetPathValue("app", tc.App)
+			if tc.Principal != nil {
+				r = r.WithContext(auth.ToContext(r.Context(), *tc.Principal))
+			}
+
+			profile.NewHTTPController(blobs, events, profile.UploadConfig{}).Promote(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHTTPController_Promotions(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		ExpectedStatus int
+		Expected       profile.PromotionsResponse
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.PromotionsResponse{
+				Promotions: []profile.Promotion{
+					{
+						Channel:    "candidate",
+						Version:    3,
+						PromotedBy: "release-bot",
+						PromotedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+					},
+					{
+						Channel:    "candidate",
+						Version:    1,
+						PromotedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				first := profile.Promotion{
+					Channel:    "candidate",
+					Version:    1,
+					PromotedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+
+				second := profile.Promotion{
+					Channel:    "candidate",
+					Version:    3,
+					PromotedBy: "release-bot",
+					PromotedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				}
+
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						_ = yield(blob.Object{Key: profile.PromotionKey("test", first.PromotedAt)}, nil) &&
+							yield(blob.Object{Key: profile.PromotionKey("test", second.PromotedAt)}, nil)
+					})
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, profile.PromotionKey("test", first.PromotedAt)).
+					Return(io.NopCloser(bytes.NewReader(mustMarshal(t, first))), nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, profile.PromotionKey("test", second.PromotedAt)).
+					Return(io.NopCloser(bytes.NewReader(mustMarshal(t, second))), nil)
+			},
+		},
+		{
+			Name:           "no promotions",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.PromotionsResponse{
+				Promotions: []profile.Promotion{},
+			},
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {})
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetPathValue("app", tc.App)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Promotions(w, r)
+
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+
+			var actual profile.PromotionsResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			assert.Equal(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestHTTPController_Pin(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Channel        string
+		Body           string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "pins the current profile",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test/pinned.pgo").
+					Return(&WriteCloser{}, nil)
+			},
+		},
+		{
+			Name:           "pins the stable profile",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test/pinned.pgo").
+					Return(&WriteCloser{}, nil)
+			},
+		},
+		{
+			Name:           "pins a previous version of a channel",
+			App:            "test",
+			Channel:        "canary",
+			Body:           `{"version": 2}`,
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/versions/2.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test/channels/canary/pinned.pgo").
+					Return(&WriteCloser{}, nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid version",
+			App:            "test",
+			Body:           `{"version": -1}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid body",
+			App:            "test",
+			Body:           `not json`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "profile does not exist",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(nil, blob.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.Body))
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Pin(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHTTPController_Unpin(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test/pinned.pgo").
+					Return(nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "not pinned",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test/pinned.pgo").
+					Return(blob.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.SetPathValue("app", tc.App)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Unpin(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHTTPController_Summary(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Channel        string
+		Query          string
+		ExpectedStatus int
+		ExpectedTop    int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			ExpectedTop:    profile.DefaultSummaryTop,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "success with channel and top",
+			App:            "test",
+			Channel:        "canary",
+			Query:          "?top=3",
+			ExpectedStatus: http.StatusOK,
+			ExpectedTop:    3,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "serves the pinned profile",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			ExpectedTop:    profile.DefaultSummaryTop,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid top",
+			App:            "test",
+			Query:          "?top=none",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "profile does not exist",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, mock.Anything).
+					Return(nil, blob.ErrNotExist).
+					Times(3)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/"+tc.Query, nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Summary(w, r)
+
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+
+			var actual profile.Summary
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			assert.EqualValues(t, 224780, actual.TotalSamples)
+			assert.EqualValues(t, 1, actual.Uploads)
+			assert.Len(t, actual.TopFlat, tc.ExpectedTop)
+			assert.Len(t, actual.TopCumulative, tc.ExpectedTop)
+		})
+	}
+}
+
+func TestHTTPController_Edges(t *testing.T) {
+	t.Parallel()
+
+	noStartLines := modifiedProfile(t, func(p *pprof.Profile) {
+		for _, fn := range p.Function {
+			fn.StartLine = 0
+		}
+	})
+
+	tt := []struct {
+		Name           string
+		App            string
+		Channel        string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "success with channel",
+			App:            "test",
+			Channel:        "canary",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "serves the pinned profile",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid channel name",
+			App:            "test",
+			Channel:        "edges",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "profile does not exist",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, mock.Anything).
+					Return(nil, blob.ErrNotExist).
+					Times(3)
+			},
+		},
+		{
+			Name:           "profile is missing start lines",
+			App:            "test",
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(noStartLines)}, nil)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Edges(w, r)
+
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+
+			var actual profile.EdgeReport
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			assert.EqualValues(t, 306862, actual.TotalWeight)
+			assert.EqualValues(t, profile.HotCallSiteThreshold, actual.Threshold)
+			require.NotEmpty(t, actual.Edges)
+			assert.Equal(t, "runtime.netpoll", actual.Edges[0].Caller)
+			assert.Equal(t, "runtime.kevent", actual.Edges[0].Callee)
+			assert.Greater(t, actual.Edges[len(actual.Edges)-1].CumulativePercent, float64(profile.HotCallSiteThreshold))
+		})
+	}
+}
+
+func TestHTTPController_FlameGraph(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		Channel        string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/stable.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+			},
+		},
+		{
+			Name:           "success with channel",
+			App:            "test",
+			Channel:        "canary",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/pinned.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
 			},
 		},
 		{
-			Name:           "error closing writer",
-			App:            "test-app",
-			ExpectedStatus: http.StatusInternalServerError,
-			Profile:        validProfile,
-			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+			Name:           "serves the pinned profile",
+			App:            "test",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewWriter(mock.Anything, appKeyMatcher("test-app")).
-					Return(&WriteCloser{closeError: io.EOF}, nil)
+					NewReader(mock.Anything, "test/pinned.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
 			},
 		},
 		{
-			Name:           "error on profile write",
-			App:            "test-app",
-			ExpectedStatus: http.StatusInternalServerError,
-			Profile:        validProfile,
-			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
-				blobs.EXPECT().
-					NewWriter(mock.Anything, appKeyMatcher("test-app")).
-					Return(&WriteCloser{writeError: io.EOF}, nil)
-			},
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
 		},
 		{
-			Name:           "success",
-			App:            "test-app",
-			ExpectedStatus: http.StatusCreated,
-			Profile:        validProfile,
-			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+			Name:           "profile does not exist",
+			App:            "test",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewWriter(mock.Anything, appKeyMatcher("test-app")).
-					Return(&WriteCloser{}, nil)
-
-				events.EXPECT().
-					Write(mock.Anything, uploadedEventMatcher("test-app")).
-					Return(nil)
+					NewReader(mock.Anything, mock.Anything).
+					Return(nil, blob.ErrNotExist).
+					Times(3)
 			},
 		},
 	}
@@ -108,77 +2142,128 @@ func TestHTTPController_Upload(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
 			blobs := mocks.NewMockBlobRepository(t)
-			events := mocks.NewMockEventWriter(t)
-
 			if tc.Setup != nil {
-				tc.Setup(blobs, events)
+				tc.Setup(blobs)
 			}
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.Profile))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
 			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
 
-			profile.NewHTTPController(blobs, events).Upload(w, r)
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).FlameGraph(w, r)
 
-			assert.EqualValues(t, tc.ExpectedStatus, w.Code)
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+
+			var actual profile.FlameGraph
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			assert.Equal(t, profile.FlameGraphRoot, actual.Name)
+			assert.EqualValues(t, 2247800000000, actual.Value)
+			assert.NotEmpty(t, actual.Children)
 		})
 	}
 }
 
-func TestHTTPController_Download(t *testing.T) {
+func TestHTTPController_Diff(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
-		Name            string
-		App             string
-		ExpectedStatus  int
-		ExpectedProfile []byte
-		Setup           func(blobs *mocks.MockBlobRepository)
+		Name           string
+		App            string
+		Channel        string
+		Query          string
+		ExpectedStatus int
+		ExpectedPprof  bool
+		Setup          func(blobs *mocks.MockBlobRepository)
 	}{
 		{
-			Name:           "invalid app name",
-			App:            "// not a valid string",
-			ExpectedStatus: http.StatusBadRequest,
-		},
-		{
-			Name:            "success",
-			App:             "test-app",
-			ExpectedStatus:  http.StatusOK,
-			ExpectedProfile: validProfile,
+			Name:           "success",
+			App:            "test",
+			Query:          "?base_version=1",
+			ExpectedStatus: http.StatusOK,
 			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewReader(mock.Anything, appKeyMatcher("test-app")).
+					NewReader(mock.Anything, "test/versions/1.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
 					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
 			},
 		},
 		{
-			Name:           "profile does not exist",
-			App:            "test-app",
-			ExpectedStatus: http.StatusNotFound,
+			Name:           "success with channels",
+			App:            "test",
+			Channel:        "canary",
+			Query:          "?base_channel=default&version=2&top=5",
+			ExpectedStatus: http.StatusOK,
 			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewReader(mock.Anything, appKeyMatcher("test-app")).
-					Return(nil, blob.ErrNotExist)
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/channels/canary/versions/2.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
 			},
 		},
 		{
-			Name:           "error opening writer",
-			App:            "test-app",
-			ExpectedStatus: http.StatusInternalServerError,
+			Name:           "success as pprof",
+			App:            "test",
+			Query:          "?base_version=1&format=pprof",
+			ExpectedStatus: http.StatusOK,
+			ExpectedPprof:  true,
 			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewReader(mock.Anything, appKeyMatcher("test-app")).
-					Return(nil, io.EOF)
+					NewReader(mock.Anything, "test/versions/1.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
 			},
 		},
 		{
-			Name:           "error on profile read",
-			App:            "test-app",
-			ExpectedStatus: http.StatusInternalServerError,
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid base channel name",
+			App:            "test",
+			Query:          "?base_channel=diff",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid base version",
+			App:            "test",
+			Query:          "?base_version=-1",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "invalid format",
+			App:            "test",
+			Query:          "?base_version=1&format=svg",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "same profiles",
+			App:            "test",
+			Query:          "?base_channel=default",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "base profile does not exist",
+			App:            "test",
+			Query:          "?base_version=1",
+			ExpectedStatus: http.StatusNotFound,
 			Setup: func(blobs *mocks.MockBlobRepository) {
 				blobs.EXPECT().
-					NewReader(mock.Anything, appKeyMatcher("test-app")).
-					Return(&ReadCloser{readError: io.ErrClosedPipe}, nil)
+					NewReader(mock.Anything, "test/versions/1.pgo").
+					Return(nil, blob.ErrNotExist)
 			},
 		},
 	}
@@ -191,133 +2276,167 @@ func TestHTTPController_Download(t *testing.T) {
 			}
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r := httptest.NewRequest(http.MethodGet, "/"+tc.Query, nil)
 			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
 
-			profile.NewHTTPController(blobs, nil).Download(w, r)
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Diff(w, r)
 
-			assert.Equal(t, tc.ExpectedStatus, w.Code)
-			if tc.ExpectedProfile != nil {
-				assert.Equal(t, tc.ExpectedProfile, w.Body.Bytes())
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
+				return
+			}
+
+			if tc.ExpectedPprof {
+				p, err := pprof.Parse(w.Body)
+				require.NoError(t, err)
+				assert.NotEmpty(t, p.Sample)
+				return
 			}
+
+			var actual profile.Diff
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			assert.InDelta(t, 1, actual.Similarity, 1e-9)
+			assert.Empty(t, actual.Grew)
+			assert.Empty(t, actual.Shrank)
 		})
 	}
 }
 
-func TestHTTPController_List(t *testing.T) {
+func TestHTTPController_Staged(t *testing.T) {
 	t.Parallel()
 
+	objects := []blob.Object{
+		{Key: "test/staging/1730477575747397000", Size: 200},
+		{Key: "test/staging/1730477575747396000", Size: 100},
+		{Key: "test/staging/invalid"},
+		{Key: "test/channels/prod/staging/1730477575747398000", Size: 300},
+	}
+
 	tt := []struct {
 		Name           string
+		App            string
+		Channel        string
 		ExpectedStatus int
-		ExpectsError   bool
-		Expected       profile.ListResponse
-		Setup          func(blobs *mocks.MockBlobRepository)
+		Expected       profile.StagedResponse
 	}{
 		{
-			Name:           "success",
+			Name:           "lists oldest first",
+			App:            "test",
 			ExpectedStatus: http.StatusOK,
-			Expected: profile.ListResponse{
-				Profiles: []profile.Profile{
+			Expected: profile.StagedResponse{
+				Uploads: []profile.StagedUpload{
 					{
-						Key:          "test",
-						Size:         1000,
-						LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						ID:         "1730477575747396000",
+						Key:        "test/staging/1730477575747396000",
+						Size:       100,
+						UploadedAt: time.Unix(0, 1730477575747396000).UTC(),
+					},
+					{
+						ID:         "1730477575747397000",
+						Key:        "test/staging/1730477575747397000",
+						Size:       200,
+						UploadedAt: time.Unix(0, 1730477575747397000).UTC(),
 					},
 				},
 			},
-			Setup: func(blobs *mocks.MockBlobRepository) {
-				blobs.EXPECT().
-					List(mock.Anything, mock.Anything).
-					Return(func(yield func(blob.Object, error) bool) {
-						yield(blob.Object{
-							Key:          "test/default.pgo",
-							Size:         1000,
-							LastModified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-						}, nil)
-					})
-			},
 		},
 		{
-			Name:           "returns errors",
-			ExpectedStatus: http.StatusInternalServerError,
-			ExpectsError:   true,
-			Setup: func(blobs *mocks.MockBlobRepository) {
-				blobs.EXPECT().
-					List(mock.Anything, mock.Anything).
-					Return(func(yield func(blob.Object, error) bool) {
-						yield(blob.Object{}, io.ErrClosedPipe)
-					})
+			Name:           "lists a channel",
+			App:            "test",
+			Channel:        "prod",
+			ExpectedStatus: http.StatusOK,
+			Expected: profile.StagedResponse{
+				Uploads: []profile.StagedUpload{
+					{
+						ID:         "1730477575747398000",
+						Key:        "test/channels/prod/staging/1730477575747398000",
+						Size:       300,
+						UploadedAt: time.Unix(0, 1730477575747398000).UTC(),
+					},
+				},
 			},
 		},
+		{
+			Name:           "invalid app name",
+			App:            "// invalid",
+			ExpectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
 			blobs := mocks.NewMockBlobRepository(t)
-			if tc.Setup != nil {
-				tc.Setup(blobs)
+			if tc.ExpectedStatus == http.StatusOK {
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					RunAndReturn(testutil.ListObjects(objects...))
 			}
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
 
-			profile.NewHTTPController(blobs, nil).List(w, r)
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Staged(w, r)
 
-			assert.Equal(t, tc.ExpectedStatus, w.Code)
-			decoder := json.NewDecoder(w.Body)
-			if tc.ExpectsError {
-				var apiErr api.Error
-				require.NoError(t, decoder.Decode(&apiErr))
-				assert.EqualValues(t, tc.ExpectedStatus, apiErr.Code)
+			require.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedStatus != http.StatusOK {
 				return
 			}
 
-			var actual profile.ListResponse
-			require.NoError(t, decoder.Decode(&actual))
+			var actual profile.StagedResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
 			assert.EqualValues(t, tc.Expected, actual)
 		})
 	}
 }
 
-func TestHTTPController_Delete(t *testing.T) {
+func TestHTTPController_Requeue(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
 		Name           string
 		App            string
+		Channel        string
+		ID             string
 		ExpectedStatus int
-		ExpectsError   bool
 		Setup          func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
 	}{
 		{
 			Name:           "success",
-			ExpectedStatus: http.StatusOK,
 			App:            "test",
+			Channel:        "prod",
+			ID:             "1730477575747397000",
+			ExpectedStatus: http.StatusOK,
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
 				blobs.EXPECT().
-					Exists(mock.Anything, "test/default.pgo").
+					Exists(mock.Anything, "test/channels/prod/staging/1730477575747397000").
 					Return(true, nil)
 
 				events.EXPECT().
-					Write(mock.Anything, deletedEventMatcher("test")).
+					Write(mock.Anything, profile.UploadedEvent{
+						App:        "test",
+						Channel:    "prod",
+						ProfileKey: "test/channels/prod/staging/1730477575747397000",
+					}).
 					Return(nil)
 			},
 		},
 		{
-			Name:           "invalid app name",
-			App:            "// invalid",
+			Name:           "invalid id",
+			App:            "test",
+			ID:             "0123",
 			ExpectedStatus: http.StatusBadRequest,
-			ExpectsError:   true,
 		},
 		{
-			Name:           "does not exist",
+			Name:           "not staged",
 			App:            "test",
+			ID:             "1730477575747397000",
 			ExpectedStatus: http.StatusNotFound,
-			ExpectsError:   true,
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
 				blobs.EXPECT().
-					Exists(mock.Anything, "test/default.pgo").
+					Exists(mock.Anything, "test/staging/1730477575747397000").
 					Return(false, nil)
 			},
 		},
@@ -332,19 +2451,73 @@ func TestHTTPController_Delete(t *testing.T) {
 			}
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
 			r.SetPathValue("app", tc.App)
+			r.SetPathValue("channel", tc.Channel)
+			r.SetPathValue("id", tc.ID)
 
-			profile.NewHTTPController(blobs, events).Delete(w, r)
+			profile.NewHTTPController(blobs, events, profile.UploadConfig{}).Requeue(w, r)
 
 			assert.Equal(t, tc.ExpectedStatus, w.Code)
-			decoder := json.NewDecoder(w.Body)
-			if tc.ExpectsError {
-				var apiErr api.Error
-				require.NoError(t, decoder.Decode(&apiErr))
-				assert.EqualValues(t, tc.ExpectedStatus, apiErr.Code)
-				return
+		})
+	}
+}
+
+func TestHTTPController_Discard(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name           string
+		App            string
+		ID             string
+		ExpectedStatus int
+		Setup          func(blobs *mocks.MockBlobRepository)
+	}{
+		{
+			Name:           "success",
+			App:            "test",
+			ID:             "1730477575747397000",
+			ExpectedStatus: http.StatusOK,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test/staging/1730477575747397000").
+					Return(nil)
+			},
+		},
+		{
+			Name:           "invalid id",
+			App:            "test",
+			ID:             "../default.pgo",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "not staged",
+			App:            "test",
+			ID:             "1730477575747397000",
+			ExpectedStatus: http.StatusNotFound,
+			Setup: func(blobs *mocks.MockBlobRepository) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test/staging/1730477575747397000").
+					Return(blob.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			blobs := mocks.NewMockBlobRepository(t)
+			if tc.Setup != nil {
+				tc.Setup(blobs)
 			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "/", nil)
+			r.SetPathValue("app", tc.App)
+			r.SetPathValue("id", tc.ID)
+
+			profile.NewHTTPController(blobs, nil, profile.UploadConfig{}).Discard(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
 		})
 	}
 }
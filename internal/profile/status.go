@@ -0,0 +1,43 @@
+package profile
+
+import (
+	"net/http"
+
+	"github.com/davidsbond/autopgo/internal/api"
+)
+
+type (
+	// The ScraperHTTPController type is used to handle inbound requests querying the state of a Scraper.
+	ScraperHTTPController struct {
+		scraper *Scraper
+	}
+)
+
+// NewScraperHTTPController returns a new instance of the ScraperHTTPController type that serves the status of
+// targets discovered by the given Scraper.
+func NewScraperHTTPController(scraper *Scraper) *ScraperHTTPController {
+	return &ScraperHTTPController{
+		scraper: scraper,
+	}
+}
+
+// Register HTTP endpoints onto the http.ServeMux.
+func (h *ScraperHTTPController) Register(m *http.ServeMux) {
+	m.HandleFunc("GET /api/targets", h.ListTargets)
+}
+
+type (
+	// The ListTargetsResponse type is the response given when listing the targets known to the scraper.
+	ListTargetsResponse struct {
+		// The targets discovered during the most recent scrape.
+		Targets []TargetStatus `json:"targets"`
+	}
+)
+
+// ListTargets handles an inbound HTTP request to list all targets discovered by the scraper, along with the outcome
+// of the most recent attempt to scrape each of them.
+func (h *ScraperHTTPController) ListTargets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	api.Respond(ctx, w, http.StatusOK, ListTargetsResponse{Targets: h.scraper.Targets()})
+}
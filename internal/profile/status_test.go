@@ -0,0 +1,117 @@
+package profile_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/target"
+)
+
+func TestScraperHTTPController_ListTargets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no targets", func(t *testing.T) {
+		client := mocks.NewMockClient(t)
+		scraper := profile.NewScraper(client, profile.ScrapeConfig{})
+
+		actual := listTargets(t, scraper)
+		assert.Empty(t, actual.Targets)
+	})
+
+	t.Run("discovered & scraped targets", func(t *testing.T) {
+		const app = "scraper-status"
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		client := mocks.NewMockClient(t)
+		source := mocks.NewMockTargetSource(t)
+		source.EXPECT().Name().Return("test")
+
+		scraped := []target.Target{
+			{Address: "http://localhost:8080", Labels: map[string]string{"pod": "a"}},
+			{Address: "http://localhost:8081", Labels: map[string]string{"pod": "b"}},
+		}
+
+		source.EXPECT().
+			List(mock.Anything).
+			Return(scraped, nil).
+			Once()
+
+		// Cancelling the context during the second discovery prevents the new target from being sampled.
+		source.EXPECT().
+			List(mock.Anything).
+			Run(func(context.Context) { cancel() }).
+			Return(append(scraped, target.Target{Address: "http://localhost:8082"}), nil).
+			Once()
+
+		client.EXPECT().
+			Profile(mock.Anything, "http://localhost:8080/debug/pprof/profile", mock.Anything).
+			Return(validProfile, nil)
+
+		client.EXPECT().
+			Profile(mock.Anything, "http://localhost:8081/debug/pprof/profile", mock.Anything).
+			Return(nil, io.EOF)
+
+		client.EXPECT().
+			UploadChannel(mock.Anything, app, "", mock.Anything).
+			Return(nil)
+
+		scraper := profile.NewScraper(client, profile.ScrapeConfig{
+			SampleSize:      2,
+			App:             app,
+			ScrapeFrequency: 10 * time.Millisecond,
+		})
+
+		require.ErrorIs(t, scraper.Scrape(ctx, source), context.Canceled)
+
+		actual := listTargets(t, scraper)
+		require.Len(t, actual.Targets, 3)
+
+		succeeded := actual.Targets[0]
+		assert.EqualValues(t, "http://localhost:8080", succeeded.Address)
+		assert.EqualValues(t, map[string]string{"pod": "a"}, succeeded.Labels)
+		assert.False(t, succeeded.LastScrape.IsZero())
+		assert.NotZero(t, succeeded.BytesUploaded)
+		assert.Empty(t, succeeded.LastError)
+
+		failed := actual.Targets[1]
+		assert.EqualValues(t, "http://localhost:8081", failed.Address)
+		assert.EqualValues(t, map[string]string{"pod": "b"}, failed.Labels)
+		assert.False(t, failed.LastScrape.IsZero())
+		assert.Zero(t, failed.BytesUploaded)
+		assert.NotEmpty(t, failed.LastError)
+
+		discovered := actual.Targets[2]
+		assert.EqualValues(t, "http://localhost:8082", discovered.Address)
+		assert.True(t, discovered.LastScrape.IsZero())
+		assert.Empty(t, discovered.LastError)
+	})
+}
+
+func listTargets(t *testing.T, scraper *profile.Scraper) profile.ListTargetsResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/targets", nil)
+
+	profile.NewScraperHTTPController(scraper).ListTargets(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var actual profile.ListTargetsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+
+	return actual
+}
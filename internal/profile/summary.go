@@ -0,0 +1,196 @@
+package profile
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+type (
+	// The Summary type describes the contents of a merged profile.
+	Summary struct {
+		// The sample types recorded within the profile, in type/unit format.
+		SampleTypes []string `json:"sampleTypes"`
+		// The total number of samples within the profile.
+		TotalSamples int64 `json:"totalSamples"`
+		// The total CPU time recorded within the profile, in nanoseconds.
+		CPUTime time.Duration `json:"cpuTime"`
+		// The number of functions, locations & mappings within the profile.
+		Functions int `json:"functions"`
+		Locations int `json:"locations"`
+		Mappings  int `json:"mappings"`
+		// The time range covered by the profiles that have been merged.
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+		// The number of uploaded profiles that have been merged.
+		Uploads int `json:"uploads"`
+		// The functions with the highest flat & cumulative CPU time, in descending order.
+		TopFlat       []FunctionSummary `json:"topFlat"`
+		TopCumulative []FunctionSummary `json:"topCumulative"`
+	}
+
+	// The FunctionSummary type describes the CPU time spent within a single function.
+	FunctionSummary struct {
+		// The name of the function.
+		Name string `json:"name"`
+		// The CPU time spent within the function itself, in nanoseconds.
+		Flat time.Duration `json:"flat"`
+		// The CPU time spent within the function and the functions it calls, in nanoseconds.
+		Cumulative time.Duration `json:"cumulative"`
+	}
+)
+
+// Summarize returns a Summary of the CPU profile, including the top functions by flat & cumulative CPU time. Returns
+// ErrNotCPUProfile if the profile does not contain both samples/count and cpu/nanoseconds sample types.
+func Summarize(p *profile.Profile, top int) (Summary, error) {
+	samples, cpu, err := CPUUsage(p)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	summary := Summary{
+		TotalSamples: samples,
+		CPUTime:      cpu,
+		Functions:    len(p.Function),
+		Locations:    len(p.Location),
+		Mappings:     len(p.Mapping),
+		Uploads:      uploads(p),
+		End:          end(p),
+	}
+
+	if p.TimeNanos > 0 {
+		summary.Start = time.Unix(0, p.TimeNanos).UTC()
+	}
+
+	for _, st := range p.SampleType {
+		summary.SampleTypes = append(summary.SampleTypes, st.Type+"/"+st.Unit)
+	}
+
+	functions := functionSummaries(p)
+
+	slices.SortStableFunc(functions, func(a, b FunctionSummary) int {
+		return cmp.Or(cmp.Compare(b.Flat, a.Flat), cmp.Compare(a.Name, b.Name))
+	})
+	summary.TopFlat = slices.Clone(functions[:min(top, len(functions))])
+
+	slices.SortStableFunc(functions, func(a, b FunctionSummary) int {
+		return cmp.Or(cmp.Compare(b.Cumulative, a.Cumulative), cmp.Compare(a.Name, b.Name))
+	})
+	summary.TopCumulative = slices.Clone(functions[:min(top, len(functions))])
+
+	return summary, nil
+}
+
+// functionSummaries returns the flat & cumulative CPU time of every function within the profile's samples.
+func functionSummaries(p *profile.Profile) []FunctionSummary {
+	cpuIndex := slices.IndexFunc(p.SampleType, func(st *profile.ValueType) bool {
+		return st.Type == "cpu" && st.Unit == "nanoseconds"
+	})
+
+	totals := make(map[string]*FunctionSummary)
+	total := func(name string) *FunctionSummary {
+		if fn, ok := totals[name]; ok {
+			return fn
+		}
+
+		fn := &FunctionSummary{Name: name}
+		totals[name] = fn
+		return fn
+	}
+
+	for _, sample := range p.Sample {
+		value := time.Duration(sample.Value[cpuIndex])
+
+		// Functions are only counted once per sample for cumulative time, as recursive functions appear within the
+		// same stack many times.
+		seen := make(map[string]bool)
+		for i, location := range sample.Location {
+			for j, line := range location.Line {
+				if line.Function == nil {
+					continue
+				}
+
+				name := line.Function.Name
+
+				// The first line of the first location is the leaf of the stack, where inlined functions precede
+				// the functions they were inlined into.
+				if i == 0 && j == 0 {
+					total(name).Flat += value
+				}
+
+				if !seen[name] {
+					seen[name] = true
+					total(name).Cumulative += value
+				}
+			}
+		}
+	}
+
+	functions := make([]FunctionSummary, 0, len(totals))
+	for _, fn := range totals {
+		functions = append(functions, *fn)
+	}
+
+	return functions
+}
+
+// Prefixes of the comments used to store metadata within merged profiles.
+const (
+	commentUploads = "autopgo.uploads="
+	commentEnd     = "autopgo.end="
+)
+
+// uploads returns the number of uploaded profiles that have been merged into the profile. Profiles without this
+// metadata are treated as a single upload.
+func uploads(p *profile.Profile) int {
+	for _, comment := range p.Comments {
+		value, ok := strings.CutPrefix(comment, commentUploads)
+		if !ok {
+			continue
+		}
+
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+
+	return 1
+}
+
+// end returns the time at which the most recent of the profiles merged into the profile ended. Profiles without this
+// metadata are assumed to end after their duration, which is only accurate for profiles that have not been merged.
+func end(p *profile.Profile) time.Time {
+	for _, comment := range p.Comments {
+		value, ok := strings.CutPrefix(comment, commentEnd)
+		if !ok {
+			continue
+		}
+
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			return time.Unix(0, n).UTC()
+		}
+	}
+
+	if p.TimeNanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, p.TimeNanos+p.DurationNanos).UTC()
+}
+
+// setMetadata replaces the metadata stored within the profile's comments, describing the number of uploaded profiles
+// that have been merged and the time at which the most recent of them ended.
+func setMetadata(p *profile.Profile, uploads int, end time.Time) {
+	p.Comments = slices.DeleteFunc(p.Comments, func(comment string) bool {
+		return strings.HasPrefix(comment, commentUploads) || strings.HasPrefix(comment, commentEnd)
+	})
+
+	p.Comments = append(p.Comments, commentUploads+strconv.Itoa(uploads))
+	if !end.IsZero() {
+		p.Comments = append(p.Comments, commentEnd+strconv.FormatInt(end.UnixNano(), 10))
+	}
+}
@@ -0,0 +1,123 @@
+package profile_test
+
+import (
+	"testing"
+	"time"
+
+	pprof "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/profile"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mainFn := &pprof.Function{ID: 1, Name: "main.main"}
+	fooFn := &pprof.Function{ID: 2, Name: "main.foo"}
+	barFn := &pprof.Function{ID: 3, Name: "main.bar"}
+
+	mainLoc := &pprof.Location{ID: 1, Line: []pprof.Line{{Function: mainFn}}}
+	fooLoc := &pprof.Location{ID: 2, Line: []pprof.Line{{Function: fooFn}}}
+	barLoc := &pprof.Location{ID: 3, Line: []pprof.Line{{Function: barFn}}}
+
+	cpuProfile := &pprof.Profile{
+		SampleType: []*pprof.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		Sample: []*pprof.Sample{
+			{Location: []*pprof.Location{barLoc, fooLoc, mainLoc}, Value: []int64{3, 30}},
+			{Location: []*pprof.Location{fooLoc, mainLoc}, Value: []int64{2, 20}},
+			{Location: []*pprof.Location{mainLoc}, Value: []int64{1, 10}},
+			// Recursive functions only count towards their cumulative time once per sample.
+			{Location: []*pprof.Location{fooLoc, fooLoc, mainLoc}, Value: []int64{1, 5}},
+		},
+		Location:      []*pprof.Location{mainLoc, fooLoc, barLoc},
+		Function:      []*pprof.Function{mainFn, fooFn, barFn},
+		Mapping:       []*pprof.Mapping{{ID: 1}},
+		TimeNanos:     start.UnixNano(),
+		DurationNanos: int64(time.Minute),
+	}
+
+	merged := cpuProfile.Copy()
+	merged.Comments = []string{
+		"autopgo.uploads=3",
+		"autopgo.end=1704070800000000000",
+	}
+
+	tt := []struct {
+		Name          string
+		Profile       *pprof.Profile
+		Top           int
+		Expected      profile.Summary
+		ExpectedError error
+	}{
+		{
+			Name:    "should summarise an uploaded profile",
+			Profile: cpuProfile,
+			Top:     2,
+			Expected: profile.Summary{
+				SampleTypes:  []string{"samples/count", "cpu/nanoseconds"},
+				TotalSamples: 7,
+				CPUTime:      65,
+				Functions:    3,
+				Locations:    3,
+				Mappings:     1,
+				Start:        start,
+				End:          start.Add(time.Minute),
+				Uploads:      1,
+				TopFlat: []profile.FunctionSummary{
+					{Name: "main.bar", Flat: 30, Cumulative: 30},
+					{Name: "main.foo", Flat: 25, Cumulative: 55},
+				},
+				TopCumulative: []profile.FunctionSummary{
+					{Name: "main.main", Flat: 10, Cumulative: 65},
+					{Name: "main.foo", Flat: 25, Cumulative: 55},
+				},
+			},
+		},
+		{
+			Name:    "should use metadata of a merged profile",
+			Profile: merged,
+			Top:     1,
+			Expected: profile.Summary{
+				SampleTypes:  []string{"samples/count", "cpu/nanoseconds"},
+				TotalSamples: 7,
+				CPUTime:      65,
+				Functions:    3,
+				Locations:    3,
+				Mappings:     1,
+				Start:        start,
+				End:          start.Add(time.Hour),
+				Uploads:      3,
+				TopFlat: []profile.FunctionSummary{
+					{Name: "main.bar", Flat: 30, Cumulative: 30},
+				},
+				TopCumulative: []profile.FunctionSummary{
+					{Name: "main.main", Flat: 10, Cumulative: 65},
+				},
+			},
+		},
+		{
+			Name: "should return an error for non-cpu profiles",
+			Profile: &pprof.Profile{
+				SampleType: []*pprof.ValueType{
+					{Type: "alloc_objects", Unit: "count"},
+				},
+			},
+			ExpectedError: profile.ErrNotCPUProfile,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := profile.Summarize(tc.Profile, tc.Top)
+			require.ErrorIs(t, err, tc.ExpectedError)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
@@ -9,8 +9,13 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/pprof/profile"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/davidsbond/autopgo/internal/blob"
 	"github.com/davidsbond/autopgo/internal/closers"
@@ -25,6 +30,33 @@ type (
 		writer  EventWriter
 		blobs   BlobRepository
 		pruning []PruneConfig
+		retain  int
+	}
+
+	// The ReconcileConfig type contains parameters used to reconcile the state of blob storage with the events
+	// that have been handled.
+	ReconcileConfig struct {
+		// How often to reconcile.
+		Interval time.Duration
+		// How long a profile may be staged before it is assumed that the event announcing its upload or merge was
+		// lost.
+		Threshold time.Duration
+		// How long records of uploads are kept to detect duplicates, which should match the DedupWindow of the
+		// server's UploadConfig. Records are kept indefinitely when zero.
+		DedupWindow time.Duration
+		// How many times a staged profile may be requeued before it is abandoned, leaving it to be requeued or
+		// discarded manually. Profiles are requeued indefinitely when zero.
+		MaxAttempts int
+	}
+
+	// The requeueRecord type is stored in blob storage for each staged profile requeued by reconciliation.
+	requeueRecord struct {
+		// How many times the profile has been requeued.
+		Attempts int `json:"attempts"`
+		// When the profile was last requeued.
+		RequeuedAt time.Time `json:"requeuedAt"`
+		// Whether the profile has been abandoned after too many attempts.
+		Abandoned bool `json:"abandoned,omitempty"`
 	}
 
 	// The PruneConfig type represents a collection of pruning rules for a specific application.
@@ -46,27 +78,43 @@ type (
 
 // NewWorker returns a new instance of the Worker type that will read and write profile data via the BlobRepository
 // implementation, read events via the EventReader implementation and publish events via the EventWriter implementation.
-func NewWorker(blobs BlobRepository, writer EventWriter, prune []PruneConfig) *Worker {
+// The retain parameter determines how many generations of each merged profile are kept, generations are not kept
+// when it is zero.
+func NewWorker(blobs BlobRepository, writer EventWriter, prune []PruneConfig, retain int) *Worker {
 	return &Worker{
 		blobs:   blobs,
 		writer:  writer,
 		pruning: prune,
+		retain:  retain,
 	}
 }
 
 // HandleEvent is an event.Handler implementation that is used to handle inbound profile events and perform profile
 // merge and deletion.
 func (w *Worker) HandleEvent(ctx context.Context, evt event.Envelope) error {
+	var err error
 	switch evt.Type {
 	case EventTypeUploaded:
-		return w.handleEventTypeUploaded(ctx, evt)
+		err = w.handleEventTypeUploaded(ctx, evt)
 	case EventTypeMerged:
-		return w.handleEventTypeMerged(ctx, evt)
+		err = w.handleEventTypeMerged(ctx, evt)
 	case EventTypeDeleted:
-		return w.handleEventTypeDeleted(ctx, evt)
+		err = w.handleEventTypeDeleted(ctx, evt)
+	case EventTypeRolledBack:
+		err = w.handleEventTypeRolledBack(ctx, evt)
+	case EventTypePromoted:
+		err = w.handleEventTypePromoted(ctx, evt)
 	default:
 		return nil
 	}
+
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeFailure
+	}
+
+	eventsHandled.WithLabelValues(evt.Type, outcome).Inc()
+	return err
 }
 
 func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope) error {
@@ -78,8 +126,27 @@ func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope
 	log := logger.FromContext(ctx).With(
 		slog.String("profile.key", payload.ProfileKey),
 		slog.String("profile.app", payload.App),
+		slog.String("profile.channel", channelName(payload.Channel)),
+	)
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("profile.key", payload.ProfileKey),
+		attribute.String("profile.app", payload.App),
+		attribute.String("profile.channel", channelName(payload.Channel)),
 	)
 
+	// A record of the merge only exists here if the event announcing it was lost or failed to be published, merging
+	// the profile again would count its samples twice.
+	recordKey := MergeRecordKey(payload.App, payload.Channel, payload.ProfileKey)
+	alreadyMerged, err := w.blobs.Exists(ctx, recordKey)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to check merge record at %s: %w", recordKey, err)
+	case alreadyMerged:
+		log.DebugContext(ctx, "profile has already been merged, removing upload")
+		return w.removeStaged(ctx, payload.App, payload.Channel, payload.ProfileKey)
+	}
+
 	newProfileReader, err := w.blobs.NewReader(ctx, payload.ProfileKey)
 	switch {
 	case errors.Is(err, blob.ErrNotExist):
@@ -89,7 +156,7 @@ func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope
 	}
 	defer closers.Close(ctx, newProfileReader)
 
-	basePath := path.Join(payload.App, "default.pgo")
+	basePath := MergedKey(payload.App, payload.Channel)
 	baseProfileReader, err := w.blobs.NewReader(ctx, basePath)
 	switch {
 	case errors.Is(err, blob.ErrNotExist):
@@ -107,6 +174,7 @@ func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope
 	}
 
 	profiles := []*profile.Profile{newProfile}
+	uploadCount, uploadEnd := uploads(newProfile), end(newProfile)
 	if baseProfileReader != nil {
 		baseProfile, err := profile.Parse(baseProfileReader)
 		if err != nil {
@@ -115,18 +183,30 @@ func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope
 
 		log.DebugContext(ctx, "merging upload with base profile")
 		profiles = append(profiles, baseProfile)
+
+		uploadCount += uploads(baseProfile)
+		if baseEnd := end(baseProfile); baseEnd.After(uploadEnd) {
+			uploadEnd = baseEnd
+		}
 	}
 
+	start := time.Now()
 	merged, err := profile.Merge(profiles)
 	if err != nil {
 		return fmt.Errorf("failed to merge profiles %s and %s: %w", payload.ProfileKey, basePath, err)
 	}
 
+	mergeDuration.Observe(time.Since(start).Seconds())
+
+	// Merged profiles carry the metadata used to summarise them, so that it follows the profile into its generations.
+	setMetadata(merged, uploadCount, uploadEnd)
+
 	for _, prune := range w.pruning {
 		if prune.App != payload.App {
 			continue
 		}
 
+		start = time.Now()
 		for _, rule := range prune.Rules {
 			attrs := make([]any, 0)
 			if rule.Drop != nil {
@@ -141,26 +221,32 @@ func (w *Worker) handleEventTypeUploaded(ctx context.Context, evt event.Envelope
 			merged.Prune(rule.Drop, rule.Keep)
 		}
 
+		pruneDuration.Observe(time.Since(start).Seconds())
 		break
 	}
 
-	profileWriter, err := w.blobs.NewWriter(ctx, basePath)
+	n, err := w.writeProfile(ctx, basePath, merged)
 	if err != nil {
-		return fmt.Errorf("failed to open writer: %w", err)
+		return fmt.Errorf("failed to write merged profile: %w", err)
 	}
 
-	if err = merged.Write(profileWriter); err != nil {
-		return fmt.Errorf("failed to write merged profile: %w", err)
+	mergedProfileBytes.WithLabelValues(payload.App, channelName(payload.Channel)).Set(float64(n))
+
+	version, err := w.saveVersion(ctx, payload.App, payload.Channel, merged)
+	if err != nil {
+		return fmt.Errorf("failed to save profile version: %w", err)
 	}
 
-	if err = profileWriter.Close(); err != nil {
-		return fmt.Errorf("failed to write merged profile: %w", err)
+	if err = w.writeMergeRecord(ctx, recordKey); err != nil {
+		return fmt.Errorf("failed to record merge: %w", err)
 	}
 
 	return w.writer.Write(ctx, MergedEvent{
 		App:        payload.App,
+		Channel:    payload.Channel,
 		ProfileKey: payload.ProfileKey,
 		MergedKey:  basePath,
+		Version:    version,
 	})
 }
 
@@ -170,40 +256,500 @@ func (w *Worker) handleEventTypeMerged(ctx context.Context, evt event.Envelope)
 		return fmt.Errorf("invalid payload: %w", err)
 	}
 
-	err = w.blobs.Delete(ctx, payload.ProfileKey)
+	return w.removeStaged(ctx, payload.App, payload.Channel, payload.ProfileKey)
+}
+
+// removeStaged deletes a staged profile that has been merged, followed by the records of its requeues and merge. The
+// merge record is kept until the staged profile is gone so that it is never merged twice.
+func (w *Worker) removeStaged(ctx context.Context, app, channel, stagingKey string) error {
+	keys := []string{
+		stagingKey,
+		RequeueRecordKey(app, channel, stagingKey),
+		MergeRecordKey(app, channel, stagingKey),
+	}
+
+	for _, key := range keys {
+		err := w.blobs.Delete(ctx, key)
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) writeMergeRecord(ctx context.Context, key string) error {
+	writer, err := w.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to open writer: %w", err)
+	}
+
+	return writer.Close()
+}
+
+func (w *Worker) handleEventTypeDeleted(ctx context.Context, evt event.Envelope) error {
+	payload, err := event.Unmarshal[DeletedEvent](evt)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	// An empty channel deletes the entire application, including all of its channels.
+	if payload.Channel != DefaultChannel {
+		return w.deletePrefix(ctx, channelPrefix(payload.App, payload.Channel))
+	}
+
+	// The default channel is stored at the root of the application, alongside the other channels. Its directories are
+	// deleted one at a time so that the objects of the other channels are never listed.
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{
+		Prefix:    channelPrefix(payload.App, payload.Channel),
+		Delimiter: "/",
+	}) {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case object.Key == channelsPrefix(payload.App):
+			continue
+		case object.IsDir:
+			err = w.deletePrefix(ctx, object.Key)
+		default:
+			err = w.deleteObject(ctx, object.Key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deletePrefix deletes every object whose key begins with the prefix.
+func (w *Worker) deletePrefix(ctx context.Context, prefix string) error {
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{Prefix: prefix}) {
+		if err != nil {
+			return err
+		}
+
+		if err = w.deleteObject(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) deleteObject(ctx context.Context, key string) error {
+	err := w.blobs.Delete(ctx, key)
+	if err != nil && !errors.Is(err, blob.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (w *Worker) handleEventTypeRolledBack(ctx context.Context, evt event.Envelope) error {
+	payload, err := event.Unmarshal[RolledBackEvent](evt)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	log := logger.FromContext(ctx).With(
+		slog.String("profile.app", payload.App),
+		slog.String("profile.channel", channelName(payload.Channel)),
+		slog.Int("profile.version", payload.Version),
+	)
+
+	key := VersionKey(payload.App, payload.Channel, payload.Version)
+	reader, err := w.blobs.NewReader(ctx, key)
 	switch {
 	case errors.Is(err, blob.ErrNotExist):
+		log.WarnContext(ctx, "profile version does not exist, skipping rollback")
 		return nil
 	case err != nil:
-		return fmt.Errorf("failed to delete profile at %s: %w", payload.ProfileKey, err)
-	default:
-		return nil
+		return fmt.Errorf("failed to read profile at %s: %w", key, err)
+	}
+	defer closers.Close(ctx, reader)
+
+	restored, err := profile.Parse(reader)
+	if err != nil {
+		return fmt.Errorf("failed to parse profile at %s: %w", key, err)
+	}
+
+	n, err := w.writeProfile(ctx, MergedKey(payload.App, payload.Channel), restored)
+	if err != nil {
+		return fmt.Errorf("failed to write restored profile: %w", err)
+	}
+
+	mergedProfileBytes.WithLabelValues(payload.App, channelName(payload.Channel)).Set(float64(n))
+
+	// The restored profile is saved as a new generation so that the rollback itself can be undone.
+	version, err := w.saveVersion(ctx, payload.App, payload.Channel, restored)
+	if err != nil {
+		return fmt.Errorf("failed to save profile version: %w", err)
 	}
+
+	log.With(slog.Int("profile.new_version", version)).InfoContext(ctx, "rolled back profile")
+	return nil
 }
 
-func (w *Worker) handleEventTypeDeleted(ctx context.Context, evt event.Envelope) error {
-	payload, err := event.Unmarshal[DeletedEvent](evt)
+func (w *Worker) handleEventTypePromoted(ctx context.Context, evt event.Envelope) error {
+	payload, err := event.Unmarshal[PromotedEvent](evt)
 	if err != nil {
 		return fmt.Errorf("invalid payload: %w", err)
 	}
 
-	for object, err := range w.blobs.List(ctx, IsApplication(payload.App)) {
+	log := logger.FromContext(ctx).With(
+		slog.String("profile.app", payload.App),
+		slog.String("profile.channel", channelName(payload.Channel)),
+		slog.Int("profile.version", payload.Version),
+		slog.String("profile.promoted_by", payload.PromotedBy),
+	)
+
+	// The record is keyed by the time the promotion was requested and written once the promotion is complete, so a
+	// redelivered event whose record exists has already been performed and must not overwrite a later promotion.
+	recordKey := PromotionKey(payload.App, payload.PromotedAt)
+	promoted, err := w.blobs.Exists(ctx, recordKey)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to check promotion record at %s: %w", recordKey, err)
+	case promoted:
+		log.DebugContext(ctx, "profile has already been promoted")
+		return nil
+	}
+
+	key := VersionKey(payload.App, payload.Channel, payload.Version)
+	reader, err := w.blobs.NewReader(ctx, key)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		log.WarnContext(ctx, "profile version does not exist, skipping promotion")
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read profile at %s: %w", key, err)
+	}
+	defer closers.Close(ctx, reader)
+
+	stable, err := profile.Parse(reader)
+	if err != nil {
+		return fmt.Errorf("failed to parse profile at %s: %w", key, err)
+	}
+
+	// The stable profile has its own key that uploads are never merged into, so it only changes via promotion.
+	if _, err = w.writeProfile(ctx, StableKey(payload.App), stable); err != nil {
+		return fmt.Errorf("failed to write promoted profile: %w", err)
+	}
+
+	promotion := Promotion{
+		Channel:    payload.Channel,
+		Version:    payload.Version,
+		PromotedBy: payload.PromotedBy,
+		PromotedAt: payload.PromotedAt,
+	}
+
+	if err = w.writePromotion(ctx, PromotionKey(payload.App, payload.PromotedAt), promotion); err != nil {
+		return fmt.Errorf("failed to record promotion: %w", err)
+	}
+
+	log.InfoContext(ctx, "promoted profile")
+	return nil
+}
+
+// Reconcile staged profiles every interval until the provided context is cancelled, see ReconcileStaged. Failures
+// are logged and retried on the next interval.
+func (w *Worker) Reconcile(ctx context.Context, config ReconcileConfig) error {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			err := w.ReconcileStaged(ctx, config)
+			if err != nil && ctx.Err() == nil {
+				logger.FromContext(ctx).With(slog.String("error", err.Error())).ErrorContext(ctx, "failed to reconcile staged profiles")
+			}
+		}
+	}
+}
+
+// ReconcileStaged searches every channel of every application for profiles that have been staged for longer than the
+// threshold. Those that have already been merged are deleted, while the upload events of the remainder are published
+// again so that they are merged. Records of merges whose staged profiles no longer exist, and records of uploads
+// older than the deduplication window, are also deleted.
+func (w *Worker) ReconcileStaged(ctx context.Context, config ReconcileConfig) error {
+	for ch, err := range listChannels(ctx, w.blobs, "", "") {
+		if err != nil {
+			return err
+		}
+
+		if err = w.reconcileChannel(ctx, ch.app, ch.channel, config); err != nil {
+			return fmt.Errorf("failed to reconcile %s/%s: %w", ch.app, channelName(ch.channel), err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) reconcileChannel(ctx context.Context, app, channel string, config ReconcileConfig) error {
+	cutoff := time.Now().Add(-config.Threshold)
+	log := logger.FromContext(ctx).With(
+		slog.String("profile.app", app),
+		slog.String("profile.channel", channelName(channel)),
+	)
+
+	staged := make(map[string]bool)
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{
+		Prefix: stagingPrefix(app, channel),
+		Filter: IsStaged(app, channel),
+	}) {
 		if err != nil {
 			return err
 		}
 
+		id := path.Base(object.Key)
+		staged[id] = true
+
+		if uploadedAt, _ := ParseStagingID(id); uploadedAt.After(cutoff) {
+			continue
+		}
+
+		recordKey := MergeRecordKey(app, channel, object.Key)
+		merged, err := w.blobs.Exists(ctx, recordKey)
+		if err != nil {
+			return fmt.Errorf("failed to check merge record at %s: %w", recordKey, err)
+		}
+
+		if merged {
+			log.With(slog.String("profile.key", object.Key)).InfoContext(ctx, "removing staged profile that has already been merged")
+			if err = w.removeStaged(ctx, app, channel, object.Key); err != nil {
+				return err
+			}
+
+			stagedReconciled.WithLabelValues(app, reconcileRemoved).Inc()
+			continue
+		}
+
+		// The record of previous requeues is shared between workers, so that each profile is requeued with backoff
+		// and eventually abandoned no matter how many workers are reconciling.
+		requeueKey := RequeueRecordKey(app, channel, object.Key)
+		requeue, err := w.readRequeueRecord(ctx, requeueKey)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case requeue.Abandoned:
+			continue
+		case config.MaxAttempts > 0 && requeue.Attempts >= config.MaxAttempts:
+			log.With(slog.String("profile.key", object.Key), slog.Int("attempts", requeue.Attempts)).
+				ErrorContext(ctx, "abandoning staged profile that could not be merged")
+
+			requeue.Abandoned = true
+			if err = w.writeRequeueRecord(ctx, requeueKey, requeue); err != nil {
+				return err
+			}
+
+			stagedReconciled.WithLabelValues(app, reconcileAbandoned).Inc()
+			continue
+		case time.Since(requeue.RequeuedAt) < requeueBackoff(config.Threshold, requeue.Attempts):
+			continue
+		}
+
+		requeue.Attempts++
+		requeue.RequeuedAt = time.Now()
+		if err = w.writeRequeueRecord(ctx, requeueKey, requeue); err != nil {
+			return err
+		}
+
+		log.With(slog.String("profile.key", object.Key), slog.Int("attempts", requeue.Attempts)).
+			InfoContext(ctx, "requeueing staged profile")
+
+		if err = w.writer.Write(ctx, UploadedEvent{App: app, Channel: channel, ProfileKey: object.Key}); err != nil {
+			return fmt.Errorf("failed to requeue profile at %s: %w", object.Key, err)
+		}
+
+		stagedReconciled.WithLabelValues(app, reconcileRequeued).Inc()
+	}
+
+	isRecord := func(obj blob.Object) bool {
+		return IsMergeRecord(app, channel)(obj) || IsRequeueRecord(app, channel)(obj)
+	}
+
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{
+		Prefix: mergeRecordsPrefix(app, channel),
+		Filter: isRecord,
+	}) {
+		if err != nil {
+			return err
+		}
+
+		id := strings.TrimSuffix(path.Base(object.Key), requeueRecordSuffix)
+		if staged[id] || object.LastModified.After(cutoff) {
+			continue
+		}
+
 		err = w.blobs.Delete(ctx, object.Key)
 		switch {
 		case errors.Is(err, blob.ErrNotExist):
 			continue
 		case err != nil:
+			return fmt.Errorf("failed to delete record at %s: %w", object.Key, err)
+		}
+	}
+
+	if config.DedupWindow <= 0 {
+		return nil
+	}
+
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{
+		Prefix: uploadRecordsPrefix(app, channel),
+		Filter: IsUploadRecord(app, channel),
+	}) {
+		if err != nil {
 			return err
 		}
+
+		if time.Since(object.LastModified) <= config.DedupWindow {
+			continue
+		}
+
+		err = w.blobs.Delete(ctx, object.Key)
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to delete upload record at %s: %w", object.Key, err)
+		}
 	}
 
 	return nil
 }
 
+// maxRequeueDoublings limits how many times the delay between requeues of a staged profile doubles.
+const maxRequeueDoublings = 6
+
+// requeueBackoff returns how long to wait after the last of the given number of requeues before requeueing a staged
+// profile again. The delay starts at the threshold and doubles with each attempt.
+func requeueBackoff(threshold time.Duration, attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+
+	return threshold << min(attempts-1, maxRequeueDoublings)
+}
+
+func (w *Worker) readRequeueRecord(ctx context.Context, key string) (requeueRecord, error) {
+	reader, err := w.blobs.NewReader(ctx, key)
+	switch {
+	case errors.Is(err, blob.ErrNotExist):
+		return requeueRecord{}, nil
+	case err != nil:
+		return requeueRecord{}, fmt.Errorf("failed to open requeue record at %s: %w", key, err)
+	}
+	defer closers.Close(ctx, reader)
+
+	var record requeueRecord
+	if err = json.NewDecoder(reader).Decode(&record); err != nil {
+		return requeueRecord{}, fmt.Errorf("failed to decode requeue record at %s: %w", key, err)
+	}
+
+	return record, nil
+}
+
+func (w *Worker) writeRequeueRecord(ctx context.Context, key string, record requeueRecord) error {
+	writer, err := w.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to open writer: %w", err)
+	}
+
+	if err = json.NewEncoder(writer).Encode(record); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func (w *Worker) writePromotion(ctx context.Context, key string, promotion Promotion) error {
+	writer, err := w.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to open writer: %w", err)
+	}
+
+	if err = json.NewEncoder(writer).Encode(promotion); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// saveVersion stores the profile as the next generation for the application's channel, removing any generations
+// beyond the retention count. Returns the new generation number, or zero if generations are not being kept.
+func (w *Worker) saveVersion(ctx context.Context, app, channel string, p *profile.Profile) (int, error) {
+	if w.retain <= 0 {
+		return 0, nil
+	}
+
+	versions := make([]int, 0)
+	for object, err := range w.blobs.List(ctx, blob.ListOptions{
+		Prefix: versionsPrefix(app, channel),
+		Filter: IsVersion(app, channel),
+	}) {
+		if err != nil {
+			return 0, err
+		}
+
+		version, _ := ParseVersionKey(app, channel, object.Key)
+		versions = append(versions, version)
+	}
+
+	slices.Sort(versions)
+
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	if _, err := w.writeProfile(ctx, VersionKey(app, channel, next), p); err != nil {
+		return 0, err
+	}
+
+	versions = append(versions, next)
+	for _, version := range versions[:max(0, len(versions)-w.retain)] {
+		err := w.blobs.Delete(ctx, VersionKey(app, channel, version))
+		switch {
+		case errors.Is(err, blob.ErrNotExist):
+			continue
+		case err != nil:
+			return 0, err
+		}
+	}
+
+	return next, nil
+}
+
+func (w *Worker) writeProfile(ctx context.Context, key string, p *profile.Profile) (int64, error) {
+	writer, err := w.blobs.NewWriter(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open writer: %w", err)
+	}
+
+	profileWriter := &countingWriter{WriteCloser: writer}
+	if err = p.Write(profileWriter); err != nil {
+		return 0, err
+	}
+
+	if err = profileWriter.Close(); err != nil {
+		return 0, err
+	}
+
+	return profileWriter.n, nil
+}
+
 // LoadPruneConfig attempts to parse the file at the specified location and decode it into an array of profile pruning
 // rules that are applied when the worker merges profiles. The file is expected to be in JSON encoding.
 func LoadPruneConfig(ctx context.Context, location string) ([]PruneConfig, error) {
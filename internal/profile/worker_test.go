@@ -3,7 +3,10 @@ package profile_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"io"
 	"regexp"
+	"strconv"
 	"testing"
 	"time"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/davidsbond/autopgo/internal/event"
 	"github.com/davidsbond/autopgo/internal/profile"
 	"github.com/davidsbond/autopgo/internal/profile/mocks"
+	"github.com/davidsbond/autopgo/internal/testutil"
 )
 
 func TestWorker_HandleEvent(t *testing.T) {
@@ -26,6 +30,7 @@ func TestWorker_HandleEvent(t *testing.T) {
 		ExpectsError bool
 		Setup        func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter)
 		Pruning      []profile.PruneConfig
+		Retain       int
 	}{
 		{
 			Name: "handle profile.uploaded with base profile, no pruning",
@@ -39,6 +44,10 @@ func TestWorker_HandleEvent(t *testing.T) {
 				}),
 			},
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/merges/12345").
+					Return(false, nil)
+
 				blobs.EXPECT().
 					NewReader(mock.Anything, "test-app/staging/12345").
 					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
@@ -51,6 +60,10 @@ func TestWorker_HandleEvent(t *testing.T) {
 					NewWriter(mock.Anything, "test-app/default.pgo").
 					Return(&WriteCloser{}, nil)
 
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/merges/12345").
+					Return(&WriteCloser{}, nil)
+
 				events.EXPECT().
 					Write(mock.Anything, profile.MergedEvent{
 						App:        "test-app",
@@ -72,6 +85,10 @@ func TestWorker_HandleEvent(t *testing.T) {
 				}),
 			},
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/merges/12345").
+					Return(false, nil)
+
 				blobs.EXPECT().
 					NewReader(mock.Anything, "test-app/staging/12345").
 					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
@@ -84,6 +101,10 @@ func TestWorker_HandleEvent(t *testing.T) {
 					NewWriter(mock.Anything, "test-app/default.pgo").
 					Return(&WriteCloser{}, nil)
 
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/merges/12345").
+					Return(&WriteCloser{}, nil)
+
 				events.EXPECT().
 					Write(mock.Anything, profile.MergedEvent{
 						App:        "test-app",
@@ -109,6 +130,70 @@ func TestWorker_HandleEvent(t *testing.T) {
 				blobs.EXPECT().
 					Delete(mock.Anything, "test-app/staging/12345").
 					Return(nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/merges/12345.requeue").
+					Return(blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/merges/12345").
+					Return(nil)
+			},
+		},
+		{
+			Name: "handle profile.merged for a removed profile",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeMerged,
+				Payload: mustMarshal(t, profile.MergedEvent{
+					App:        "test-app",
+					Channel:    "prod",
+					ProfileKey: "test-app/channels/prod/staging/12345",
+					MergedKey:  "test-app/channels/prod/default.pgo",
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/channels/prod/staging/12345").
+					Return(blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/channels/prod/merges/12345.requeue").
+					Return(blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/channels/prod/merges/12345").
+					Return(nil)
+			},
+		},
+		{
+			Name: "handle profile.uploaded for an already merged profile",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeUploaded,
+				Payload: mustMarshal(t, profile.UploadedEvent{
+					App:        "test-app",
+					ProfileKey: "test-app/staging/12345",
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/merges/12345").
+					Return(true, nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/staging/12345").
+					Return(nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/merges/12345.requeue").
+					Return(blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/merges/12345").
+					Return(nil)
 			},
 		},
 		{
@@ -133,6 +218,52 @@ func TestWorker_HandleEvent(t *testing.T) {
 				},
 			},
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/merges/12345").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/staging/12345").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/default.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/default.pgo").
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/merges/12345").
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, profile.MergedEvent{
+						App:        "test-app",
+						ProfileKey: "test-app/staging/12345",
+						MergedKey:  "test-app/default.pgo",
+					}).
+					Return(nil)
+			},
+		},
+		{
+			Name: "handle profile.uploaded with versioning",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeUploaded,
+				Payload: mustMarshal(t, profile.UploadedEvent{
+					App:        "test-app",
+					ProfileKey: "test-app/staging/12345",
+				}),
+			},
+			Retain: 2,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/merges/12345").
+					Return(false, nil)
+
 				blobs.EXPECT().
 					NewReader(mock.Anything, "test-app/staging/12345").
 					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
@@ -145,15 +276,270 @@ func TestWorker_HandleEvent(t *testing.T) {
 					NewWriter(mock.Anything, "test-app/default.pgo").
 					Return(&WriteCloser{}, nil)
 
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						_ = yield(blob.Object{Key: "test-app/versions/2.pgo"}, nil) &&
+							yield(blob.Object{Key: "test-app/versions/1.pgo"}, nil)
+					})
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/versions/3.pgo").
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/versions/1.pgo").
+					Return(nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/merges/12345").
+					Return(&WriteCloser{}, nil)
+
 				events.EXPECT().
 					Write(mock.Anything, profile.MergedEvent{
 						App:        "test-app",
 						ProfileKey: "test-app/staging/12345",
 						MergedKey:  "test-app/default.pgo",
+						Version:    3,
 					}).
 					Return(nil)
 			},
 		},
+		{
+			Name: "handle profile.rolledback",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeRolledBack,
+				Payload: mustMarshal(t, profile.RolledBackEvent{
+					App:     "test-app",
+					Version: 1,
+				}),
+			},
+			Retain: 10,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/versions/1.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/default.pgo").
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					List(mock.Anything, mock.Anything).
+					Return(func(yield func(blob.Object, error) bool) {
+						_ = yield(blob.Object{Key: "test-app/versions/1.pgo"}, nil) &&
+							yield(blob.Object{Key: "test-app/versions/2.pgo"}, nil)
+					})
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/versions/3.pgo").
+					Return(&WriteCloser{}, nil)
+			},
+		},
+		{
+			Name: "handle profile.rolledback for missing version",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeRolledBack,
+				Payload: mustMarshal(t, profile.RolledBackEvent{
+					App:     "test-app",
+					Version: 1,
+				}),
+			},
+			Retain: 10,
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/versions/1.pgo").
+					Return(nil, blob.ErrNotExist)
+			},
+		},
+		{
+			Name: "handle profile.promoted",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypePromoted,
+				Payload: mustMarshal(t, profile.PromotedEvent{
+					App:        "test-app",
+					Channel:    "candidate",
+					Version:    2,
+					PromotedBy: "release-bot",
+					PromotedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, profile.PromotionKey("test-app", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))).
+					Return(false, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/channels/candidate/versions/2.pgo").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/stable.pgo").
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, profile.PromotionKey("test-app", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))).
+					Return(&WriteCloser{}, nil)
+			},
+		},
+		{
+			Name: "handle profile.promoted that has already been performed",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypePromoted,
+				Payload: mustMarshal(t, profile.PromotedEvent{
+					App:        "test-app",
+					Channel:    "candidate",
+					Version:    2,
+					PromotedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, profile.PromotionKey("test-app", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))).
+					Return(true, nil)
+			},
+		},
+		{
+			Name: "handle profile.promoted for missing version",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypePromoted,
+				Payload: mustMarshal(t, profile.PromotedEvent{
+					App:     "test-app",
+					Channel: "candidate",
+					Version: 2,
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, mock.Anything).
+					Return(false, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/channels/candidate/versions/2.pgo").
+					Return(nil, blob.ErrNotExist)
+			},
+		},
+		{
+			Name: "handle profile.uploaded for a channel",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeUploaded,
+				Payload: mustMarshal(t, profile.UploadedEvent{
+					App:        "test-app",
+					Channel:    "prod",
+					ProfileKey: "test-app/channels/prod/staging/12345",
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					Exists(mock.Anything, "test-app/channels/prod/merges/12345").
+					Return(false, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/channels/prod/staging/12345").
+					Return(&ReadCloser{data: bytes.NewBuffer(validProfile)}, nil)
+
+				blobs.EXPECT().
+					NewReader(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(nil, blob.ErrNotExist)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(&WriteCloser{}, nil)
+
+				blobs.EXPECT().
+					NewWriter(mock.Anything, "test-app/channels/prod/merges/12345").
+					Return(&WriteCloser{}, nil)
+
+				events.EXPECT().
+					Write(mock.Anything, profile.MergedEvent{
+						App:        "test-app",
+						Channel:    "prod",
+						ProfileKey: "test-app/channels/prod/staging/12345",
+						MergedKey:  "test-app/channels/prod/default.pgo",
+					}).
+					Return(nil)
+			},
+		},
+		{
+			Name: "handle profile.deleted for a channel",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeDeleted,
+				Payload: mustMarshal(t, profile.DeletedEvent{
+					App:     "test-app",
+					Channel: "prod",
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				blobs.EXPECT().
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/channels/prod/"}).
+					RunAndReturn(testutil.ListObjects(
+						blob.Object{Key: "test-app/default.pgo"},
+						blob.Object{Key: "test-app/channels/prod/default.pgo"},
+					))
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(nil)
+			},
+		},
+		{
+			Name: "handle profile.deleted for the default channel",
+			Event: event.Envelope{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Type:      profile.EventTypeDeleted,
+				Payload: mustMarshal(t, profile.DeletedEvent{
+					App:     "test-app",
+					Channel: profile.DefaultChannel,
+				}),
+			},
+			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
+				list := testutil.ListObjects(
+					blob.Object{Key: "test-app/default.pgo"},
+					blob.Object{Key: "test-app/staging/12345"},
+					blob.Object{Key: "test-app/versions/1.pgo"},
+					blob.Object{Key: "test-app/channels/prod/default.pgo"},
+				)
+
+				blobs.EXPECT().
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/", Delimiter: "/"}).
+					RunAndReturn(list)
+
+				blobs.EXPECT().
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/staging/"}).
+					RunAndReturn(list)
+
+				blobs.EXPECT().
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/versions/"}).
+					RunAndReturn(list)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/default.pgo").
+					Return(nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/staging/12345").
+					Return(blob.ErrNotExist)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/versions/1.pgo").
+					Return(nil)
+			},
+		},
 		{
 			Name: "handle profile.deleted",
 			Event: event.Envelope{
@@ -166,18 +552,19 @@ func TestWorker_HandleEvent(t *testing.T) {
 			},
 			Setup: func(blobs *mocks.MockBlobRepository, events *mocks.MockEventWriter) {
 				blobs.EXPECT().
-					List(mock.Anything, mock.Anything).
-					Return(func(yield func(blob.Object, error) bool) {
-						yield(blob.Object{
-							Key:          "test-app/default.pgo",
-							Size:         1000,
-							LastModified: time.Now(),
-						}, nil)
-					})
+					List(mock.Anything, blob.ListOptions{Prefix: "test-app/"}).
+					RunAndReturn(testutil.ListObjects(
+						blob.Object{Key: "test-app/default.pgo"},
+						blob.Object{Key: "test-app/channels/prod/default.pgo"},
+					))
 
 				blobs.EXPECT().
 					Delete(mock.Anything, "test-app/default.pgo").
 					Return(nil)
+
+				blobs.EXPECT().
+					Delete(mock.Anything, "test-app/channels/prod/default.pgo").
+					Return(nil)
 			},
 		},
 	}
@@ -191,7 +578,7 @@ func TestWorker_HandleEvent(t *testing.T) {
 				tc.Setup(blobs, events)
 			}
 
-			err := profile.NewWorker(blobs, events, tc.Pruning).HandleEvent(context.Background(), tc.Event)
+			err := profile.NewWorker(blobs, events, tc.Pruning, tc.Retain).HandleEvent(context.Background(), tc.Event)
 			if tc.ExpectsError {
 				require.Error(t, err)
 			}
@@ -200,3 +587,186 @@ func TestWorker_HandleEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestWorker_ReconcileStaged(t *testing.T) {
+	t.Parallel()
+
+	id := func(age time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(-age).UnixNano(), 10)
+	}
+
+	var (
+		orphaned = id(2 * time.Hour)
+		merged   = id(3 * time.Hour)
+		recent   = id(time.Minute)
+		channel  = id(4 * time.Hour)
+		stale    = id(5 * time.Hour)
+		fresh    = id(6 * time.Hour)
+		waiting  = id(7 * time.Hour)
+		retried  = id(8 * time.Hour)
+		failing  = id(9 * time.Hour)
+		given    = id(10 * time.Hour)
+	)
+
+	requeued := func(attempts int, age time.Duration, abandoned bool) io.ReadCloser {
+		record, err := json.Marshal(map[string]any{
+			"attempts":   attempts,
+			"requeuedAt": time.Now().Add(-age),
+			"abandoned":  abandoned,
+		})
+		require.NoError(t, err)
+
+		return io.NopCloser(bytes.NewReader(record))
+	}
+
+	objects := []blob.Object{
+		{Key: "test-app/default.pgo"},
+		{Key: "test-app/staging/" + orphaned},
+		{Key: "test-app/staging/" + merged},
+		{Key: "test-app/staging/" + recent},
+		{Key: "test-app/merges/" + merged, LastModified: time.Now().Add(-2 * time.Hour)},
+		{Key: "test-app/merges/" + stale, LastModified: time.Now().Add(-2 * time.Hour)},
+		{Key: "test-app/merges/" + fresh, LastModified: time.Now()},
+		{Key: "test-app/staging/" + waiting},
+		{Key: "test-app/staging/" + retried},
+		{Key: "test-app/staging/" + failing},
+		{Key: "test-app/staging/" + given},
+		{Key: "test-app/merges/" + waiting + ".requeue", LastModified: time.Now().Add(-2 * time.Hour)},
+		{Key: "test-app/merges/" + stale + ".requeue", LastModified: time.Now().Add(-2 * time.Hour)},
+		{Key: "test-app/channels/prod/staging/" + channel},
+		{Key: "test-app/uploads/sha256-old.json", LastModified: time.Now().Add(-2 * time.Hour)},
+		{Key: "test-app/uploads/sha256-new.json", LastModified: time.Now()},
+	}
+
+	blobs := mocks.NewMockBlobRepository(t)
+	events := mocks.NewMockEventWriter(t)
+
+	blobs.EXPECT().
+		List(mock.Anything, mock.Anything).
+		RunAndReturn(testutil.ListObjects(objects...))
+
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+orphaned).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/merges/"+orphaned+".requeue").
+		Return(nil, blob.ErrNotExist)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, "test-app/merges/"+orphaned+".requeue").
+		Return(&WriteCloser{}, nil)
+
+	events.EXPECT().
+		Write(mock.Anything, profile.UploadedEvent{
+			App:        "test-app",
+			ProfileKey: "test-app/staging/" + orphaned,
+		}).
+		Return(nil)
+
+	// Requeued recently, so waits for the backoff to pass.
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+waiting).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/merges/"+waiting+".requeue").
+		Return(requeued(2, 90*time.Minute, false), nil)
+
+	// Requeued long enough ago to be requeued again.
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+retried).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/merges/"+retried+".requeue").
+		Return(requeued(2, 3*time.Hour, false), nil)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, "test-app/merges/"+retried+".requeue").
+		Return(&WriteCloser{}, nil)
+
+	events.EXPECT().
+		Write(mock.Anything, profile.UploadedEvent{
+			App:        "test-app",
+			ProfileKey: "test-app/staging/" + retried,
+		}).
+		Return(nil)
+
+	// Requeued too many times, so is abandoned.
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+failing).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/merges/"+failing+".requeue").
+		Return(requeued(3, 24*time.Hour, false), nil)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, "test-app/merges/"+failing+".requeue").
+		Return(&WriteCloser{}, nil)
+
+	// Already abandoned.
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+given).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/merges/"+given+".requeue").
+		Return(requeued(3, 24*time.Hour, true), nil)
+
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/merges/"+merged).
+		Return(true, nil)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/staging/"+merged).
+		Return(nil)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/merges/"+merged+".requeue").
+		Return(blob.ErrNotExist)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/merges/"+merged).
+		Return(nil)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/merges/"+stale).
+		Return(nil)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/merges/"+stale+".requeue").
+		Return(nil)
+
+	blobs.EXPECT().
+		Delete(mock.Anything, "test-app/uploads/sha256-old.json").
+		Return(nil)
+
+	blobs.EXPECT().
+		Exists(mock.Anything, "test-app/channels/prod/merges/"+channel).
+		Return(false, nil)
+
+	blobs.EXPECT().
+		NewReader(mock.Anything, "test-app/channels/prod/merges/"+channel+".requeue").
+		Return(nil, blob.ErrNotExist)
+
+	blobs.EXPECT().
+		NewWriter(mock.Anything, "test-app/channels/prod/merges/"+channel+".requeue").
+		Return(&WriteCloser{}, nil)
+
+	events.EXPECT().
+		Write(mock.Anything, profile.UploadedEvent{
+			App:        "test-app",
+			Channel:    "prod",
+			ProfileKey: "test-app/channels/prod/staging/" + channel,
+		}).
+		Return(nil)
+
+	err := profile.NewWorker(blobs, events, nil, 0).ReconcileStaged(context.Background(), profile.ReconcileConfig{
+		Threshold:   time.Hour,
+		DedupWindow: time.Hour,
+		MaxAttempts: 3,
+	})
+	require.NoError(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/pprof"
 	"time"
@@ -23,6 +24,11 @@ type (
 		Middleware []Middleware
 		// Enables debug endpoints for pprof.
 		Debug bool
+		// Optional configuration for serving HTTPS traffic. When no certificate or key is set, plain HTTP is served.
+		TLS TLSConfig
+		// The logger used to report errors that occur outside of request handling, such as failing to reload the TLS
+		// certificate. Defaults to slog.Default.
+		Logger *slog.Logger
 	}
 
 	// The Controller interface describes types that register HTTP request handlers.
@@ -58,9 +64,30 @@ func Run(ctx context.Context, config Config) error {
 		server.Handler = middleware(server.Handler)
 	}
 
+	if config.TLS.enabled() {
+		log := config.Logger
+		if log == nil {
+			log = slog.Default()
+		}
+
+		tlsConfig, err := config.TLS.build(log)
+		if err != nil {
+			return err
+		}
+
+		server.TLSConfig = tlsConfig
+	}
+
 	group, ctx := errgroup.WithContext(ctx)
 	group.Go(func() error {
-		err := server.ListenAndServe()
+		var err error
+		if server.TLSConfig != nil {
+			// The certificate is provided via the tls.Config, so no files are given here.
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
 		}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// The TLSConfig type contains configuration values used for serving HTTPS traffic.
+	TLSConfig struct {
+		// The location of the PEM-encoded certificate to serve. The certificate is reloaded when the file changes.
+		CertFile string
+		// The location of the PEM-encoded private key for the certificate. The key is reloaded when the file changes.
+		KeyFile string
+		// The location of PEM-encoded CA certificates used to verify client certificates. When set, clients must
+		// present a certificate signed by one of these CAs.
+		ClientCAFile string
+		// The minimum TLS version to accept, either "1.2" or "1.3". Defaults to "1.2".
+		MinVersion string
+	}
+
+	certReloader struct {
+		certFile string
+		keyFile  string
+		logger   *slog.Logger
+
+		mux         sync.Mutex
+		certificate *tls.Certificate
+		modified    time.Time
+		failed      time.Time
+	}
+)
+
+// ParseTLSVersion converts a version string, such as "1.3", into its corresponding crypto/tls constant.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q", version)
+	}
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+func (c TLSConfig) build(logger *slog.Logger) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("both a certificate and key file are required to serve TLS")
+	}
+
+	version, err := ParseTLSVersion(c.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader := &certReloader{
+		certFile: c.CertFile,
+		keyFile:  c.KeyFile,
+		logger:   logger,
+	}
+
+	// The certificate is loaded upfront so that invalid configuration is reported on startup rather than on the
+	// first connection.
+	if _, err = reloader.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     version,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", c.ClientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// GetCertificate returns the certificate to serve, reloading it from disk if either the certificate or key file has
+// been modified since it was last loaded. If reloading fails, the failure is logged and the previously loaded certificate
+// continues to be served until the files are modified again.
+func (cr *certReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+
+	modified, err := cr.lastModified()
+	switch {
+	case err != nil && cr.certificate != nil:
+		return cr.certificate, nil
+	case err != nil:
+		return nil, err
+	case cr.certificate != nil && (modified.Equal(cr.modified) || modified.Equal(cr.failed)):
+		return cr.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	switch {
+	case err != nil && cr.certificate != nil:
+		// The files may be mid-update, so keep serving the current certificate until they're valid. The modification
+		// time is recorded so that the same broken files aren't reloaded on every handshake.
+		ctx := context.Background()
+		if hello != nil {
+			ctx = hello.Context()
+		}
+
+		cr.logger.With(
+			slog.String("error", err.Error()),
+			slog.String("certificate", cr.certFile),
+			slog.String("key", cr.keyFile),
+		).ErrorContext(ctx, "failed to reload certificate")
+
+		cr.failed = modified
+		return cr.certificate, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	cr.certificate = &certificate
+	cr.modified = modified
+	return cr.certificate, nil
+}
+
+func (cr *certReloader) lastModified() (time.Time, error) {
+	var modified time.Time
+	for _, location := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(location)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	return modified, nil
+}
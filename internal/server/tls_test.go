@@ -0,0 +1,198 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/davidsbond/autopgo/internal/server"
+	"github.com/davidsbond/autopgo/pkg/client"
+)
+
+func TestRun_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := generateCA(t)
+
+	writeCertificate(t, dir, "ca", ca, nil)
+	issue(t, dir, "server", "first", ca, caKey)
+	issue(t, dir, "client", "client", ca, caKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failures := &lineCounter{}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Run(ctx, server.Config{
+			Port:   port,
+			Logger: slog.New(slog.NewTextHandler(failures, nil)),
+			TLS: server.TLSConfig{
+				CertFile:     filepath.Join(dir, "server.crt"),
+				KeyFile:      filepath.Join(dir, "server.key"),
+				ClientCAFile: filepath.Join(dir, "ca.crt"),
+				MinVersion:   "1.3",
+			},
+		})
+	}()
+
+	config, err := client.LoadTLSConfig(
+		filepath.Join(dir, "ca.crt"),
+		filepath.Join(dir, "client.crt"),
+		filepath.Join(dir, "client.key"),
+	)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("https://localhost:%d", port)
+	cl := &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+
+	served := func() string {
+		var resp *http.Response
+		require.EventuallyWithT(t, func(c *assert.CollectT) {
+			resp, err = cl.Get(url)
+			assert.NoError(c, err)
+		}, 5*time.Second, 50*time.Millisecond)
+
+		require.NoError(t, resp.Body.Close())
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	t.Run("serves certificate", func(t *testing.T) {
+		assert.EqualValues(t, "first", served())
+	})
+
+	t.Run("requires client certificate", func(t *testing.T) {
+		anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: config.RootCAs}}}
+		_, err := anonymous.Get(url)
+		assert.Error(t, err)
+	})
+
+	t.Run("reloads certificate", func(t *testing.T) {
+		issue(t, dir, "server", "second", ca, caKey)
+
+		// Ensure the modification time changes on filesystems with coarse timestamps.
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, "server.crt"), future, future))
+
+		assert.EqualValues(t, "second", served())
+	})
+
+	t.Run("keeps certificate when reload fails", func(t *testing.T) {
+		location := filepath.Join(dir, "server.key")
+		require.NoError(t, os.WriteFile(location, []byte("not a key"), 0o600))
+
+		future := time.Now().Add(2 * time.Minute)
+		require.NoError(t, os.Chtimes(location, future, future))
+
+		assert.EqualValues(t, "second", served())
+		assert.EqualValues(t, "second", served())
+
+		// The broken files are only loaded once until they're modified again.
+		assert.EqualValues(t, 1, failures.count.Load())
+	})
+
+	cancel()
+	assert.NoError(t, <-errs)
+}
+
+type lineCounter struct {
+	count atomic.Int64
+}
+
+func (lc *lineCounter) Write(p []byte) (int, error) {
+	lc.count.Add(int64(bytes.Count(p, []byte("\n"))))
+	return len(p), nil
+}
+
+func generateCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return certificate, key
+}
+
+func issue(t *testing.T, dir, file, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	writeCertificate(t, dir, file, certificate, key)
+}
+
+func writeCertificate(t *testing.T, dir, name string, certificate *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0o600))
+
+	if key == nil {
+		return
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600))
+}
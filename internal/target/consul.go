@@ -74,6 +74,9 @@ func (cs *ConsulSource) List(ctx context.Context) ([]Target, error) {
 			targets = append(targets, Target{
 				Address: u.String(),
 				Path:    tags[pathLabel],
+				Labels: targetLabels(tags, map[string]string{
+					"service": service.ServiceName,
+				}),
 			})
 		}
 	}
@@ -32,6 +32,13 @@ func TestConsulSource_List(t *testing.T) {
 				{
 					Address: "https://127.0.0.1:8080",
 					Path:    "/test/app",
+					Labels: map[string]string{
+						"autopgo.scrape":        "true",
+						"autopgo.scrape.app":    "test",
+						"autopgo.scrape.scheme": "https",
+						"autopgo.scrape.path":   "/test/app",
+						"service":               "test",
+					},
 				},
 			},
 			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -52,6 +59,7 @@ func TestConsulSource_List(t *testing.T) {
 				if r.URL.Path == "/v1/catalog/service/test" {
 					require.NoError(t, encoder.Encode([]*api.CatalogService{
 						{
+							ServiceName:    "test",
 							ServiceAddress: "127.0.0.1",
 							ServiceTags: []string{
 								"autopgo.scrape=true",
@@ -106,6 +106,10 @@ func (ks *KubernetesSource) List(ctx context.Context) ([]Target, error) {
 		targets = append(targets, Target{
 			Address: u.String(),
 			Path:    annotations[pathLabel],
+			Labels: targetLabels(pod.GetObjectMeta().GetLabels(), map[string]string{
+				"namespace": pod.Namespace,
+				"pod":       pod.Name,
+			}),
 		})
 	}
 
@@ -32,6 +32,12 @@ func TestKubernetesSource_List(t *testing.T) {
 				{
 					Address: "https://127.0.0.1:8080",
 					Path:    "/test/path",
+					Labels: map[string]string{
+						"namespace":          corev1.NamespaceDefault,
+						"pod":                "test",
+						"autopgo.scrape":     "true",
+						"autopgo.scrape.app": "test",
+					},
 				},
 			},
 			Objects: []runtime.Object{
@@ -145,6 +151,46 @@ func TestKubernetesSource_List(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "built-in labels take precedence",
+			App:  "test",
+			Expected: []target.Target{
+				{
+					Address: "http://127.0.0.1:8080",
+					Labels: map[string]string{
+						"namespace":          corev1.NamespaceDefault,
+						"pod":                "test",
+						"autopgo.scrape":     "true",
+						"autopgo.scrape.app": "test",
+					},
+				},
+			},
+			Objects: []runtime.Object{
+				&corev1.PodList{
+					Items: []corev1.Pod{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "test",
+								Labels: map[string]string{
+									"autopgo.scrape":     "true",
+									"autopgo.scrape.app": "test",
+									"namespace":          "other",
+									"pod":                "other",
+								},
+								Annotations: map[string]string{
+									"autopgo.scrape.port": "8080",
+								},
+								Namespace: corev1.NamespaceDefault,
+							},
+							Status: corev1.PodStatus{
+								PodIP: "127.0.0.1",
+								Phase: corev1.PodRunning,
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			Name: "defaults scheme to http",
 			App:  "test",
@@ -152,6 +198,12 @@ func TestKubernetesSource_List(t *testing.T) {
 				{
 					Address: "http://127.0.0.1:8080",
 					Path:    "/test/path",
+					Labels: map[string]string{
+						"namespace":          corev1.NamespaceDefault,
+						"pod":                "test",
+						"autopgo.scrape":     "true",
+						"autopgo.scrape.app": "test",
+					},
 				},
 			},
 			Objects: []runtime.Object{
@@ -87,6 +87,10 @@ func (ns *NomadSource) List(ctx context.Context) ([]Target, error) {
 				targets = append(targets, Target{
 					Address: u.String(),
 					Path:    tags[pathLabel],
+					Labels: targetLabels(tags, map[string]string{
+						"namespace": service.Namespace,
+						"service":   service.ServiceName,
+					}),
 				})
 			}
 		}
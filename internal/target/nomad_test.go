@@ -31,6 +31,14 @@ func TestNomadSource_List(t *testing.T) {
 				{
 					Address: "https://127.0.0.1:8080",
 					Path:    "/test/app",
+					Labels: map[string]string{
+						"autopgo.scrape":        "true",
+						"autopgo.scrape.app":    "test",
+						"autopgo.scrape.scheme": "https",
+						"autopgo.scrape.path":   "/test/app",
+						"namespace":             "test",
+						"service":               "test",
+					},
 				},
 			},
 			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -3,6 +3,7 @@ package target
 
 import (
 	"context"
+	"maps"
 	"strings"
 
 	"github.com/davidsbond/autopgo/internal/operation"
@@ -16,6 +17,8 @@ type (
 		// The path to the pprof profile endpoint, including leading slash. Defaults to /debug/pprof/profile if
 		// unset.
 		Path string `json:"path"`
+		// Arbitrary key/value pairs describing the target, such as the pod or service it was discovered from.
+		Labels map[string]string `json:"labels,omitempty"`
 	}
 
 	// The Source interface describes types that can query scrapable targets from some system that stores them.
@@ -52,3 +55,14 @@ func tagsToMap(tags []string) map[string]string {
 
 	return out
 }
+
+// targetLabels returns the labels for a target, combining those obtained from its source with the built-in labels
+// describing where it was discovered, such as its namespace. Built-in labels take precedence, so they always identify
+// the target regardless of the source.
+func targetLabels(discovered, builtin map[string]string) map[string]string {
+	labels := make(map[string]string, len(discovered)+len(builtin))
+	maps.Copy(labels, discovered)
+	maps.Copy(labels, builtin)
+
+	return labels
+}
@@ -0,0 +1,65 @@
+package testutil
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/davidsbond/autopgo/internal/blob"
+)
+
+// ListObjects returns a function that lists the objects in the same way as blob.Bucket.List, applying the prefix,
+// delimiter & filter of the given blob.ListOptions. It is intended for use with the RunAndReturn method of mocks.
+func ListObjects(objects ...blob.Object) func(context.Context, blob.ListOptions) iter.Seq2[blob.Object, error] {
+	objects = slices.Clone(objects)
+	slices.SortFunc(objects, func(a, b blob.Object) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return func(_ context.Context, opts blob.ListOptions) iter.Seq2[blob.Object, error] {
+		return func(yield func(blob.Object, error) bool) {
+			dirs := make(map[string]bool)
+			for _, obj := range objects {
+				rest, ok := strings.CutPrefix(obj.Key, opts.Prefix)
+				if !ok {
+					continue
+				}
+
+				if opts.Delimiter != "" {
+					if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+						key := opts.Prefix + rest[:i+len(opts.Delimiter)]
+						if dirs[key] {
+							continue
+						}
+
+						dirs[key] = true
+						obj = blob.Object{Key: key, IsDir: true}
+					}
+				}
+
+				if opts.Filter != nil && !opts.Filter(obj) {
+					continue
+				}
+
+				if !yield(obj, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// StatObjects returns a function that returns metadata on the object with the given key in the same way as
+// blob.Bucket.Stat. It is intended for use with the RunAndReturn method of mocks.
+func StatObjects(objects ...blob.Object) func(context.Context, string) (blob.Object, error) {
+	return func(_ context.Context, key string) (blob.Object, error) {
+		for _, obj := range objects {
+			if obj.Key == key {
+				return obj, nil
+			}
+		}
+
+		return blob.Object{}, blob.ErrNotExist
+	}
+}
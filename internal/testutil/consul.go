@@ -62,5 +62,12 @@ func ConsulTarget(t *testing.T, client *api.Client) target.Target {
 	return target.Target{
 		Address: "https://127.0.0.1:8080",
 		Path:    "/test/app",
+		Labels: map[string]string{
+			"autopgo.scrape":        "true",
+			"autopgo.scrape.app":    "test",
+			"autopgo.scrape.scheme": "https",
+			"autopgo.scrape.path":   "/test/app",
+			"service":               "test",
+		},
 	}
 }
@@ -93,5 +93,11 @@ func KubernetesTarget(t *testing.T, client kubernetes.Interface) target.Target {
 	return target.Target{
 		Address: "https://" + pod.Status.PodIP + ":8080",
 		Path:    "/test/path",
+		Labels: map[string]string{
+			"namespace":          pod.Namespace,
+			"pod":                pod.Name,
+			"autopgo.scrape":     "true",
+			"autopgo.scrape.app": "test",
+		},
 	}
 }
@@ -0,0 +1,78 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+// MetricValue returns the value of the counter or gauge with the given name & labels from the default prometheus
+// registry, or the number of observations made by a histogram. Returns zero if nothing has been recorded for the
+// labels. Metrics are shared by all tests, so tests should use labels that are unique to them.
+func MetricValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	metric := findMetric(t, name, labels)
+	switch {
+	case metric == nil:
+		return 0
+	case metric.Counter != nil:
+		return metric.Counter.GetValue()
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue()
+	case metric.Histogram != nil:
+		return float64(metric.Histogram.GetSampleCount())
+	default:
+		return 0
+	}
+}
+
+// HistogramSum returns the sum of the observations made by the histogram with the given name & labels from the
+// default prometheus registry. Returns zero if nothing has been recorded for the labels.
+func HistogramSum(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	metric := findMetric(t, name, labels)
+	if metric == nil || metric.Histogram == nil {
+		return 0
+	}
+
+	return metric.Histogram.GetSampleSum()
+}
+
+func findMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			if hasLabels(metric, labels) {
+				return metric
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	if len(metric.GetLabel()) != len(labels) {
+		return false
+	}
+
+	for _, label := range metric.GetLabel() {
+		if value, ok := labels[label.GetName()]; !ok || value != label.GetValue() {
+			return false
+		}
+	}
+
+	return true
+}
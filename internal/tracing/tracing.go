@@ -0,0 +1,187 @@
+// Package tracing provides functions for configuring OpenTelemetry tracing and propagating trace context across HTTP
+// requests.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/davidsbond/autopgo/internal/server"
+)
+
+const (
+	tracerName = "github.com/davidsbond/autopgo"
+)
+
+// Init configures the global OpenTelemetry tracer provider to export spans via OTLP over HTTP to the given endpoint
+// URL, identifying spans with the provided service name and version. Trace context propagation is always configured,
+// so that trace context is forwarded even when the endpoint is empty and spans are not exported. The returned function
+// flushes any remaining spans and should be called before the process exits.
+func Init(ctx context.Context, endpoint, service, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(service),
+			semconv.ServiceVersion(version),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start a new span with the given name as a child of any span contained within the context.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End the span, recording the error if it is non-nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Inject the trace context from the provided context into a map, for use in transports that are not HTTP based.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+
+	return carrier
+}
+
+// Extract trace context from a map populated using Inject, returning a copy of the context containing it.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// Middleware is a server.Middleware implementation that starts a span for each inbound HTTP request, continuing any
+// trace propagated via the request headers. Spans are named using the pattern matched by the http.ServeMux, so this
+// should be placed in server.Config before any middleware that replaces the request, such as logger.Middleware.
+func Middleware() server.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+			req := r.WithContext(ctx)
+
+			next.ServeHTTP(recorder, req)
+
+			// The http.ServeMux sets the matched pattern on the request it was given, so it is copied back for any
+			// middleware wrapping this one.
+			r.Pattern = req.Pattern
+			if r.Pattern != "" {
+				span.SetName(r.Pattern)
+				span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.code))
+			if recorder.code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, strconv.Itoa(recorder.code))
+			}
+		})
+	}
+}
+
+// Transport returns an http.RoundTripper implementation that starts a span for each outbound HTTP request made via
+// the base http.RoundTripper, propagating trace context via the request headers.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+type (
+	transport struct {
+		base http.RoundTripper
+	}
+
+	statusRecorder struct {
+		http.ResponseWriter
+		code int
+	}
+)
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(r.URL.Redacted()),
+			semconv.ServerAddress(r.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
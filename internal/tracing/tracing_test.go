@@ -0,0 +1,67 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/davidsbond/autopgo/internal/tracing"
+)
+
+func TestPropagation(t *testing.T) {
+	ctx := context.Background()
+	_, err := tracing.Init(ctx, "", "test", "dev")
+	require.NoError(t, err)
+
+	expected := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	ctx = trace.ContextWithSpanContext(ctx, expected)
+
+	t.Run("headers", func(t *testing.T) {
+		headers := tracing.Inject(ctx)
+		assert.NotEmpty(t, headers["traceparent"])
+
+		actual := trace.SpanContextFromContext(tracing.Extract(context.Background(), headers))
+		assert.True(t, actual.IsRemote())
+		assert.EqualValues(t, expected.TraceID(), actual.TraceID())
+	})
+
+	t.Run("http", func(t *testing.T) {
+		var (
+			actual  trace.SpanContext
+			pattern string
+		)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/test/{id}", func(w http.ResponseWriter, r *http.Request) {
+			actual = trace.SpanContextFromContext(r.Context())
+		})
+
+		handler := tracing.Middleware()(mux)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r)
+			pattern = r.Pattern
+		}))
+		defer server.Close()
+
+		cl := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/test/1", nil)
+		require.NoError(t, err)
+
+		resp, err := cl.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.EqualValues(t, expected.TraceID(), actual.TraceID())
+		assert.EqualValues(t, "GET /api/test/{id}", pattern)
+	})
+}
@@ -0,0 +1,375 @@
+'use strict';
+
+// The web interface reads everything it displays from the server's API, authenticating with the bearer token stored
+// in the browser when one has been provided.
+const tokenKey = 'autopgo.token';
+const rowHeight = 18;
+
+const state = {
+  app: null,
+  channel: '',
+  graph: null,
+  zoom: null,
+  frames: [],
+};
+
+function token() {
+  return localStorage.getItem(tokenKey) || '';
+}
+
+function profilePath(app, channel, suffix) {
+  let path = '/api/profile/' + encodeURIComponent(app);
+  if (channel) {
+    path += '/' + encodeURIComponent(channel);
+  }
+
+  if (suffix) {
+    path += '/' + suffix;
+  }
+
+  return path;
+}
+
+async function request(path) {
+  const headers = {};
+  if (token()) {
+    headers['Authorization'] = 'Bearer ' + token();
+  }
+
+  const resp = await fetch(path, {headers});
+  if (!resp.ok) {
+    let message = resp.statusText;
+    try {
+      message = (await resp.json()).message || message;
+    } catch (e) {
+      // The body is not an API error, so the status text is used instead.
+    }
+
+    if (resp.status === 401 || resp.status === 403) {
+      message += ', check the token';
+    }
+
+    throw new Error(message);
+  }
+
+  return resp;
+}
+
+async function requestJSON(path) {
+  return (await request(path)).json();
+}
+
+function showError(err) {
+  const element = document.getElementById('error');
+  element.textContent = err ? err.message : '';
+  element.hidden = !err;
+}
+
+function formatBytes(bytes) {
+  const units = ['B', 'KiB', 'MiB', 'GiB'];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) {
+    bytes /= 1024;
+    i++;
+  }
+
+  return (i === 0 ? bytes : bytes.toFixed(1)) + ' ' + units[i];
+}
+
+function formatAge(timestamp) {
+  const seconds = Math.max(0, Math.floor((Date.now() - new Date(timestamp).getTime()) / 1000));
+  const units = [['d', 86400], ['h', 3600], ['m', 60]];
+  for (const [unit, size] of units) {
+    if (seconds >= size) {
+      return Math.floor(seconds / size) + unit;
+    }
+  }
+
+  return seconds + 's';
+}
+
+function formatDuration(nanoseconds) {
+  if (nanoseconds >= 1e9) {
+    return (nanoseconds / 1e9).toFixed(2) + 's';
+  }
+
+  return (nanoseconds / 1e6).toFixed(2) + 'ms';
+}
+
+function formatPercent(value, total) {
+  return total ? (value * 100 / total).toFixed(2) + '%' : '0.00%';
+}
+
+function row(cells, classes) {
+  const tr = document.createElement('tr');
+  cells.forEach((cell, i) => {
+    const td = document.createElement('td');
+    if (cell instanceof Node) {
+      td.appendChild(cell);
+    } else {
+      td.textContent = cell;
+    }
+
+    if (classes && classes[i]) {
+      td.className = classes[i];
+    }
+
+    tr.appendChild(td);
+  });
+
+  return tr;
+}
+
+async function loadApps() {
+  const body = document.getElementById('apps-body');
+  const {profiles} = await requestJSON('/api/profile');
+
+  body.replaceChildren();
+  profiles.sort((a, b) => a.key.localeCompare(b.key) || (a.channel || '').localeCompare(b.channel || ''));
+  for (const profile of profiles) {
+    const tr = row(
+      [profile.key, profile.channel || 'default', formatBytes(profile.size), formatAge(profile.lastModified)],
+      ['', '', 'number', 'number'],
+    );
+
+    tr.addEventListener('click', () => {
+      body.querySelectorAll('tr').forEach((other) => other.classList.remove('selected'));
+      tr.classList.add('selected');
+      selectApp(profile.key, profile.channel || '').catch(showError);
+    });
+
+    body.appendChild(tr);
+  }
+}
+
+async function selectApp(app, channel) {
+  showError(null);
+  state.app = app;
+  state.channel = channel;
+
+  document.getElementById('details').hidden = false;
+  document.getElementById('details-title').textContent = channel ? app + ' (' + channel + ')' : app;
+
+  const [graph, summary, versions] = await Promise.all([
+    requestJSON(profilePath(app, channel, 'flamegraph')),
+    requestJSON(profilePath(app, channel, 'summary') + '?top=25'),
+    requestJSON(profilePath(app, channel, 'versions')),
+  ]);
+
+  renderSummary(summary);
+  renderVersions(versions.versions);
+
+  state.graph = graph;
+  linkParents(graph, null);
+  zoomTo(graph);
+}
+
+function renderSummary(summary) {
+  document.getElementById('summary-stats').textContent = [
+    summary.totalSamples + ' samples',
+    formatDuration(summary.cpuTime) + ' CPU time',
+    summary.uploads + ' uploads',
+    summary.functions + ' functions',
+  ].join(' · ');
+
+  const body = document.getElementById('top-body');
+  body.replaceChildren();
+  for (const fn of summary.topFlat) {
+    body.appendChild(row([
+      formatDuration(fn.flat),
+      formatPercent(fn.flat, summary.cpuTime),
+      formatDuration(fn.cumulative),
+      formatPercent(fn.cumulative, summary.cpuTime),
+      fn.name,
+    ], ['number', 'number', 'number', 'number', 'function']));
+  }
+}
+
+function renderVersions(versions) {
+  const body = document.getElementById('versions-body');
+  body.replaceChildren();
+  for (const version of versions) {
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.textContent = 'Download';
+    button.addEventListener('click', () => download(version.version).catch(showError));
+
+    body.appendChild(row(
+      [String(version.version), formatBytes(version.size), formatAge(version.lastModified), button],
+      ['number', 'number', 'number', ''],
+    ));
+  }
+}
+
+async function download(version) {
+  let path = profilePath(state.app, state.channel, '');
+  if (version) {
+    path += '?version=' + version;
+  }
+
+  const resp = await request(path);
+  const url = URL.createObjectURL(await resp.blob());
+
+  // The go command expects a profile named default.pgo within the main package.
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = 'default.pgo';
+  link.click();
+
+  URL.revokeObjectURL(url);
+}
+
+function linkParents(node, parent) {
+  node.parent = parent;
+  for (const child of node.children || []) {
+    linkParents(child, node);
+  }
+}
+
+function zoomTo(node) {
+  state.zoom = node;
+  document.getElementById('flame-reset').hidden = node === state.graph;
+  document.getElementById('flame-zoom').textContent = node === state.graph ? '' : node.name;
+  drawFlameGraph();
+}
+
+// layoutFrames positions each frame of the flame graph, where the zoomed frame and its ancestors span the full width
+// and its descendants are sized relative to it. Frames too narrow to see are skipped.
+function layoutFrames(width) {
+  const frames = [];
+  const ancestors = [];
+  for (let node = state.zoom.parent; node; node = node.parent) {
+    ancestors.unshift(node);
+  }
+
+  ancestors.forEach((node, depth) => frames.push({node, x: 0, width, depth}));
+
+  const visit = (node, x, nodeWidth, depth) => {
+    frames.push({node, x, width: nodeWidth, depth});
+
+    let childX = x;
+    for (const child of node.children || []) {
+      const childWidth = nodeWidth * child.value / node.value;
+      if (childWidth >= 0.5) {
+        visit(child, childX, childWidth, depth + 1);
+      }
+
+      childX += childWidth;
+    }
+  };
+
+  visit(state.zoom, 0, width, ancestors.length);
+  return frames;
+}
+
+function frameColor(name) {
+  let hash = 0;
+  for (let i = 0; i < name.length; i++) {
+    hash = (hash * 31 + name.charCodeAt(i)) | 0;
+  }
+
+  const hue = 10 + Math.abs(hash) % 40;
+  const lightness = 55 + Math.abs(hash >> 8) % 15;
+  return 'hsl(' + hue + ', 85%, ' + lightness + '%)';
+}
+
+function drawFlameGraph() {
+  const canvas = document.getElementById('flame-canvas');
+  const width = canvas.clientWidth;
+  const frames = layoutFrames(width);
+  const depth = frames.reduce((max, frame) => Math.max(max, frame.depth), 0) + 1;
+  const height = depth * rowHeight;
+  const ratio = window.devicePixelRatio || 1;
+
+  canvas.width = width * ratio;
+  canvas.height = height * ratio;
+  canvas.style.height = height + 'px';
+
+  const ctx = canvas.getContext('2d');
+  ctx.scale(ratio, ratio);
+  ctx.font = '12px ui-monospace, monospace';
+  ctx.textBaseline = 'middle';
+
+  for (const frame of frames) {
+    const y = frame.depth * rowHeight;
+
+    ctx.fillStyle = frameColor(frame.node.name);
+    ctx.fillRect(frame.x, y, Math.max(frame.width - 1, 0.5), rowHeight - 1);
+
+    if (frame.width > 30) {
+      let label = frame.node.name;
+      const maxChars = Math.floor((frame.width - 8) / 7);
+      if (label.length > maxChars) {
+        label = label.slice(0, Math.max(maxChars - 1, 1)) + '…';
+      }
+
+      ctx.fillStyle = '#1f2328';
+      ctx.fillText(label, frame.x + 4, y + rowHeight / 2);
+    }
+  }
+
+  state.frames = frames;
+}
+
+function frameAt(event) {
+  const canvas = document.getElementById('flame-canvas');
+  const rect = canvas.getBoundingClientRect();
+  const x = event.clientX - rect.left;
+  const depth = Math.floor((event.clientY - rect.top) / rowHeight);
+
+  return state.frames.find((frame) => frame.depth === depth && x >= frame.x && x < frame.x + frame.width);
+}
+
+function init() {
+  const input = document.getElementById('token');
+  input.value = token();
+
+  document.getElementById('token-form').addEventListener('submit', (event) => {
+    event.preventDefault();
+    localStorage.setItem(tokenKey, input.value);
+    showError(null);
+    loadApps().catch(showError);
+  });
+
+  document.getElementById('download').addEventListener('click', () => download(0).catch(showError));
+  document.getElementById('flame-reset').addEventListener('click', () => zoomTo(state.graph));
+
+  const canvas = document.getElementById('flame-canvas');
+  const tooltip = document.getElementById('flame-tooltip');
+
+  canvas.addEventListener('click', (event) => {
+    const frame = frameAt(event);
+    if (frame) {
+      zoomTo(frame.node);
+    }
+  });
+
+  canvas.addEventListener('mousemove', (event) => {
+    const frame = frameAt(event);
+    if (!frame) {
+      tooltip.hidden = true;
+      return;
+    }
+
+    const rect = canvas.getBoundingClientRect();
+    tooltip.textContent = frame.node.name + ' · ' + formatDuration(frame.node.value) + ' (' +
+      formatPercent(frame.node.value, state.graph.value) + ')';
+    tooltip.style.left = Math.min(event.clientX - rect.left + 12, rect.width - 240) + 'px';
+    tooltip.style.top = (event.clientY - rect.top + 12) + 'px';
+    tooltip.hidden = false;
+  });
+
+  canvas.addEventListener('mouseleave', () => {
+    tooltip.hidden = true;
+  });
+
+  window.addEventListener('resize', () => {
+    if (state.zoom) {
+      drawFlameGraph();
+    }
+  });
+
+  loadApps().catch(showError);
+}
+
+init();
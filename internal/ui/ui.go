@@ -0,0 +1,41 @@
+// Package ui provides the web interface used to browse the profiles stored by the server. Its assets are embedded
+// within the binary and read profiles using the server's API, so requests made by the interface are authenticated the
+// same way as those made by the CLI.
+package ui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+var (
+	//go:embed static
+	static embed.FS
+)
+
+type (
+	// The HTTPController type is used to serve the web interface.
+	HTTPController struct {
+		files http.Handler
+	}
+)
+
+// NewHTTPController returns a new instance of the HTTPController type that serves the embedded web interface.
+func NewHTTPController() *HTTPController {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		// The static directory is embedded at compile time, so can only be missing if the embed directive changes.
+		panic(err)
+	}
+
+	return &HTTPController{
+		files: http.StripPrefix("/ui/", http.FileServerFS(files)),
+	}
+}
+
+// Register endpoints onto the http.ServeMux.
+func (h *HTTPController) Register(m *http.ServeMux) {
+	m.Handle("GET /ui/", h.files)
+	m.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+}
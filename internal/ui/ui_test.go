@@ -0,0 +1,74 @@
+package ui_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/davidsbond/autopgo/internal/ui"
+)
+
+func TestHTTPController(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	ui.NewHTTPController().Register(mux)
+
+	tt := []struct {
+		Name             string
+		Path             string
+		ExpectedStatus   int
+		ExpectedType     string
+		ExpectedLocation string
+	}{
+		{
+			Name:           "serves the index",
+			Path:           "/ui/",
+			ExpectedStatus: http.StatusOK,
+			ExpectedType:   "text/html; charset=utf-8",
+		},
+		{
+			Name:           "serves scripts",
+			Path:           "/ui/app.js",
+			ExpectedStatus: http.StatusOK,
+			ExpectedType:   "text/javascript; charset=utf-8",
+		},
+		{
+			Name:           "serves stylesheets",
+			Path:           "/ui/style.css",
+			ExpectedStatus: http.StatusOK,
+			ExpectedType:   "text/css; charset=utf-8",
+		},
+		{
+			Name:           "unknown file",
+			Path:           "/ui/unknown.js",
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:             "redirects the root",
+			Path:             "/",
+			ExpectedStatus:   http.StatusFound,
+			ExpectedLocation: "/ui/",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+
+			mux.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+			if tc.ExpectedType != "" {
+				assert.Equal(t, tc.ExpectedType, w.Header().Get("Content-Type"))
+			}
+
+			if tc.ExpectedLocation != "" {
+				assert.Equal(t, tc.ExpectedLocation, w.Header().Get("Location"))
+			}
+		})
+	}
+}
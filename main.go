@@ -19,13 +19,20 @@ import (
 
 	"github.com/davidsbond/autopgo/cmd/clean"
 	delete "github.com/davidsbond/autopgo/cmd/delete"
+	"github.com/davidsbond/autopgo/cmd/diff"
 	"github.com/davidsbond/autopgo/cmd/download"
+	"github.com/davidsbond/autopgo/cmd/inspect"
 	"github.com/davidsbond/autopgo/cmd/list"
+	"github.com/davidsbond/autopgo/cmd/pin"
+	"github.com/davidsbond/autopgo/cmd/promote"
 	"github.com/davidsbond/autopgo/cmd/scrape"
 	"github.com/davidsbond/autopgo/cmd/server"
+	"github.com/davidsbond/autopgo/cmd/staged"
+	"github.com/davidsbond/autopgo/cmd/unpin"
 	"github.com/davidsbond/autopgo/cmd/upload"
 	"github.com/davidsbond/autopgo/cmd/worker"
 	"github.com/davidsbond/autopgo/internal/logger"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 var (
@@ -38,7 +45,9 @@ func main() {
 	defer cancel()
 
 	var (
-		logLevel string
+		logLevel     string
+		otlpEndpoint string
+		shutdown     func(context.Context) error
 	)
 
 	cmd := &cobra.Command{
@@ -63,12 +72,19 @@ func main() {
 		CompletionOptions: cobra.CompletionOptions{
 			DisableDefaultCmd: true,
 		},
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			ctx = logger.ToContext(ctx, slog.New(
 				slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logger.LevelFromString(logLevel)}),
 			))
 
+			var err error
+			shutdown, err = tracing.Init(ctx, otlpEndpoint, "autopgo-"+cmd.Name(), version)
+			if err != nil {
+				return err
+			}
+
 			cmd.SetContext(ctx)
+			return nil
 		},
 	}
 
@@ -86,10 +102,17 @@ func main() {
 		list.Command(),
 		delete.Command(),
 		clean.Command(),
+		promote.Command(),
+		pin.Command(),
+		unpin.Command(),
+		inspect.Command(),
+		diff.Command(),
+		staged.Command(),
 	)
 
 	flags := cmd.PersistentFlags()
 	flags.StringVarP(&logLevel, "log-level", "l", "info", "Sets the minimum log level (debug, info, warn or error)")
+	flags.StringVar(&otlpEndpoint, "otlp-endpoint", "", "The URL of an OTLP HTTP endpoint to export traces to")
 
 	v := viper.New()
 	v.AutomaticEnv()
@@ -100,7 +123,14 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	err := cmd.ExecuteContext(ctx)
+	if shutdown != nil {
+		if err := shutdown(context.Background()); err != nil {
+			logger.FromContext(ctx).With(slog.String("error", err.Error())).Error("failed to flush traces")
+		}
+	}
+
+	if err != nil {
 		os.Exit(1)
 	}
 }
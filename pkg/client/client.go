@@ -1,94 +1,216 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/davidsbond/autopgo/internal/api"
 	"github.com/davidsbond/autopgo/internal/closers"
 	"github.com/davidsbond/autopgo/internal/logger"
 	"github.com/davidsbond/autopgo/internal/profile"
+	"github.com/davidsbond/autopgo/internal/tracing"
 )
 
 type (
 	// The Client type is used to interact with the profile server.
 	Client struct {
 		baseURL string
+		token   string
 		http    *http.Client
+		targets *http.Client
 	}
+
+	// The Option type is a function that modifies the configuration of a Client.
+	Option func(c *Client)
 )
 
 // New returns a new instance of the Client type that makes HTTP requests to the provided base URL.
-func New(baseURL string) *Client {
-	return &Client{
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		http: &http.Client{
-			Timeout: time.Minute,
+			Timeout:   time.Minute,
+			Transport: tracing.Transport(http.DefaultTransport),
+		},
+		// Profiling a target takes as long as the requested duration, so requests to targets have their timeouts set
+		// per request instead.
+		targets: &http.Client{
+			Transport: tracing.Transport(http.DefaultTransport),
 		},
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithTLSConfig returns an Option that sets the TLS configuration used when making HTTPS requests to the profile
+// server. Requests to profile targets use the default TLS configuration, so they do not present client certificates
+// meant for the profile server.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		c.http.Transport = tracing.Transport(transport)
+	}
+}
+
+// LoadTLSConfig returns a tls.Config for communicating with a profile server that uses certificates issued by a
+// private CA or requires client certificates. The caFile contains PEM-encoded CA certificates that are trusted in
+// addition to the system's certificates. The certFile and keyFile contain the PEM-encoded client certificate and its
+// key. All parameters are optional, although certFile and keyFile must be provided together.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	switch {
+	case certFile != "" && keyFile != "":
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	case certFile != "" || keyFile != "":
+		return nil, errors.New("both a client certificate and key are required")
+	}
+
+	return config, nil
+}
+
+// WithToken returns an Option that sets the bearer token sent with requests to the profile server. Requests to
+// profile targets do not include the token or any other credentials for the profile server.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
 }
 
 // Upload the contents of an application's profile to the profile server.
 func (c *Client) Upload(ctx context.Context, app string, r io.Reader) error {
+	return c.UploadChannel(ctx, app, "", r)
+}
+
+// UploadChannel uploads the contents of an application's profile to a channel on the profile server. An empty channel
+// uploads to the default channel.
+func (c *Client) UploadChannel(ctx context.Context, app, channel string, r io.Reader) error {
+	_, err := c.UploadWithOptions(ctx, app, UploadOptions{Channel: channel}, r)
+	return err
+}
+
+type (
+	// The UploadOptions type contains optional parameters for uploading a profile.
+	UploadOptions struct {
+		// The channel to upload the profile to, the default channel is used when empty.
+		Channel string
+		// Identifies the upload, so that retries using the same key are not merged more than once.
+		IdempotencyKey string
+	}
+)
+
+// UploadWithOptions writes the profile data stored within the io.Reader implementation to the profile server for a
+// specified application, using the provided UploadOptions. The response describes where the profile was stored and
+// whether it had already been uploaded.
+func (c *Client) UploadWithOptions(ctx context.Context, app string, opts UploadOptions, r io.Reader) (profile.UploadResponse, error) {
 	u, err := url.Parse(c.baseURL)
 	if err != nil {
-		return err
+		return profile.UploadResponse{}, err
 	}
 
-	u.Path = path.Join("/api", "profile", app)
+	u.Path = profilePath(app, opts.Channel)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), r)
 	if err != nil {
-		return err
+		return profile.UploadResponse{}, err
 	}
 
-	logger.FromContext(ctx).With(
-		slog.String("http.url", req.URL.String()),
-		slog.String("http.method", req.Method),
-	).DebugContext(ctx, "performing HTTP request")
+	if opts.IdempotencyKey != "" {
+		req.Header.Set(profile.IdempotencyKeyHeader, opts.IdempotencyKey)
+	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return err
+		return profile.UploadResponse{}, err
 	}
 	defer closers.Close(ctx, resp.Body)
 
-	if resp.StatusCode != http.StatusCreated {
-		return bodyToError(resp.Body)
+	// Duplicate uploads receive a 200 rather than a 201, as nothing new was stored.
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return profile.UploadResponse{}, bodyToError(resp.Body)
 	}
 
-	return nil
+	var upload profile.UploadResponse
+	if err = json.NewDecoder(resp.Body).Decode(&upload); err != nil {
+		return profile.UploadResponse{}, err
+	}
+
+	return upload, nil
 }
 
 // Download the profile for a specified application, writing its contents to the given io.Writer implementation. Returns
 // ErrNotExist if no profile exists for the application.
 func (c *Client) Download(ctx context.Context, app string, w io.Writer) error {
-	u, err := url.Parse(c.baseURL)
-	if err != nil {
-		return err
-	}
+	return c.DownloadWithOptions(ctx, app, DownloadOptions{}, w)
+}
 
-	u.Path = path.Join("/api", "profile", app)
+type (
+	// The DownloadOptions type contains optional parameters for downloading a profile.
+	DownloadOptions struct {
+		// The channel to download the profile from, the default channel is used when empty.
+		Channel string
+		// Channels to fall back to, in order, when the channel has no profile.
+		Fallback []string
+		// The generation of the profile to download, the current profile is downloaded when zero.
+		Version int
+	}
+)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+// DownloadWithOptions downloads the profile for an application as described by the DownloadOptions, writing its
+// contents to the given io.Writer implementation.
+func (c *Client) DownloadWithOptions(ctx context.Context, app string, opts DownloadOptions, w io.Writer) error {
+	req, err := c.downloadRequest(ctx, app, opts)
 	if err != nil {
 		return err
 	}
 
-	logger.FromContext(ctx).With(
-		slog.String("http.url", req.URL.String()),
-		slog.String("http.method", req.Method),
-	).DebugContext(ctx, "performing HTTP request")
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -105,19 +227,120 @@ func (c *Client) Download(ctx context.Context, app string, w io.Writer) error {
 	return nil
 }
 
+// DownloadFile downloads the profile for an application as described by the DownloadOptions to the named file. If the
+// file already contains the requested profile it is left untouched and false is returned.
+func (c *Client) DownloadFile(ctx context.Context, app string, opts DownloadOptions, name string) (bool, error) {
+	req, err := c.downloadRequest(ctx, app, opts)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := os.ReadFile(name)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		break
+	case err != nil:
+		return false, err
+	default:
+		req.Header.Set("If-None-Match", profile.ETag(existing))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+		break
+	default:
+		return false, bodyToError(resp.Body)
+	}
+
+	// The profile is written to a temporary file first so that a failed download does not leave a partial profile in
+	// place of the existing one.
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		closers.Close(ctx, f)
+		return false, err
+	}
+
+	if err = f.Chmod(0o644); err != nil {
+		closers.Close(ctx, f)
+		return false, err
+	}
+
+	if err = f.Close(); err != nil {
+		return false, err
+	}
+
+	if err = os.Rename(f.Name(), name); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (c *Client) downloadRequest(ctx context.Context, app string, opts DownloadOptions) (*http.Request, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = profilePath(app, opts.Channel)
+
+	query := url.Values{}
+	if opts.Version > 0 {
+		query.Set("version", strconv.Itoa(opts.Version))
+	}
+
+	if len(opts.Fallback) > 0 {
+		query.Set("fallback", strings.Join(opts.Fallback, ","))
+	}
+
+	u.RawQuery = query.Encode()
+
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
 // ProfileAndUpload profiles the provided src URL for the given duration. It then uploads the profile to the server
 // using the given application name.
 func (c *Client) ProfileAndUpload(ctx context.Context, app, src string, duration time.Duration) error {
-	u, err := url.Parse(src)
+	data, err := c.Profile(ctx, src, duration)
 	if err != nil {
 		return err
 	}
 
+	return c.Upload(ctx, app, bytes.NewReader(data))
+}
+
+// ProfileTimeoutMargin is the time allowed for a target to respond beyond the duration of the requested profile.
+const ProfileTimeoutMargin = 30 * time.Second
+
+// Profile the provided src URL for the given duration, returning the raw contents of the profile. The request times
+// out if the profile is not obtained within ProfileTimeoutMargin of the duration.
+func (c *Client) Profile(ctx context.Context, src string, duration time.Duration) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration+ProfileTimeoutMargin)
+	defer cancel()
+
 	u.RawQuery = "seconds=" + strconv.FormatFloat(duration.Seconds(), 'g', -1, 64)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	logger.FromContext(ctx).With(
@@ -125,39 +348,186 @@ func (c *Client) ProfileAndUpload(ctx context.Context, app, src string, duration
 		slog.String("http.method", req.Method),
 	).DebugContext(ctx, "performing HTTP request")
 
-	resp, err := c.http.Do(req)
+	resp, err := c.targets.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer closers.Close(ctx, resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("target endpoint returned %d", resp.StatusCode)
+		return nil, fmt.Errorf("target endpoint returned %d", resp.StatusCode)
 	}
 
-	return c.Upload(ctx, app, resp.Body)
+	return io.ReadAll(resp.Body)
 }
 
 // List all profiles stored within the server.
 func (c *Client) List(ctx context.Context) ([]profile.Profile, error) {
+	return c.ListWithOptions(ctx, ListOptions{})
+}
+
+type (
+	// The ListOptions type contains parameters for filtering and ordering the profiles returned when listing profiles.
+	ListOptions struct {
+		// Only include profiles for applications whose name begins with the prefix.
+		Prefix string
+		// Only include profiles modified at or after the given time.
+		ModifiedSince time.Time
+		// The order to list profiles in, one of profile.ListSortName, profile.ListSortSize or
+		// profile.ListSortLastModified, descending when prefixed with a hyphen. Profiles are ordered by name when empty.
+		Sort string
+		// The maximum number of profiles to return within a single page, profile.MaxListLimit is used when zero.
+		Limit int
+		// The token of the page to return, as given by a previous call to ListPage.
+		PageToken string
+	}
+)
+
+// ListWithOptions lists all profiles stored within the server that match the ListOptions, following every page of
+// results.
+func (c *Client) ListWithOptions(ctx context.Context, opts ListOptions) ([]profile.Profile, error) {
+	profiles := make([]profile.Profile, 0)
+	for p, err := range c.Profiles(ctx, opts) {
+		if err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	return profiles, nil
+}
+
+// Profiles returns an iterator over the profiles stored within the server that match the ListOptions, requesting
+// further pages of results as the iterator is consumed. Iteration stops after the first error.
+func (c *Client) Profiles(ctx context.Context, opts ListOptions) iter.Seq2[profile.Profile, error] {
+	return func(yield func(profile.Profile, error) bool) {
+		for {
+			page, err := c.ListPage(ctx, opts)
+			if err != nil {
+				yield(profile.Profile{}, err)
+				return
+			}
+
+			for _, p := range page.Profiles {
+				if !yield(p, nil) {
+					return
+				}
+			}
+
+			if page.NextPageToken == "" {
+				return
+			}
+
+			opts.PageToken = page.NextPageToken
+		}
+	}
+}
+
+// ListPage returns a single page of the profiles stored within the server that match the ListOptions. The response's
+// NextPageToken is set as ListOptions.PageToken to request the next page, and is empty on the last page.
+func (c *Client) ListPage(ctx context.Context, opts ListOptions) (profile.ListResponse, error) {
 	u, err := url.Parse(c.baseURL)
 	if err != nil {
-		return nil, err
+		return profile.ListResponse{}, err
 	}
 
 	u.Path = path.Join("/api", "profile")
 
+	query := url.Values{}
+	if opts.Prefix != "" {
+		query.Set("prefix", opts.Prefix)
+	}
+
+	if !opts.ModifiedSince.IsZero() {
+		query.Set("modified_since", opts.ModifiedSince.Format(time.RFC3339Nano))
+	}
+
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	if opts.PageToken != "" {
+		query.Set("page_token", opts.PageToken)
+	}
+
+	u.RawQuery = query.Encode()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return profile.ListResponse{}, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return profile.ListResponse{}, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return profile.ListResponse{}, bodyToError(resp.Body)
+	}
+
+	var list profile.ListResponse
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return profile.ListResponse{}, err
+	}
+
+	return list, nil
+}
+
+// Delete an application's profile, including all of its channels.
+func (c *Client) Delete(ctx context.Context, app string) error {
+	return c.DeleteChannel(ctx, app, "")
+}
+
+// DeleteChannel deletes the profile for a channel of an application. An empty channel deletes the entire application,
+// including all of its channels, whereas profile.DefaultChannel deletes only the default channel.
+func (c *Client) DeleteChannel(ctx context.Context, app, channel string) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = profilePath(app, channel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	return nil
+}
+
+// Versions lists the retained generations of the profile for an application's channel, newest first. An empty channel
+// lists the generations of the default channel.
+func (c *Client) Versions(ctx context.Context, app, channel string) ([]profile.Version, error) {
+	u, err := url.Parse(c.baseURL)
 	if err != nil {
 		return nil, err
 	}
 
-	logger.FromContext(ctx).With(
-		slog.String("http.url", req.URL.String()),
-		slog.String("http.method", req.Method),
-	).DebugContext(ctx, "performing HTTP request")
+	u.Path = profilePath(app, channel, "versions")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -167,33 +537,300 @@ func (c *Client) List(ctx context.Context) ([]profile.Profile, error) {
 		return nil, bodyToError(resp.Body)
 	}
 
-	var list profile.ListResponse
+	var list profile.VersionsResponse
 	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
 		return nil, err
 	}
 
-	return list.Profiles, nil
+	return list.Versions, nil
 }
 
-// Delete an application's profile.
-func (c *Client) Delete(ctx context.Context, app string) error {
+// Summary returns a summary of the merged profile for an application's channel, including the given number of top
+// functions by flat & cumulative CPU time. An empty channel summarises the default channel.
+func (c *Client) Summary(ctx context.Context, app, channel string, top int) (profile.Summary, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return profile.Summary{}, err
+	}
+
+	u.Path = profilePath(app, channel, "summary")
+	if top > 0 {
+		u.RawQuery = url.Values{"top": {strconv.Itoa(top)}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return profile.Summary{}, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return profile.Summary{}, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return profile.Summary{}, bodyToError(resp.Body)
+	}
+
+	var summary profile.Summary
+	if err = json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return profile.Summary{}, err
+	}
+
+	return summary, nil
+}
+
+// Edges returns the hot call sites of the merged profile for an application's channel, weighted as the compiler does
+// when building with PGO. An empty channel inspects the default channel.
+func (c *Client) Edges(ctx context.Context, app, channel string) (profile.EdgeReport, error) {
 	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return profile.EdgeReport{}, err
+	}
+
+	u.Path = profilePath(app, channel, "edges")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return profile.EdgeReport{}, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return profile.EdgeReport{}, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return profile.EdgeReport{}, bodyToError(resp.Body)
+	}
+
+	var report profile.EdgeReport
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return profile.EdgeReport{}, err
+	}
+
+	return report, nil
+}
+
+type (
+	// The DiffOptions type contains parameters for comparing two of an application's profiles.
+	DiffOptions struct {
+		// The channel of the target profile, the default channel is used when empty.
+		Channel string
+		// The generation of the target profile, the current profile is used when zero.
+		Version int
+		// The channel of the base profile, the target channel is used when empty.
+		BaseChannel string
+		// The generation of the base profile, the current profile is used when zero.
+		BaseVersion int
+		// The number of functions to include in each list of functions, the server's default is used when zero.
+		Top int
+	}
+)
+
+// Diff compares two of an application's profiles as described by the DiffOptions, returning the functions whose share
+// of CPU time changed the most between them.
+func (c *Client) Diff(ctx context.Context, app string, opts DiffOptions) (profile.Diff, error) {
+	req, err := c.diffRequest(ctx, app, opts, "")
+	if err != nil {
+		return profile.Diff{}, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return profile.Diff{}, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return profile.Diff{}, bodyToError(resp.Body)
+	}
+
+	var diff profile.Diff
+	if err = json.NewDecoder(resp.Body).Decode(&diff); err != nil {
+		return profile.Diff{}, err
+	}
+
+	return diff, nil
+}
+
+// DiffBase compares two of an application's profiles as described by the DiffOptions, writing a pprof diff-base
+// profile of their differences to the given io.Writer implementation.
+func (c *Client) DiffBase(ctx context.Context, app string, opts DiffOptions, w io.Writer) error {
+	req, err := c.diffRequest(ctx, app, opts, profile.DiffFormatPprof)
 	if err != nil {
 		return err
 	}
 
-	u.Path = path.Join("/api", "profile", app)
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	defer closers.Close(ctx, resp.Body)
 
-	logger.FromContext(ctx).With(
-		slog.String("http.url", req.URL.String()),
-		slog.String("http.method", req.Method),
-	).DebugContext(ctx, "performing HTTP request")
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *Client) diffRequest(ctx context.Context, app string, opts DiffOptions, format string) (*http.Request, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = profilePath(app, opts.Channel, "diff")
+
+	query := url.Values{}
+	if opts.Version > 0 {
+		query.Set("version", strconv.Itoa(opts.Version))
+	}
+
+	if opts.BaseChannel != "" {
+		query.Set("base_channel", opts.BaseChannel)
+	}
+
+	if opts.BaseVersion > 0 {
+		query.Set("base_version", strconv.Itoa(opts.BaseVersion))
+	}
+
+	if opts.Top > 0 {
+		query.Set("top", strconv.Itoa(opts.Top))
+	}
+
+	if format != "" {
+		query.Set("format", format)
+	}
+
+	u.RawQuery = query.Encode()
+
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// Rollback restores a previous generation of the profile for an application's channel as its current profile. An
+// empty channel rolls back the default channel.
+func (c *Client) Rollback(ctx context.Context, app, channel string, version int) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = profilePath(app, channel, "rollback")
+
+	body, err := json.Marshal(profile.RollbackRequest{Version: version})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	return nil
+}
+
+// Promote copies a generation of the profile for an application's channel to the application's stable profile, which
+// is served from the default channel.
+func (c *Client) Promote(ctx context.Context, app, channel string, version int) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = profilePath(app, "", "promote")
+
+	body, err := json.Marshal(profile.PromoteRequest{Channel: channel, Version: version})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	return nil
+}
+
+// Promotions returns the promotions made to an application's stable profile, newest first.
+func (c *Client) Promotions(ctx context.Context, app string) ([]profile.Promotion, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = profilePath(app, "", "promotions")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, bodyToError(resp.Body)
+	}
 
-	resp, err := c.http.Do(req)
+	var list profile.PromotionsResponse
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	return list.Promotions, nil
+}
+
+// Pin pins the profile of an application's channel, so that downloads are served the given generation until it is
+// unpinned. A version of zero pins the current profile. An empty channel pins the default channel.
+func (c *Client) Pin(ctx context.Context, app, channel string, version int) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = profilePath(app, channel, "pin")
+
+	body, err := json.Marshal(profile.PinRequest{Version: version})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -206,6 +843,124 @@ func (c *Client) Delete(ctx context.Context, app string) error {
 	return nil
 }
 
+// Unpin unpins the profile of an application's channel, so that downloads are served its current profile. An empty
+// channel unpins the default channel.
+func (c *Client) Unpin(ctx context.Context, app, channel string) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = profilePath(app, channel, "unpin")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	return nil
+}
+
+// Staged returns the profiles uploaded to an application's channel that are waiting to be merged, oldest first. An
+// empty channel lists those of the default channel.
+func (c *Client) Staged(ctx context.Context, app, channel string) ([]profile.StagedUpload, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = profilePath(app, channel, "staging")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, bodyToError(resp.Body)
+	}
+
+	var list profile.StagedResponse
+	if err = json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	return list.Uploads, nil
+}
+
+// Requeue publishes the upload event of a profile staged for an application's channel again, so that the worker
+// attempts to merge it. An empty channel requeues an upload to the default channel.
+func (c *Client) Requeue(ctx context.Context, app, channel, id string) error {
+	return c.stagedRequest(ctx, http.MethodPost, profilePath(app, channel, "staging", id, "requeue"))
+}
+
+// Discard deletes a profile staged for an application's channel without merging it. An empty channel discards an
+// upload to the default channel.
+func (c *Client) Discard(ctx context.Context, app, channel, id string) error {
+	return c.stagedRequest(ctx, http.MethodDelete, profilePath(app, channel, "staging", id))
+}
+
+func (c *Client) stagedRequest(ctx context.Context, method, p string) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = p
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closers.Close(ctx, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return bodyToError(resp.Body)
+	}
+
+	return nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	logger.FromContext(ctx).With(
+		slog.String("http.url", req.URL.String()),
+		slog.String("http.method", req.Method),
+	).DebugContext(ctx, "performing HTTP request")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.http.Do(req)
+}
+
+func profilePath(app, channel string, elem ...string) string {
+	return path.Join(append([]string{"/api", "profile", app, channel}, elem...)...)
+}
+
 func bodyToError(body io.Reader) error {
 	var apiErr api.Error
 	if err := json.NewDecoder(body).Decode(&apiErr); err != nil {
@@ -3,9 +3,14 @@ package client_test
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -76,12 +81,89 @@ func TestClient_Upload(t *testing.T) {
 	}
 }
 
+func TestClient_UploadWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		App          string
+		Options      client.UploadOptions
+		Setup        func(t *testing.T) http.Handler
+		Expected     profile.UploadResponse
+		ExpectsError bool
+	}{
+		{
+			Name:    "upload with idempotency key",
+			App:     "test",
+			Options: client.UploadOptions{Channel: "canary", IdempotencyKey: "abc"},
+			Expected: profile.UploadResponse{
+				Key: "test/channels/canary/staging/1",
+			},
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, "/api/profile/test/canary", r.URL.Path)
+					assert.EqualValues(t, "abc", r.Header.Get(profile.IdempotencyKeyHeader))
+					api.Respond(r.Context(), w, http.StatusCreated, profile.UploadResponse{
+						Key: "test/channels/canary/staging/1",
+					})
+				})
+			},
+		},
+		{
+			Name: "duplicate upload",
+			App:  "test",
+			Expected: profile.UploadResponse{
+				Key:       "test/staging/1",
+				Duplicate: true,
+			},
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.Empty(t, r.Header.Get(profile.IdempotencyKeyHeader))
+					api.Respond(r.Context(), w, http.StatusOK, profile.UploadResponse{
+						Key:       "test/staging/1",
+						Duplicate: true,
+					})
+				})
+			},
+		},
+		{
+			Name:         "returns errors",
+			App:          "test",
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					api.ErrorResponse(r.Context(), w, "uh oh", http.StatusBadRequest)
+				})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := tc.Setup(t)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			cl := client.New(server.URL)
+			actual, err := cl.UploadWithOptions(context.Background(), tc.App, tc.Options, bytes.NewReader([]byte("test")))
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
 func TestClient_Download(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
 		Name         string
 		App          string
+		Options      client.DownloadOptions
 		Expected     []byte
 		Setup        func(t *testing.T) http.Handler
 		ExpectsError bool
@@ -94,6 +176,37 @@ func TestClient_Download(t *testing.T) {
 				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					assert.EqualValues(t, http.MethodGet, r.Method)
 					assert.EqualValues(t, "/api/profile/test", r.URL.Path)
+					assert.Empty(t, r.URL.Query().Get("version"))
+
+					_, err := io.Copy(w, bytes.NewReader([]byte("test")))
+					require.NoError(t, err)
+				})
+			},
+		},
+		{
+			Name:     "successful version download",
+			App:      "test",
+			Options:  client.DownloadOptions{Version: 3},
+			Expected: []byte("test"),
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, "/api/profile/test", r.URL.Path)
+					assert.EqualValues(t, "3", r.URL.Query().Get("version"))
+
+					_, err := io.Copy(w, bytes.NewReader([]byte("test")))
+					require.NoError(t, err)
+				})
+			},
+		},
+		{
+			Name:     "successful channel download with fallback",
+			App:      "test",
+			Options:  client.DownloadOptions{Channel: "canary", Fallback: []string{"prod", "default"}},
+			Expected: []byte("test"),
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, "/api/profile/test/canary", r.URL.Path)
+					assert.EqualValues(t, "prod,default", r.URL.Query().Get("fallback"))
 
 					_, err := io.Copy(w, bytes.NewReader([]byte("test")))
 					require.NoError(t, err)
@@ -130,7 +243,7 @@ func TestClient_Download(t *testing.T) {
 
 			cl := client.New(server.URL)
 			data := bytes.NewBuffer(nil)
-			err := cl.Download(context.Background(), tc.App, data)
+			err := cl.DownloadWithOptions(context.Background(), tc.App, tc.Options, data)
 			if tc.ExpectsError {
 				assert.Error(t, err)
 				return
@@ -142,11 +255,70 @@ func TestClient_Download(t *testing.T) {
 	}
 }
 
+func TestClient_DownloadFile(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := profile.ETag([]byte("test"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, err := w.Write([]byte("test"))
+		require.NoError(t, err)
+	})
+
+	tt := []struct {
+		Name            string
+		Existing        []byte
+		ExpectedWritten bool
+	}{
+		{
+			Name:            "no existing file",
+			ExpectedWritten: true,
+		},
+		{
+			Name:            "existing file is unchanged",
+			Existing:        []byte("test"),
+			ExpectedWritten: false,
+		},
+		{
+			Name:            "existing file is stale",
+			Existing:        []byte("stale"),
+			ExpectedWritten: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			name := filepath.Join(t.TempDir(), "default.pgo")
+			if tc.Existing != nil {
+				require.NoError(t, os.WriteFile(name, tc.Existing, 0o644))
+			}
+
+			cl := client.New(server.URL)
+			written, err := cl.DownloadFile(context.Background(), "test", client.DownloadOptions{}, name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedWritten, written)
+
+			actual, err := os.ReadFile(name)
+			require.NoError(t, err)
+			assert.EqualValues(t, "test", actual)
+		})
+	}
+}
+
 func TestClient_List(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
 		Name         string
+		Opts         client.ListOptions
 		Setup        func(t *testing.T) http.Handler
 		ExpectsError bool
 		Expected     []profile.Profile
@@ -177,6 +349,50 @@ func TestClient_List(t *testing.T) {
 				})
 			},
 		},
+		{
+			Name: "follows pages",
+			Opts: client.ListOptions{
+				Prefix:        "te",
+				ModifiedSince: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sort:          "-size",
+				Limit:         1,
+			},
+			Expected: []profile.Profile{
+				{Key: "test", Size: 2000},
+				{Key: "test", Channel: "prod", Size: 1000},
+			},
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					query := r.URL.Query()
+					assert.EqualValues(t, "te", query.Get("prefix"))
+					assert.EqualValues(t, "2020-01-01T00:00:00Z", query.Get("modified_since"))
+					assert.EqualValues(t, "-size", query.Get("sort"))
+					assert.EqualValues(t, "1", query.Get("limit"))
+
+					if query.Get("page_token") == "" {
+						api.Respond(r.Context(), w, http.StatusOK, profile.ListResponse{
+							Profiles:      []profile.Profile{{Key: "test", Size: 2000}},
+							NextPageToken: "next",
+						})
+						return
+					}
+
+					assert.EqualValues(t, "next", query.Get("page_token"))
+					api.Respond(r.Context(), w, http.StatusOK, profile.ListResponse{
+						Profiles: []profile.Profile{{Key: "test", Channel: "prod", Size: 1000}},
+					})
+				})
+			},
+		},
+		{
+			Name:         "error response",
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					api.ErrorResponse(r.Context(), w, "invalid sort", http.StatusBadRequest)
+				})
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -186,7 +402,7 @@ func TestClient_List(t *testing.T) {
 			defer server.Close()
 
 			cl := client.New(server.URL)
-			actual, err := cl.List(context.Background())
+			actual, err := cl.ListWithOptions(context.Background(), tc.Opts)
 			if tc.ExpectsError {
 				assert.Error(t, err)
 				return
@@ -256,3 +472,328 @@ func TestClient_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_Promote(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		App          string
+		Channel      string
+		Version      int
+		Setup        func(t *testing.T) http.Handler
+		ExpectsError bool
+	}{
+		{
+			Name:    "successful promotion",
+			App:     "test",
+			Channel: "candidate",
+			Version: 3,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, http.MethodPost, r.Method)
+					assert.EqualValues(t, "/api/profile/test/promote", r.URL.Path)
+
+					var request profile.PromoteRequest
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+					assert.EqualValues(t, profile.PromoteRequest{Channel: "candidate", Version: 3}, request)
+				})
+			},
+		},
+		{
+			Name:         "version not found",
+			App:          "test",
+			Channel:      "candidate",
+			Version:      3,
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					api.ErrorResponse(r.Context(), w, "uh oh", http.StatusNotFound)
+				})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := tc.Setup(t)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			cl := client.New(server.URL)
+			err := cl.Promote(context.Background(), tc.App, tc.Channel, tc.Version)
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_Diff(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		App          string
+		Options      client.DiffOptions
+		Setup        func(t *testing.T) http.Handler
+		Expected     profile.Diff
+		ExpectsError bool
+	}{
+		{
+			Name: "successful diff",
+			App:  "test",
+			Options: client.DiffOptions{
+				Channel:     "canary",
+				BaseChannel: "default",
+				BaseVersion: 3,
+				Top:         5,
+			},
+			Expected: profile.Diff{Similarity: 0.5},
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, http.MethodGet, r.Method)
+					assert.EqualValues(t, "/api/profile/test/canary/diff", r.URL.Path)
+					assert.EqualValues(t, "base_channel=default&base_version=3&top=5", r.URL.RawQuery)
+
+					api.Respond(r.Context(), w, http.StatusOK, profile.Diff{Similarity: 0.5})
+				})
+			},
+		},
+		{
+			Name:         "profile not found",
+			App:          "test",
+			Options:      client.DiffOptions{BaseVersion: 3},
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					api.ErrorResponse(r.Context(), w, "uh oh", http.StatusNotFound)
+				})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := tc.Setup(t)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			cl := client.New(server.URL)
+			actual, err := cl.Diff(context.Background(), tc.App, tc.Options)
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestClient_Pin(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		App          string
+		Channel      string
+		Version      int
+		Setup        func(t *testing.T) http.Handler
+		ExpectsError bool
+	}{
+		{
+			Name:    "successful pin",
+			App:     "test",
+			Channel: "canary",
+			Version: 3,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, http.MethodPost, r.Method)
+					assert.EqualValues(t, "/api/profile/test/canary/pin", r.URL.Path)
+
+					var request profile.PinRequest
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+					assert.EqualValues(t, 3, request.Version)
+				})
+			},
+		},
+		{
+			Name:         "profile not found",
+			App:          "test",
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					api.ErrorResponse(r.Context(), w, "uh oh", http.StatusNotFound)
+				})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := tc.Setup(t)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			cl := client.New(server.URL)
+			err := cl.Pin(context.Background(), tc.App, tc.Channel, tc.Version)
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_Staged(t *testing.T) {
+	t.Parallel()
+
+	uploads := []profile.StagedUpload{
+		{
+			ID:         "1730477575747397000",
+			Key:        "test/channels/prod/staging/1730477575747397000",
+			Size:       1000,
+			UploadedAt: time.Unix(0, 1730477575747397000).UTC(),
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/profile/test/prod/staging", func(w http.ResponseWriter, r *http.Request) {
+		api.Respond(r.Context(), w, http.StatusOK, profile.StagedResponse{Uploads: uploads})
+	})
+	mux.HandleFunc("POST /api/profile/test/prod/staging/1730477575747397000/requeue", func(w http.ResponseWriter, r *http.Request) {
+		api.Respond(r.Context(), w, http.StatusOK, profile.RequeueResponse{})
+	})
+	mux.HandleFunc("DELETE /api/profile/test/prod/staging/1730477575747397000", func(w http.ResponseWriter, r *http.Request) {
+		api.ErrorResponse(r.Context(), w, "staged upload does not exist", http.StatusNotFound)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := client.New(server.URL)
+
+	actual, err := cl.Staged(ctx, "test", "prod")
+	require.NoError(t, err)
+	assert.EqualValues(t, uploads, actual)
+
+	require.NoError(t, cl.Requeue(ctx, "test", "prod", "1730477575747397000"))
+	assert.Error(t, cl.Discard(ctx, "test", "prod", "1730477575747397000"))
+}
+
+func TestClient_Profile(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name         string
+		Duration     time.Duration
+		Expected     []byte
+		Setup        func(t *testing.T) http.Handler
+		ExpectsError bool
+	}{
+		{
+			Name:     "successful profile",
+			Duration: 30 * time.Second,
+			Expected: []byte("test"),
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.EqualValues(t, http.MethodGet, r.Method)
+					assert.EqualValues(t, "/debug/pprof/profile", r.URL.Path)
+					assert.EqualValues(t, "30", r.URL.Query().Get("seconds"))
+
+					_, err := io.Copy(w, bytes.NewReader([]byte("test")))
+					require.NoError(t, err)
+				})
+			},
+		},
+		{
+			Name:         "returns errors",
+			Duration:     30 * time.Second,
+			ExpectsError: true,
+			Setup: func(t *testing.T) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				})
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := tc.Setup(t)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			cl := client.New(server.URL)
+			actual, err := cl.Profile(context.Background(), server.URL+"/debug/pprof/profile", tc.Duration)
+			if tc.ExpectsError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.EqualValues(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestClient_WithToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug/pprof/profile":
+			assert.Empty(t, r.Header.Get("Authorization"))
+		default:
+			assert.EqualValues(t, "Bearer test", r.Header.Get("Authorization"))
+		}
+
+		api.Respond(r.Context(), w, http.StatusOK, profile.ListResponse{})
+	}))
+	defer server.Close()
+
+	cl := client.New(server.URL, client.WithToken("test"))
+
+	_, err := cl.List(context.Background())
+	require.NoError(t, err)
+
+	_, err = cl.Profile(context.Background(), server.URL+"/debug/pprof/profile", time.Second)
+	require.NoError(t, err)
+}
+
+func TestClient_WithTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.TLS.PeerCertificates)
+		api.Respond(r.Context(), w, http.StatusOK, profile.ListResponse{})
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	cl := client.New(server.URL, client.WithTLSConfig(&tls.Config{
+		RootCAs:      pool,
+		Certificates: server.TLS.Certificates,
+	}))
+
+	_, err := cl.List(context.Background())
+	require.NoError(t, err)
+
+	// Targets are profiled without the TLS configuration for the profile server, so the CA is not trusted and the
+	// client certificate is never presented.
+	_, err = cl.Profile(context.Background(), server.URL+"/debug/pprof/profile", time.Second)
+	var unknownAuthority x509.UnknownAuthorityError
+	assert.ErrorAs(t, err, &unknownAuthority)
+}